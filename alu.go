@@ -0,0 +1,116 @@
+package main
+
+// Arithmetic and logic helpers shared by the instructions. Each one
+// takes its operands plus the current flags where some of them are
+// preserved, and returns the result along with the new flags.
+
+// Adds b plus carry to a
+func aluAdd(a, b uint8, carry int) (uint8, FlagReg) {
+	res := int(a) + int(b) + carry
+	f := FlagReg{
+		z: flag(res&0xff == 0),
+		h: flag(int(a&0xf)+int(b&0xf)+carry > 0xf),
+		c: flag(res > 0xff),
+	}
+
+	return uint8(res), f
+}
+
+// Subtracts b plus carry from a. Also used by CP, which discards the
+// result.
+func aluSub(a, b uint8, carry int) (uint8, FlagReg) {
+	res := int(a) - int(b) - carry
+	f := FlagReg{
+		z: flag(res&0xff == 0),
+		n: 1,
+		h: flag(int(a&0xf)-int(b&0xf)-carry < 0),
+		c: flag(res < 0),
+	}
+
+	return uint8(res), f
+}
+
+func aluAnd(a, b uint8) (uint8, FlagReg) {
+	res := a & b
+	return res, FlagReg{z: flag(res == 0), h: 1}
+}
+
+func aluOr(a, b uint8) (uint8, FlagReg) {
+	res := a | b
+	return res, FlagReg{z: flag(res == 0)}
+}
+
+func aluXor(a, b uint8) (uint8, FlagReg) {
+	res := a ^ b
+	return res, FlagReg{z: flag(res == 0)}
+}
+
+// Increments an 8-bit value, preserving the carry flag
+func aluInc(val uint8, f FlagReg) (uint8, FlagReg) {
+	res := val + 1
+	f.z = flag(res == 0)
+	f.n = 0
+	f.h = flag(val&0xf == 0xf)
+
+	return res, f
+}
+
+// Decrements an 8-bit value, preserving the carry flag
+func aluDec(val uint8, f FlagReg) (uint8, FlagReg) {
+	res := val - 1
+	f.z = flag(res == 0)
+	f.n = 1
+	f.h = flag(val&0xf == 0)
+
+	return res, f
+}
+
+// Adds two 16-bit values, preserving the zero flag. Half carry is the
+// carry out of bit 11.
+func aluAdd16(a, b uint16, f FlagReg) (uint16, FlagReg) {
+	res := int(a) + int(b)
+	f.n = 0
+	f.h = flag(int(a&0xfff)+int(b&0xfff) > 0xfff)
+	f.c = flag(res > 0xffff)
+
+	return uint16(res), f
+}
+
+// Adds a signed 8-bit offset to SP, as done by ADD SP,r8 and
+// LD HL,SP+r8. Flags come from the unsigned addition of the offset to
+// the low byte of SP, regardless of its sign.
+func aluAddSp(sp uint16, offset uint8) (uint16, FlagReg) {
+	f := FlagReg{
+		h: flag(int(sp&0xf)+int(offset&0xf) > 0xf),
+		c: flag(int(sp&0xff)+int(offset) > 0xff),
+	}
+
+	return sp + uint16(int8(offset)), f
+}
+
+// Adjusts the result of a BCD addition or subtraction. The correction
+// depends on the N, H and C flags left by that operation, and carry is
+// only ever set, never cleared.
+func aluDaa(a uint8, f FlagReg) (uint8, FlagReg) {
+	if f.n == 0 {
+		if f.c == 1 || a > 0x99 {
+			a += 0x60
+			f.c = 1
+		}
+		if f.h == 1 || a&0xf > 0x9 {
+			a += 0x06
+		}
+	} else {
+		if f.c == 1 {
+			a -= 0x60
+		}
+		if f.h == 1 {
+			a -= 0x06
+		}
+	}
+
+	f.z = flag(a == 0)
+	f.h = 0
+
+	return a, f
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"testing"
+)
+
+// Reference models derive half carry and carry from the bits that
+// differ between the operands and the full-width result, rather than
+// from nibble arithmetic as the ALU does.
+
+func refAdd(a, b, carry int) (int, FlagReg) {
+	res := a + b + carry
+	return res & 0xff, FlagReg{
+		z: flag(res&0xff == 0),
+		h: (a ^ b ^ res) >> 4 & 1,
+		c: res >> 8 & 1,
+	}
+}
+
+func refSub(a, b, carry int) (int, FlagReg) {
+	res := a - b - carry
+	return res & 0xff, FlagReg{
+		z: flag(res&0xff == 0),
+		n: 1,
+		h: (a ^ b ^ res) >> 4 & 1,
+		c: res >> 8 & 1,
+	}
+}
+
+func refAdd16(a, b int, f FlagReg) (int, FlagReg) {
+	res := a + b
+	return res & 0xffff, FlagReg{
+		z: f.z,
+		h: (a ^ b ^ res) >> 12 & 1,
+		c: res >> 16 & 1,
+	}
+}
+
+func refAddSp(sp, offset int) (int, FlagReg) {
+	extended := offset
+	if offset&0x80 != 0 {
+		extended |= 0xff00
+	}
+
+	res := sp + extended
+	return res & 0xffff, FlagReg{
+		h: (sp ^ extended ^ res) >> 4 & 1,
+		c: (sp ^ extended ^ res) >> 8 & 1,
+	}
+}
+
+// DAA as implemented by SameBoy, correcting the low nibble first
+func refDaa(a int, f FlagReg) (int, FlagReg) {
+	res := a
+	carry := f.c
+
+	if f.n == 1 {
+		if f.h == 1 {
+			res = (res - 0x06) & 0xff
+		}
+		if f.c == 1 {
+			res -= 0x60
+		}
+	} else {
+		if f.h == 1 || res&0x0f > 0x09 {
+			res += 0x06
+		}
+		if f.c == 1 || res > 0x9f {
+			res += 0x60
+		}
+	}
+
+	if res&0x100 != 0 {
+		carry = 1
+	}
+
+	return res & 0xff, FlagReg{z: flag(res&0xff == 0), n: f.n, c: carry}
+}
+
+// All combinations of the four flags
+func allFlags() []FlagReg {
+	var flags []FlagReg
+	for n := 0; n < 16; n++ {
+		flags = append(flags, fromInt(n<<4))
+	}
+
+	return flags
+}
+
+func TestAluArithmetic(t *testing.T) {
+	for _, tt := range []struct {
+		testName string
+		alu      func(a, b uint8, carry int) (uint8, FlagReg)
+		ref      func(a, b, carry int) (int, FlagReg)
+	}{
+		{testName: "ADD/ADC", alu: aluAdd, ref: refAdd},
+		{testName: "SUB/SBC/CP", alu: aluSub, ref: refSub},
+	} {
+		t.Log(tt.testName)
+
+		for a := 0; a < 0x100; a++ {
+			for b := 0; b < 0x100; b++ {
+				for carry := 0; carry < 2; carry++ {
+					res, f := tt.alu(uint8(a), uint8(b), carry)
+					expectedRes, expectedF := tt.ref(a, b, carry)
+
+					if int(res) != expectedRes || f != expectedF {
+						t.Fatalf("%s %#02x, %#02x, %d: expected %#02x %+v, got %#02x %+v",
+							tt.testName, a, b, carry, expectedRes, expectedF, res, f)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestAluLogic(t *testing.T) {
+	for a := 0; a < 0x100; a++ {
+		for b := 0; b < 0x100; b++ {
+			if res, f := aluAnd(uint8(a), uint8(b)); int(res) != a&b || f != (FlagReg{z: flag(a&b == 0), h: 1}) {
+				t.Fatalf("AND %#02x, %#02x: got %#02x %+v", a, b, res, f)
+			}
+
+			if res, f := aluOr(uint8(a), uint8(b)); int(res) != a|b || f != (FlagReg{z: flag(a|b == 0)}) {
+				t.Fatalf("OR %#02x, %#02x: got %#02x %+v", a, b, res, f)
+			}
+
+			if res, f := aluXor(uint8(a), uint8(b)); int(res) != a^b || f != (FlagReg{z: flag(a^b == 0)}) {
+				t.Fatalf("XOR %#02x, %#02x: got %#02x %+v", a, b, res, f)
+			}
+		}
+	}
+}
+
+func TestAluIncDec(t *testing.T) {
+	for val := 0; val < 0x100; val++ {
+		for _, f := range allFlags() {
+			// INC and DEC behave as ADD and SUB of 1 that keep carry
+			expectedRes, expectedF := refAdd(val, 1, 0)
+			expectedF.c = f.c
+
+			if res, incF := aluInc(uint8(val), f); int(res) != expectedRes || incF != expectedF {
+				t.Fatalf("INC %#02x %+v: expected %#02x %+v, got %#02x %+v", val, f, expectedRes, expectedF, res, incF)
+			}
+
+			expectedRes, expectedF = refSub(val, 1, 0)
+			expectedF.c = f.c
+
+			if res, decF := aluDec(uint8(val), f); int(res) != expectedRes || decF != expectedF {
+				t.Fatalf("DEC %#02x %+v: expected %#02x %+v, got %#02x %+v", val, f, expectedRes, expectedF, res, decF)
+			}
+		}
+	}
+}
+
+func TestAluAdd16(t *testing.T) {
+	for a := 0; a < 0x10000; a++ {
+		for _, b := range []int{0x0000, 0x0001, 0x000f, 0x00ff, 0x0fff, 0x1000, 0x7fff, 0x8000, 0xf001, 0xffff, a} {
+			for _, z := range []int{0, 1} {
+				f := FlagReg{z: z, n: 1}
+
+				res, addF := aluAdd16(uint16(a), uint16(b), f)
+				expectedRes, expectedF := refAdd16(a, b, f)
+
+				if int(res) != expectedRes || addF != expectedF {
+					t.Fatalf("ADD HL %#04x, %#04x: expected %#04x %+v, got %#04x %+v", a, b, expectedRes, expectedF, res, addF)
+				}
+			}
+		}
+	}
+}
+
+func TestAluAddSp(t *testing.T) {
+	for sp := 0; sp < 0x10000; sp++ {
+		for offset := 0; offset < 0x100; offset++ {
+			res, f := aluAddSp(uint16(sp), uint8(offset))
+			expectedRes, expectedF := refAddSp(sp, offset)
+
+			if int(res) != expectedRes || f != expectedF {
+				t.Fatalf("ADD SP %#04x, %#02x: expected %#04x %+v, got %#04x %+v", sp, offset, expectedRes, expectedF, res, f)
+			}
+		}
+	}
+}
+
+func TestAluDaa(t *testing.T) {
+	for a := 0; a < 0x100; a++ {
+		for _, f := range allFlags() {
+			res, daaF := aluDaa(uint8(a), f)
+			expectedRes, expectedF := refDaa(a, f)
+
+			if int(res) != expectedRes || daaF != expectedF {
+				t.Fatalf("DAA %#02x %+v: expected %#02x %+v, got %#02x %+v", a, f, expectedRes, expectedF, res, daaF)
+			}
+		}
+	}
+}
+
+// Converts a number between 0 and 99 to packed BCD
+func bcd(n int) uint8 {
+	return uint8(n/10<<4 | n%10)
+}
+
+func TestAluDaaDecimal(t *testing.T) {
+	for x := 0; x < 100; x++ {
+		for y := 0; y < 100; y++ {
+			for carry := 0; carry < 2; carry++ {
+				sum, f := aluAdd(bcd(x), bcd(y), carry)
+				sum, f = aluDaa(sum, f)
+
+				expected := x + y + carry
+				if sum != bcd(expected%100) || f.c != flag(expected >= 100) {
+					t.Fatalf("%d + %d + %d: expected %#02x carry %d, got %#02x carry %d",
+						x, y, carry, bcd(expected%100), flag(expected >= 100), sum, f.c)
+				}
+
+				diff, f := aluSub(bcd(x), bcd(y), carry)
+				diff, f = aluDaa(diff, f)
+
+				expected = x - y - carry
+				if diff != bcd((expected+100)%100) || f.c != flag(expected < 0) {
+					t.Fatalf("%d - %d - %d: expected %#02x carry %d, got %#02x carry %d",
+						x, y, carry, bcd((expected+100)%100), flag(expected < 0), diff, f.c)
+				}
+			}
+		}
+	}
+}
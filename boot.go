@@ -0,0 +1,198 @@
+package main
+
+import "fmt"
+
+// Hardware models, which boot into slightly different states that games
+// can use to tell them apart
+const (
+	MODEL_DMG0 = iota // Early DMG
+	MODEL_DMG
+	MODEL_MGB // Game Boy Pocket and Light
+	MODEL_SGB
+	MODEL_SGB2
+	MODEL_CGB
+	MODEL_AGB // Game Boy Advance running Game Boy software
+)
+
+const (
+	BOOT_ADDR = 0xff50 // Writing 1 unmaps the boot ROM
+
+	// The DMG boot ROM covers 0x0000-0x00ff. The CGB one also covers
+	// 0x0200-0x08ff, leaving the cartridge header visible in between.
+	BOOT_ROM_SIZE     = 0x100
+	CGB_BOOT_ROM_SIZE = 0x900
+)
+
+// Maps a boot ROM over the start of the cartridge ROM until BOOT_ADDR is
+// written. The CPU then starts running it from 0x0000, in CGB mode for a
+// CGB boot ROM.
+func (m *Mmu) SetBootRom(rom []uint8) error {
+	if len(rom) != BOOT_ROM_SIZE && len(rom) != CGB_BOOT_ROM_SIZE {
+		return fmt.Errorf("boot ROM is %d bytes, expected %d or %d", len(rom), BOOT_ROM_SIZE, CGB_BOOT_ROM_SIZE)
+	}
+
+	m.bootRom = rom
+	m.cgb = len(rom) == CGB_BOOT_ROM_SIZE
+	return nil
+}
+
+// Hands over to the cartridge. The CGB boot ROM stays in CGB mode only
+// for cartridges supporting it, and leaves the others in DMG mode.
+func (m *Mmu) unmapBootRom() {
+	if len(m.bootRom) == CGB_BOOT_ROM_SIZE {
+		m.cgb = m.readCart(HEADER_CGB_FLAG)&CGB_SUPPORTED != 0
+	}
+
+	m.bootRom = nil
+}
+
+// Whether the boot ROM rather than the cartridge answers at an address
+func (m *Mmu) bootRomMapped(addr uint16) bool {
+	if m.bootRom == nil {
+		return false
+	}
+
+	return addr < BOOT_ROM_SIZE || addr >= 0x0200 && int(addr) < len(m.bootRom)
+}
+
+// Register values left by the boot ROM, in the order A, F, B, C, D, E,
+// H, L. Those depending on the cartridge are adjusted by PostBoot.
+var postBootRegisters = map[int][8]uint8{
+	MODEL_DMG0: {0x01, 0x00, 0xff, 0x13, 0x00, 0xc1, 0x84, 0x03},
+	MODEL_DMG:  {0x01, 0x80, 0x00, 0x13, 0x00, 0xd8, 0x01, 0x4d},
+	MODEL_MGB:  {0xff, 0x80, 0x00, 0x13, 0x00, 0xd8, 0x01, 0x4d},
+	MODEL_SGB:  {0x01, 0x00, 0x00, 0x14, 0x00, 0x00, 0xc0, 0x60},
+	MODEL_SGB2: {0xff, 0x00, 0x00, 0x14, 0x00, 0x00, 0xc0, 0x60},
+	MODEL_CGB:  {0x11, 0x80, 0x00, 0x00, 0xff, 0x56, 0x00, 0x0d},
+	MODEL_AGB:  {0x11, 0x00, 0x01, 0x00, 0xff, 0x56, 0x00, 0x0d},
+}
+
+// I/O registers as the DMG boot ROM leaves them, from 0xff00
+var postBootIo = map[uint16]uint8{
+	0x00: 0xcf, 0x01: 0x00, 0x02: 0x7e, 0x04: 0xab, 0x05: 0x00, 0x06: 0x00, 0x07: 0xf8, 0x0f: 0xe1,
+	0x10: 0x80, 0x11: 0xbf, 0x12: 0xf3, 0x13: 0xff, 0x14: 0xbf,
+	0x16: 0x3f, 0x17: 0x00, 0x18: 0xff, 0x19: 0xbf,
+	0x1a: 0x7f, 0x1b: 0xff, 0x1c: 0x9f, 0x1d: 0xff, 0x1e: 0xbf,
+	0x20: 0xff, 0x21: 0x00, 0x22: 0x00, 0x23: 0xbf,
+	0x24: 0x77, 0x25: 0xf3, 0x26: 0xf1,
+	0x40: 0x91, 0x41: 0x85, 0x42: 0x00, 0x43: 0x00, 0x44: 0x00, 0x45: 0x00, 0x46: 0xff, 0x47: 0xfc,
+	0x4a: 0x00, 0x4b: 0x00,
+}
+
+// The registered trademark symbol the DMG boot ROM draws after the logo
+var bootTrademark = [8]uint8{0x3c, 0x42, 0xb9, 0xa5, 0xb9, 0xa5, 0x42, 0x3c}
+
+// Puts the hardware in the state the boot ROM of the given model leaves
+// it in when it hands over to the cartridge at 0x0100, so that the boot
+// ROM can be skipped. The cartridge has to be inserted already, as some
+// of the values depend on its header.
+func PostBoot(model int, cpu *Cpu, mmu *Mmu) {
+	regs := postBootRegisters[model]
+	cgbMode := mmu.Read(HEADER_CGB_FLAG)&CGB_SUPPORTED != 0
+
+	switch model {
+	case MODEL_DMG, MODEL_MGB:
+		// Left over from comparing the header checksum
+		if mmu.Read(HEADER_CHECKSUM) != 0 {
+			regs[1] |= BIT_5 | BIT_4
+		}
+	case MODEL_CGB, MODEL_AGB:
+		if !cgbMode {
+			// DMG games are colorized based on a hash of their title
+			b := bootTitleHash(mmu)
+			regs[2], regs[4], regs[5] = b, 0x00, 0x08
+			regs[6], regs[7] = 0x00, 0x7c
+			if b == 0x43 || b == 0x58 {
+				regs[6], regs[7] = 0x99, 0x1a
+			}
+
+			if model == MODEL_AGB {
+				// The AGB boot ROM ends with INC B
+				regs[2]++
+				regs[1] = uint8(flag(regs[2] == 0)<<7 | flag(regs[2]&0x0f == 0)<<5)
+			}
+		}
+	}
+
+	cpu.a, cpu.p = regs[0], fromInt(int(regs[1]))
+	cpu.b, cpu.c, cpu.d, cpu.e, cpu.h, cpu.l = regs[2], regs[3], regs[4], regs[5], regs[6], regs[7]
+	cpu.sp, cpu.pc = 0xfffe, 0x0100
+	SetCgbMode((model == MODEL_CGB || model == MODEL_AGB) && cgbMode, cpu, mmu)
+
+	mmu.bootRom = nil
+	for addr, val := range postBootIo {
+		mmu.io[addr] = val
+	}
+
+	switch model {
+	case MODEL_DMG0:
+		mmu.io[0x04] = 0x18
+	case MODEL_SGB, MODEL_SGB2:
+		mmu.io[0x04] = 0x00
+		mmu.io[0x26] = 0xf0
+	case MODEL_CGB, MODEL_AGB:
+		mmu.io[0x02] = 0x7f
+		mmu.io[0x04] = 0x00
+		mmu.io[0x46] = 0x00
+	}
+
+	// The CGB boot ROM draws its logo differently and clears VRAM
+	// before handing over, leaving background palettes white for CGB
+	// games
+	switch {
+	case mmu.cgb:
+		for i := range mmu.bgPalettes.data {
+			mmu.bgPalettes.data[i] = 0xff
+		}
+	case model != MODEL_CGB && model != MODEL_AGB:
+		bootLogo(mmu)
+	}
+}
+
+// Sum of the title bytes, used when the licensee is Nintendo
+func bootTitleHash(mmu *Mmu) uint8 {
+	old := mmu.Read(HEADER_OLD_LICENSEE)
+	nintendo := old == 0x01 || old == USE_NEW_LICENSEE &&
+		mmu.Read(HEADER_NEW_LICENSEE) == '0' && mmu.Read(HEADER_NEW_LICENSEE+1) == '1'
+	if !nintendo {
+		return 0
+	}
+
+	var sum uint8
+	for addr := uint16(HEADER_TITLE); addr < HEADER_TITLE+16; addr++ {
+		sum += mmu.Read(addr)
+	}
+
+	return sum
+}
+
+// Leaves the logo from the cartridge header in VRAM, as the DMG boot ROM
+// does: tiles from 0x8010, each logo nibble scaled up to 2x2 pixels, and
+// the tile map of the two rows of the logo followed by the trademark.
+func bootLogo(mmu *Mmu) {
+	addr := uint16(0x8010)
+	for i := uint16(0); i < 48; i++ {
+		logo := mmu.Read(HEADER_LOGO + i)
+		for _, nibble := range []uint8{logo >> 4, logo & 0x0f} {
+			var scaled uint8
+			for bit := 3; bit >= 0; bit-- {
+				scaled = scaled<<2 | (nibble>>bit&1)*0x3
+			}
+
+			mmu.vram[addr-VRAM_ADDR] = scaled
+			mmu.vram[addr+2-VRAM_ADDR] = scaled
+			addr += 4
+		}
+	}
+
+	for _, line := range bootTrademark {
+		mmu.vram[addr-VRAM_ADDR] = line
+		addr += 2
+	}
+
+	mmu.vram[0x9910-VRAM_ADDR] = 0x19
+	for tile := uint8(1); tile <= 12; tile++ {
+		mmu.vram[0x9903+uint16(tile)-VRAM_ADDR] = tile
+		mmu.vram[0x9923+uint16(tile)-VRAM_ADDR] = tile + 12
+	}
+}
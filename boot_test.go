@@ -0,0 +1,282 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestBootRomMapping(t *testing.T) {
+	cart := &cartStub{}
+	for addr := range cart.memory {
+		cart.memory[addr] = 0xca
+	}
+
+	m := NewMmu(cart)
+	if err := m.SetBootRom(make([]uint8, 0x200)); err == nil {
+		t.Error("Expected an error for a boot ROM of the wrong size")
+	}
+
+	rom := make([]uint8, CGB_BOOT_ROM_SIZE)
+	for i := range rom {
+		rom[i] = 0xb0
+	}
+	if err := m.SetBootRom(rom); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, tt := range []struct {
+		testName string
+		addr     uint16
+		expected uint8
+	}{
+		{testName: "Start of the boot ROM", addr: 0x0000, expected: 0xb0},
+		{testName: "Cartridge header", addr: 0x0100, expected: 0xca},
+		{testName: "CGB part of the boot ROM", addr: 0x0200, expected: 0xb0},
+		{testName: "End of the boot ROM", addr: 0x08ff, expected: 0xb0},
+		{testName: "Past the boot ROM", addr: 0x0900, expected: 0xca},
+	} {
+		t.Log(tt.testName)
+
+		if val := m.Read(tt.addr); val != tt.expected {
+			t.Errorf("%s: expected %#02x at %#04x, got %#02x", tt.testName, tt.expected, tt.addr, val)
+		}
+	}
+
+	// Only bit 0 unmaps the boot ROM
+	m.Write(BOOT_ADDR, 0xfe)
+	if val := m.Read(0x0000); val != 0xb0 {
+		t.Errorf("Expected the boot ROM to stay mapped, got %#02x", val)
+	}
+
+	m.Write(BOOT_ADDR, 0x01)
+	if val := m.Read(0x0000); val != 0xca {
+		t.Errorf("Expected the boot ROM to be unmapped, got %#02x", val)
+	}
+}
+
+func TestBootRomHandover(t *testing.T) {
+	cart := &cartStub{}
+	cart.memory[0x0004] = 0x3c // INC A
+
+	m := NewMmu(cart)
+	// LD A,1; LDH (0x50),A
+	if err := m.SetBootRom(append([]uint8{0x3e, 0x01, 0xe0, 0x50}, make([]uint8, BOOT_ROM_SIZE-4)...)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	cpu := NewCpu(m)
+	for i := 0; i < 3; i++ {
+		cpu.tick()
+	}
+
+	if cpu.a != 0x02 || cpu.pc != 0x0005 {
+		t.Errorf("Expected the cartridge to run after the boot ROM, got A=%#02x PC=%#04x", cpu.a, cpu.pc)
+	}
+}
+
+func TestBootRomCgbMode(t *testing.T) {
+	for _, tt := range []struct {
+		testName          string
+		bootRomSize       int
+		cgbFlag           uint8
+		expectedBooting   bool
+		expectedCartridge bool
+	}{
+		{testName: "DMG boot ROM", bootRomSize: BOOT_ROM_SIZE, cgbFlag: CGB_SUPPORTED},
+		{testName: "CGB game", bootRomSize: CGB_BOOT_ROM_SIZE, cgbFlag: CGB_SUPPORTED, expectedBooting: true, expectedCartridge: true},
+		{testName: "CGB only game", bootRomSize: CGB_BOOT_ROM_SIZE, cgbFlag: CGB_ONLY, expectedBooting: true, expectedCartridge: true},
+		{testName: "DMG game", bootRomSize: CGB_BOOT_ROM_SIZE, expectedBooting: true},
+	} {
+		t.Log(tt.testName)
+
+		cart := &cartStub{}
+		cart.memory[HEADER_CGB_FLAG] = tt.cgbFlag
+
+		m := NewMmu(cart)
+		if err := m.SetBootRom(make([]uint8, tt.bootRomSize)); err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.testName, err)
+		}
+
+		cpu := NewCpu(m)
+		if m.cgb != tt.expectedBooting || cpu.cgbMode() != tt.expectedBooting {
+			t.Errorf("%s: expected CGB mode %v while booting, got %v", tt.testName, tt.expectedBooting, m.cgb)
+		}
+
+		m.Write(BOOT_ADDR, 0x01)
+		if m.cgb != tt.expectedCartridge || cpu.cgbMode() != tt.expectedCartridge {
+			t.Errorf("%s: expected CGB mode %v after booting, got %v", tt.testName, tt.expectedCartridge, m.cgb)
+		}
+	}
+}
+
+func TestPostBootRegisters(t *testing.T) {
+	for _, tt := range []struct {
+		testName string
+		model    int
+		header   map[uint16]uint8
+		expected [8]uint8 // A, F, B, C, D, E, H, L
+		cgb      bool
+	}{
+		{
+			testName: "DMG0",
+			model:    MODEL_DMG0,
+			expected: [8]uint8{0x01, 0x00, 0xff, 0x13, 0x00, 0xc1, 0x84, 0x03},
+		},
+		{
+			testName: "DMG with a zero header checksum",
+			model:    MODEL_DMG,
+			expected: [8]uint8{0x01, 0x80, 0x00, 0x13, 0x00, 0xd8, 0x01, 0x4d},
+		},
+		{
+			testName: "DMG",
+			model:    MODEL_DMG,
+			header:   map[uint16]uint8{HEADER_CHECKSUM: 0x66},
+			expected: [8]uint8{0x01, 0xb0, 0x00, 0x13, 0x00, 0xd8, 0x01, 0x4d},
+		},
+		{
+			testName: "MGB",
+			model:    MODEL_MGB,
+			header:   map[uint16]uint8{HEADER_CHECKSUM: 0x66},
+			expected: [8]uint8{0xff, 0xb0, 0x00, 0x13, 0x00, 0xd8, 0x01, 0x4d},
+		},
+		{
+			testName: "SGB",
+			model:    MODEL_SGB,
+			expected: [8]uint8{0x01, 0x00, 0x00, 0x14, 0x00, 0x00, 0xc0, 0x60},
+		},
+		{
+			testName: "SGB2",
+			model:    MODEL_SGB2,
+			expected: [8]uint8{0xff, 0x00, 0x00, 0x14, 0x00, 0x00, 0xc0, 0x60},
+		},
+		{
+			testName: "CGB",
+			model:    MODEL_CGB,
+			header:   map[uint16]uint8{HEADER_CGB_FLAG: 0x80},
+			expected: [8]uint8{0x11, 0x80, 0x00, 0x00, 0xff, 0x56, 0x00, 0x0d},
+			cgb:      true,
+		},
+		{
+			testName: "CGB running a DMG game from another licensee",
+			model:    MODEL_CGB,
+			header:   map[uint16]uint8{HEADER_TITLE: 0x43, HEADER_OLD_LICENSEE: 0x02},
+			expected: [8]uint8{0x11, 0x80, 0x00, 0x00, 0x00, 0x08, 0x00, 0x7c},
+		},
+		{
+			testName: "CGB running a DMG game from Nintendo",
+			model:    MODEL_CGB,
+			header:   map[uint16]uint8{HEADER_TITLE: 0x40, HEADER_TITLE + 1: 0x03, HEADER_OLD_LICENSEE: 0x01},
+			expected: [8]uint8{0x11, 0x80, 0x43, 0x00, 0x00, 0x08, 0x99, 0x1a},
+		},
+		{
+			testName: "CGB with the new licensee code",
+			model:    MODEL_CGB,
+			header: map[uint16]uint8{HEADER_TITLE: 0x12, HEADER_OLD_LICENSEE: USE_NEW_LICENSEE,
+				HEADER_NEW_LICENSEE: '0', HEADER_NEW_LICENSEE + 1: '1'},
+			expected: [8]uint8{0x11, 0x80, 0x12, 0x00, 0x00, 0x08, 0x00, 0x7c},
+		},
+		{
+			testName: "AGB",
+			model:    MODEL_AGB,
+			header:   map[uint16]uint8{HEADER_CGB_FLAG: 0xc0},
+			expected: [8]uint8{0x11, 0x00, 0x01, 0x00, 0xff, 0x56, 0x00, 0x0d},
+			cgb:      true,
+		},
+		{
+			testName: "AGB running a DMG game",
+			model:    MODEL_AGB,
+			header:   map[uint16]uint8{HEADER_TITLE: 0x0f, HEADER_OLD_LICENSEE: 0x01},
+			expected: [8]uint8{0x11, 0x20, 0x10, 0x00, 0x00, 0x08, 0x00, 0x7c},
+		},
+	} {
+		t.Log(tt.testName)
+
+		cart := &cartStub{}
+		for addr, val := range tt.header {
+			cart.memory[addr] = val
+		}
+
+		m := NewMmu(cart)
+		cpu := NewCpu(m)
+		PostBoot(tt.model, cpu, m)
+
+		regs := [8]uint8{cpu.a, uint8(cpu.p.toInt()), cpu.b, cpu.c, cpu.d, cpu.e, cpu.h, cpu.l}
+		if regs != tt.expected {
+			t.Errorf("%s: expected registers %#02x, got %#02x", tt.testName, tt.expected, regs)
+		}
+
+		if cpu.pc != 0x0100 || cpu.sp != 0xfffe {
+			t.Errorf("%s: expected PC=0x0100 SP=0xfffe, got PC=%#04x SP=%#04x", tt.testName, cpu.pc, cpu.sp)
+		}
+
+		if cpu.cgb != tt.cgb || m.cgb != tt.cgb {
+			t.Errorf("%s: expected CGB mode %v", tt.testName, tt.cgb)
+		}
+	}
+}
+
+func TestPostBootIo(t *testing.T) {
+	for _, tt := range []struct {
+		testName string
+		model    int
+		addr     uint16
+		expected uint8
+	}{
+		{testName: "DMG LCDC", model: MODEL_DMG, addr: 0xff40, expected: 0x91},
+		{testName: "DMG BGP", model: MODEL_DMG, addr: 0xff47, expected: 0xfc},
+		{testName: "DMG IF", model: MODEL_DMG, addr: IF_ADDR, expected: 0xe1},
+		{testName: "DMG DIV", model: MODEL_DMG, addr: 0xff04, expected: 0xab},
+		{testName: "DMG0 DIV", model: MODEL_DMG0, addr: 0xff04, expected: 0x18},
+		{testName: "DMG NR52", model: MODEL_DMG, addr: 0xff26, expected: 0xf1},
+		{testName: "SGB NR52", model: MODEL_SGB, addr: 0xff26, expected: 0xf0},
+		{testName: "DMG SC", model: MODEL_DMG, addr: 0xff02, expected: 0x7e},
+		{testName: "CGB SC", model: MODEL_CGB, addr: 0xff02, expected: 0x7f},
+		{testName: "CGB DMA", model: MODEL_CGB, addr: 0xff46, expected: 0x00},
+	} {
+		t.Log(tt.testName)
+
+		m := NewMmu(&cartStub{})
+		PostBoot(tt.model, NewCpu(m), m)
+
+		if val := m.Read(tt.addr); val != tt.expected {
+			t.Errorf("%s: expected %#02x at %#04x, got %#02x", tt.testName, tt.expected, tt.addr, val)
+		}
+	}
+}
+
+func TestPostBootLogo(t *testing.T) {
+	cart := &cartStub{}
+	copy(cart.memory[HEADER_LOGO:], nintendoLogo[:])
+
+	m := NewMmu(cart)
+	PostBoot(MODEL_DMG, NewCpu(m), m)
+
+	for _, tt := range []struct {
+		testName string
+		addr     uint16
+		expected uint8
+	}{
+		{testName: "High nibble of the first logo byte", addr: 0x8010, expected: 0xf0},
+		{testName: "Scaled up vertically", addr: 0x8012, expected: 0xf0},
+		{testName: "Upper bitplane is clear", addr: 0x8011, expected: 0x00},
+		{testName: "Low nibble of the first logo byte", addr: 0x8014, expected: 0xfc},
+		{testName: "Trademark", addr: 0x8192, expected: 0x42},
+		{testName: "First tile of the top row", addr: 0x9904, expected: 0x01},
+		{testName: "Last tile of the top row", addr: 0x990f, expected: 0x0c},
+		{testName: "Trademark tile", addr: 0x9910, expected: 0x19},
+		{testName: "First tile of the bottom row", addr: 0x9924, expected: 0x0d},
+		{testName: "Last tile of the bottom row", addr: 0x992f, expected: 0x18},
+	} {
+		t.Log(tt.testName)
+
+		if val := m.Read(tt.addr); val != tt.expected {
+			t.Errorf("%s: expected %#02x at %#04x, got %#02x", tt.testName, tt.expected, tt.addr, val)
+		}
+	}
+
+	// The CGB leaves VRAM clear
+	m = NewMmu(cart)
+	PostBoot(MODEL_CGB, NewCpu(m), m)
+	if val := m.Read(0x9904); val != 0x00 {
+		t.Errorf("Expected no logo in VRAM on CGB, got %#02x", val)
+	}
+}
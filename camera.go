@@ -0,0 +1,174 @@
+package main
+
+const (
+	// Sensor resolution
+	CAMERA_WIDTH  = 128
+	CAMERA_HEIGHT = 112
+
+	// Bit 4 of the RAM bank register maps the camera registers
+	CAMERA_REG_SELECT = BIT_4
+	CAMERA_REG_COUNT  = 0x36
+
+	// Register 0 bit starting a capture, which reads back set until done
+	CAMERA_CAPTURE = BIT_0
+
+	// Where captures are written as tiles, in RAM bank 0
+	CAMERA_IMAGE_ADDR = 0x0100
+
+	// Fixed part of the capture time, in CPU cycles, to which the
+	// exposure time adds
+	CAMERA_CAPTURE_CYCLES = 32446 * 4
+	CAMERA_EXPOSURE_STEP  = 16 * 4
+)
+
+// Provides the images seen by the Pocket Camera sensor, as 8-bit
+// brightness values from black to white, row by row
+type CameraSource interface {
+	Capture() [CAMERA_WIDTH * CAMERA_HEIGHT]uint8
+}
+
+// Pocket Camera, whose controller maps 128KB of RAM and the registers of
+// the image sensor
+type camera struct {
+	rom, ram []uint8
+	source   CameraSource // Nil shows a blank image
+
+	ramWriteEnabled bool
+	romBank         uint8
+	ramBank         uint8
+
+	regs [CAMERA_REG_COUNT]uint8
+	// Cycles left until the capture in progress completes
+	capturing int
+}
+
+func newCamera(rom, ram []uint8) *camera {
+	return &camera{rom: rom, ram: ram, romBank: 1}
+}
+
+func (m *camera) Read(addr uint16) uint8 {
+	switch {
+	case addr < ROM_BANK_SIZE:
+		return romByte(m.rom, 0, addr)
+	case addr < VRAM_ADDR:
+		return romByte(m.rom, int(m.romBank), addr-ROM_BANK_SIZE)
+	case addr >= EXT_RAM_ADDR && addr < WRAM_ADDR:
+		if m.ramBank&CAMERA_REG_SELECT != 0 {
+			// Only the control register can be read, the rest are write-only
+			if addr&0x7f == 0 {
+				return m.regs[0]
+			}
+
+			return 0x00
+		}
+
+		if len(m.ram) == 0 {
+			return 0xff
+		}
+
+		// RAM reads do not require enabling
+		return m.ram[ramOffset(m.ram, int(m.ramBank), addr)]
+	}
+
+	return 0xff
+}
+
+func (m *camera) Write(addr uint16, val uint8) {
+	switch {
+	case addr < 0x2000:
+		m.ramWriteEnabled = val&0x0f == 0x0a
+	case addr < 0x4000:
+		m.romBank = val & 0x3f
+	case addr < 0x6000:
+		m.ramBank = val & 0x1f
+	case addr >= EXT_RAM_ADDR && addr < WRAM_ADDR:
+		if m.ramBank&CAMERA_REG_SELECT != 0 {
+			m.writeRegister(int(addr&0x7f), val)
+		} else if m.ramWriteEnabled && len(m.ram) != 0 {
+			m.ram[ramOffset(m.ram, int(m.ramBank), addr)] = val
+		}
+	}
+}
+
+func (m *camera) writeRegister(reg int, val uint8) {
+	if reg >= CAMERA_REG_COUNT {
+		return
+	}
+
+	if reg != 0 {
+		m.regs[reg] = val
+		return
+	}
+
+	// Only bits 0-2 of the control register are used
+	m.regs[0] = val & 0x07
+	if val&CAMERA_CAPTURE != 0 && m.capturing == 0 {
+		exposure := int(m.regs[2])<<8 | int(m.regs[3])
+		m.capturing = CAMERA_CAPTURE_CYCLES + exposure*CAMERA_EXPOSURE_STEP
+	}
+}
+
+// Completes captures once they have taken as long as the sensor would
+func (m *camera) tick(cycles int) {
+	if m.capturing == 0 {
+		return
+	}
+
+	m.capturing -= cycles
+	if m.capturing <= 0 {
+		m.capturing = 0
+		m.capture()
+		m.regs[0] &^= CAMERA_CAPTURE
+	}
+}
+
+// Takes a picture and stores it as 2bpp tiles. Each pixel is turned into
+// one of 4 shades by comparing it with the thresholds of the dither
+// matrix entry for its position. Edge enhancement and gain are not
+// emulated.
+func (m *camera) capture() {
+	// Without RAM there is nowhere to put the picture
+	if len(m.ram) < CAMERA_IMAGE_ADDR+CAMERA_WIDTH*CAMERA_HEIGHT/4 {
+		return
+	}
+
+	var image [CAMERA_WIDTH * CAMERA_HEIGHT]uint8
+	if m.source != nil {
+		image = m.source.Capture()
+	} else {
+		for i := range image {
+			image[i] = 0xff
+		}
+	}
+
+	for y := 0; y < CAMERA_HEIGHT; y++ {
+		for x := 0; x < CAMERA_WIDTH; x++ {
+			// Thresholds from darkest to lightest, in registers 0x06-0x35
+			matrix := m.regs[6+((y&3)*4+x&3)*3:]
+			pixel := image[y*CAMERA_WIDTH+x]
+
+			color := 0
+			switch {
+			case pixel < matrix[0]:
+				color = 3
+			case pixel < matrix[1]:
+				color = 2
+			case pixel < matrix[2]:
+				color = 1
+			}
+
+			tile := y/8*(CAMERA_WIDTH/8) + x/8
+			offset := CAMERA_IMAGE_ADDR + tile*16 + y%8*2
+			bit := uint8(0x80) >> (x % 8)
+
+			m.ram[offset] &^= bit
+			m.ram[offset+1] &^= bit
+			if color&1 != 0 {
+				m.ram[offset] |= bit
+			}
+			if color&2 != 0 {
+				m.ram[offset+1] |= bit
+			}
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"testing"
+)
+
+// Sees a horizontal gradient, from black on the left to white
+type gradientSource struct{}
+
+func (gradientSource) Capture() [CAMERA_WIDTH * CAMERA_HEIGHT]uint8 {
+	var image [CAMERA_WIDTH * CAMERA_HEIGHT]uint8
+	for y := 0; y < CAMERA_HEIGHT; y++ {
+		for x := 0; x < CAMERA_WIDTH; x++ {
+			image[y*CAMERA_WIDTH+x] = uint8(x * 2)
+		}
+	}
+
+	return image
+}
+
+func TestCameraCapture(t *testing.T) {
+	cart := newTestCartridge(t, makeRom(0xfc, 0x05, 0x04))
+	cart.SetCameraSource(gradientSource{})
+
+	cart.Write(0x4000, CAMERA_REG_SELECT)
+	for reg := uint16(0xa006); reg < 0xa036; reg += 3 {
+		cart.Write(reg, 0x40)
+		cart.Write(reg+1, 0x80)
+		cart.Write(reg+2, 0xc0)
+	}
+	cart.Write(0xa002, 0x00)
+	cart.Write(0xa003, 0x10)
+	cart.Write(0xa000, CAMERA_CAPTURE)
+
+	if val := cart.Read(0xa002); val != 0x00 {
+		t.Errorf("Expected write-only registers to read 0x00, got %#02x", val)
+	}
+
+	cycles := CAMERA_CAPTURE_CYCLES + 0x10*CAMERA_EXPOSURE_STEP
+	cart.Tick(cycles - 1)
+	if val := cart.Read(0xa000); val&CAMERA_CAPTURE == 0 {
+		t.Fatal("Expected the capture to be in progress")
+	}
+
+	cart.Tick(1)
+	if val := cart.Read(0xa000); val&CAMERA_CAPTURE != 0 {
+		t.Fatal("Expected the capture to be done")
+	}
+
+	cart.Write(0x4000, 0x00)
+	for _, tt := range []struct {
+		testName string
+		tile     int
+		expected [2]uint8
+	}{
+		{testName: "Black", tile: 0, expected: [2]uint8{0xff, 0xff}},
+		{testName: "Dark gray", tile: 4, expected: [2]uint8{0x00, 0xff}},
+		{testName: "Light gray", tile: 8, expected: [2]uint8{0xff, 0x00}},
+		{testName: "White", tile: 15, expected: [2]uint8{0x00, 0x00}},
+		{testName: "Last row", tile: 13*16 + 1, expected: [2]uint8{0xff, 0xff}},
+	} {
+		t.Log(tt.testName)
+
+		// The last line of each tile
+		addr := EXT_RAM_ADDR + CAMERA_IMAGE_ADDR + uint16(tt.tile)*16 + 14
+		if line := [2]uint8{cart.Read(addr), cart.Read(addr + 1)}; line != tt.expected {
+			t.Errorf("%s: expected %#02x in tile %d, got %#02x", tt.testName, tt.expected, tt.tile, line)
+		}
+	}
+}
+
+func TestCameraRam(t *testing.T) {
+	cart := newTestCartridge(t, makeRom(0xfc, 0x05, 0x04))
+
+	cart.Write(0x4000, 0x0f)
+	cart.Write(0xa000, 0x12)
+	if val := cart.Read(0xa000); val != 0x00 {
+		t.Errorf("Expected writes to need enabling, got %#02x", val)
+	}
+
+	cart.Write(0x0000, 0x0a)
+	cart.Write(0xa000, 0x12)
+	if val := cart.ram[15*RAM_BANK_SIZE]; val != 0x12 {
+		t.Errorf("Expected 0x12 in RAM bank 15, got %#02x", val)
+	}
+
+	// Without a source the image is blank
+	cart.Write(0x4000, CAMERA_REG_SELECT)
+	cart.Write(0xa000, CAMERA_CAPTURE)
+	cart.Tick(CAMERA_CAPTURE_CYCLES)
+	cart.Write(0x4000, 0x00)
+	for addr := uint16(0xa100); addr < 0xa100+CAMERA_WIDTH*CAMERA_HEIGHT/4; addr++ {
+		if val := cart.Read(addr); val != 0x00 {
+			t.Fatalf("Expected a white image, got %#02x at %#04x", val, addr)
+		}
+	}
+}
+
+func TestCameraWithoutRam(t *testing.T) {
+	cart := newTestCartridge(t, makeRom(0xfc, 0x05, 0x00))
+
+	cart.Write(0x0000, 0x0a)
+	cart.Write(0xa000, 0x12)
+	if val := cart.Read(0xa000); val != 0xff {
+		t.Errorf("Expected 0xff without RAM, got %#02x", val)
+	}
+
+	// A capture completes without storing the picture anywhere
+	cart.Write(0x4000, CAMERA_REG_SELECT)
+	cart.Write(0xa000, CAMERA_CAPTURE)
+	cart.Tick(CAMERA_CAPTURE_CYCLES)
+	if val := cart.Read(0xa000); val&CAMERA_CAPTURE != 0 {
+		t.Errorf("Expected the capture to be done")
+	}
+}
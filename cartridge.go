@@ -0,0 +1,477 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Cartridge header layout
+const (
+	HEADER_LOGO            = 0x0104
+	HEADER_TITLE           = 0x0134
+	HEADER_MANUFACTURER    = 0x013f
+	HEADER_CGB_FLAG        = 0x0143
+	HEADER_NEW_LICENSEE    = 0x0144
+	HEADER_SGB_FLAG        = 0x0146
+	HEADER_TYPE            = 0x0147
+	HEADER_ROM_SIZE        = 0x0148
+	HEADER_RAM_SIZE        = 0x0149
+	HEADER_OLD_LICENSEE    = 0x014b
+	HEADER_VERSION         = 0x014c
+	HEADER_CHECKSUM        = 0x014d
+	HEADER_GLOBAL_CHECKSUM = 0x014e
+	HEADER_END             = 0x0150
+
+	ROM_BANK_SIZE = 0x4000
+	RAM_BANK_SIZE = 0x2000
+
+	// Old licensee code meaning the new one is used instead
+	USE_NEW_LICENSEE = 0x33
+)
+
+// CGB flag values
+const (
+	CGB_SUPPORTED = 0x80
+	CGB_ONLY      = 0xc0
+)
+
+// The logo the boot ROM compares against before starting a cartridge
+var nintendoLogo = [48]uint8{
+	0xce, 0xed, 0x66, 0x66, 0xcc, 0x0d, 0x00, 0x0b, 0x03, 0x73, 0x00, 0x83, 0x00, 0x0c, 0x00, 0x0d,
+	0x00, 0x08, 0x11, 0x1f, 0x88, 0x89, 0x00, 0x0e, 0xdc, 0xcc, 0x6e, 0xe6, 0xdd, 0xdd, 0xd9, 0x99,
+	0xbb, 0xbb, 0x67, 0x63, 0x6e, 0x0e, 0xec, 0xcc, 0xdd, 0xdc, 0x99, 0x9f, 0xbb, 0xb9, 0x33, 0x3e,
+}
+
+var cartridgeTypes = map[uint8]string{
+	0x00: "ROM ONLY",
+	0x01: "MBC1",
+	0x02: "MBC1+RAM",
+	0x03: "MBC1+RAM+BATTERY",
+	0x05: "MBC2",
+	0x06: "MBC2+BATTERY",
+	0x08: "ROM+RAM",
+	0x09: "ROM+RAM+BATTERY",
+	0x0b: "MMM01",
+	0x0c: "MMM01+RAM",
+	0x0d: "MMM01+RAM+BATTERY",
+	0x0f: "MBC3+TIMER+BATTERY",
+	0x10: "MBC3+TIMER+RAM+BATTERY",
+	0x11: "MBC3",
+	0x12: "MBC3+RAM",
+	0x13: "MBC3+RAM+BATTERY",
+	0x19: "MBC5",
+	0x1a: "MBC5+RAM",
+	0x1b: "MBC5+RAM+BATTERY",
+	0x1c: "MBC5+RUMBLE",
+	0x1d: "MBC5+RUMBLE+RAM",
+	0x1e: "MBC5+RUMBLE+RAM+BATTERY",
+	0x20: "MBC6",
+	0x22: "MBC7+SENSOR+RUMBLE+RAM+BATTERY",
+	0xfc: "POCKET CAMERA",
+	0xfd: "BANDAI TAMA5",
+	0xfe: "HuC3",
+	0xff: "HuC1+RAM+BATTERY",
+}
+
+// Cartridge types whose state persists when switched off
+var batteryTypes = map[uint8]bool{
+	0x03: true, 0x06: true, 0x09: true, 0x0d: true, 0x0f: true, 0x10: true, 0x13: true,
+	0x1b: true, 0x1e: true, 0x20: true, 0x22: true, 0xfc: true, 0xfe: true, 0xff: true,
+}
+
+// ROM sizes in banks, indexed by the header code
+var romBanks = map[uint8]int{
+	0x00: 2, 0x01: 4, 0x02: 8, 0x03: 16, 0x04: 32, 0x05: 64, 0x06: 128, 0x07: 256, 0x08: 512,
+	0x52: 72, 0x53: 80, 0x54: 96,
+}
+
+// RAM sizes in bytes, indexed by the header code
+var ramSizes = map[uint8]int{
+	0x00: 0, 0x01: 0x800, 0x02: 0x2000, 0x03: 0x8000, 0x04: 0x20000, 0x05: 0x10000,
+}
+
+var (
+	ErrRomTruncated    = errors.New("ROM image is truncated")
+	ErrRomSizeMismatch = errors.New("ROM image size does not match its header")
+	ErrBadHeader       = errors.New("invalid cartridge header")
+	ErrBadLogo         = errors.New("logo does not match")
+	ErrHeaderChecksum  = errors.New("header checksum mismatch")
+	ErrGlobalChecksum  = errors.New("global checksum mismatch")
+	ErrUnsupportedType = errors.New("unsupported cartridge type")
+)
+
+type Header struct {
+	Title        string
+	Manufacturer string // Only present in later CGB cartridges
+	CgbFlag      uint8
+	SgbFlag      uint8
+	Type         uint8
+	RomSize      int // In bytes
+	RamSize      int // In bytes
+	Licensee     string
+	Version      uint8
+}
+
+// Whether the cartridge runs on the CGB in color mode
+func (h *Header) Cgb() bool {
+	return h.CgbFlag&CGB_SUPPORTED != 0
+}
+
+// Whether the cartridge makes use of SGB functions
+func (h *Header) Sgb() bool {
+	return h.SgbFlag == 0x03
+}
+
+// Whether the cartridge keeps its RAM, flash or clock running off a
+// battery, and so needs saving
+func (h *Header) Battery() bool {
+	return batteryTypes[h.Type]
+}
+
+func (h *Header) TypeName() string {
+	return cartridgeTypes[h.Type]
+}
+
+type Cartridge struct {
+	Header Header
+	rom    []uint8
+	ram    []uint8
+
+	// Problems with the image that the hardware does not check, so the
+	// cartridge still loads
+	Warnings []error
+
+	// Memory bank controller mapping ROM and RAM into the address space
+	mbc   Mem
+	clock cartClock // Nil for cartridges without a clock
+
+	// Save file of battery-backed cartridges, empty when not saving
+	savePath    string
+	dirty       bool // RAM was written since the last flush
+	flushCycles int  // Since the last flush
+}
+
+// Clock hardware running off the cartridge battery, whose state is
+// saved after the RAM
+type cartClock interface {
+	tick(cycles int)
+	marshal() []uint8
+	// Fails without changing the clock when the data is not in the
+	// expected format
+	unmarshal(data []uint8) error
+}
+
+// Controllers with hardware that runs along with the CPU
+type cartTicker interface {
+	tick(cycles int)
+}
+
+// Loads and validates a .gb or .gbc ROM image
+func LoadCartridge(path string) (*Cartridge, error) {
+	rom, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cart, err := NewCartridge(rom)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if cart.Header.Battery() {
+		if err := cart.openSave(savePath(path)); err != nil {
+			return nil, err
+		}
+	}
+
+	return cart, nil
+}
+
+// Validates a ROM image and parses its header
+func NewCartridge(rom []uint8) (*Cartridge, error) {
+	if len(rom) < HEADER_END {
+		return nil, fmt.Errorf("%w: %d bytes is shorter than the header", ErrRomTruncated, len(rom))
+	}
+
+	offset := headerBase(rom)
+	base := rom[offset:]
+	if !hasLogo(base) {
+		return nil, ErrBadLogo
+	}
+
+	if sum := headerChecksum(base); sum != base[HEADER_CHECKSUM] {
+		return nil, fmt.Errorf("%w: expected $%02X, got $%02X", ErrHeaderChecksum, base[HEADER_CHECKSUM], sum)
+	}
+
+	header, err := parseHeader(base)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rom) < header.RomSize {
+		return nil, fmt.Errorf("%w: %d bytes, header declares %d", ErrRomTruncated, len(rom), header.RomSize)
+	}
+
+	cart := &Cartridge{Header: header, rom: rom, ram: make([]uint8, header.RamSize)}
+
+	if len(rom) > header.RomSize {
+		cart.Warnings = append(cart.Warnings,
+			fmt.Errorf("%w: %d bytes, header declares %d", ErrRomSizeMismatch, len(rom), header.RomSize))
+	}
+
+	expected := uint16(base[HEADER_GLOBAL_CHECKSUM])<<8 | uint16(base[HEADER_GLOBAL_CHECKSUM+1])
+	if sum := globalChecksum(rom, offset); sum != expected {
+		cart.Warnings = append(cart.Warnings,
+			fmt.Errorf("%w: expected $%04X, got $%04X", ErrGlobalChecksum, expected, sum))
+	}
+
+	switch header.Type {
+	case 0x00, 0x08, 0x09:
+		cart.mbc = &romOnly{rom: rom, ram: cart.ram}
+	case 0x01, 0x02, 0x03:
+		cart.mbc = newMbc1(rom, cart.ram)
+	case 0x05, 0x06:
+		// The RAM is built into the controller, and not in the header
+		cart.ram = make([]uint8, MBC2_RAM_SIZE)
+		cart.mbc = newMbc2(rom, cart.ram)
+	case 0x0b, 0x0c, 0x0d:
+		cart.mbc = newMmm01(rom, cart.ram)
+	case 0x0f, 0x10:
+		rtc := newRtc()
+		cart.clock = rtc
+		cart.mbc = newMbc3(rom, cart.ram, rtc)
+	case 0x11, 0x12, 0x13:
+		cart.mbc = newMbc3(rom, cart.ram, nil)
+	case 0x19, 0x1a, 0x1b:
+		cart.mbc = newMbc5(rom, cart.ram, false)
+	case 0x1c, 0x1d, 0x1e:
+		cart.mbc = newMbc5(rom, cart.ram, true)
+	case 0x20:
+		// Flash is saved along with the RAM
+		cart.ram = make([]uint8, header.RamSize+MBC6_FLASH_SIZE)
+		cart.mbc = newMbc6(rom, cart.ram[:header.RamSize], cart.ram[header.RamSize:])
+	case 0x22:
+		cart.ram = make([]uint8, EEPROM_SIZE)
+		cart.mbc = newMbc7(rom, cart.ram)
+	case 0xfc:
+		cart.mbc = newCamera(rom, cart.ram)
+	case 0xfe:
+		rtc := newHuc3Rtc()
+		cart.clock = rtc
+		cart.mbc = newHuc3(rom, cart.ram, rtc)
+	case 0xff:
+		cart.mbc = newHuc1(rom, cart.ram)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedType, header.TypeName())
+	}
+
+	return cart, nil
+}
+
+// MMM01 multicarts keep their own header along with the menu in the
+// last 32KB of the ROM, while the first bank holds the header of a game
+func headerBase(rom []uint8) int {
+	base := len(rom) - 2*ROM_BANK_SIZE
+	if base <= 0 {
+		return 0
+	}
+
+	if menu := rom[base:]; hasLogo(menu) && menu[HEADER_TYPE] >= 0x0b && menu[HEADER_TYPE] <= 0x0d {
+		return base
+	}
+
+	return 0
+}
+
+func hasLogo(rom []uint8) bool {
+	return [48]uint8(rom[HEADER_LOGO:HEADER_LOGO+len(nintendoLogo)]) == nintendoLogo
+}
+
+func parseHeader(rom []uint8) (Header, error) {
+	h := Header{
+		CgbFlag: rom[HEADER_CGB_FLAG],
+		SgbFlag: rom[HEADER_SGB_FLAG],
+		Type:    rom[HEADER_TYPE],
+		Version: rom[HEADER_VERSION],
+	}
+
+	// The CGB flag and manufacturer code took over the end of the title
+	titleEnd := HEADER_CGB_FLAG + 1
+	if h.Cgb() {
+		titleEnd = HEADER_CGB_FLAG
+		if code := string(rom[HEADER_MANUFACTURER:HEADER_CGB_FLAG]); isManufacturerCode(code) {
+			h.Manufacturer = code
+			titleEnd = HEADER_MANUFACTURER
+		}
+	}
+	h.Title = strings.TrimRight(string(rom[HEADER_TITLE:titleEnd]), "\x00 ")
+
+	if _, ok := cartridgeTypes[h.Type]; !ok {
+		return h, fmt.Errorf("%w: unknown cartridge type $%02X", ErrBadHeader, h.Type)
+	}
+
+	banks, ok := romBanks[rom[HEADER_ROM_SIZE]]
+	if !ok {
+		return h, fmt.Errorf("%w: unknown ROM size code $%02X", ErrBadHeader, rom[HEADER_ROM_SIZE])
+	}
+	h.RomSize = banks * ROM_BANK_SIZE
+
+	if h.RamSize, ok = ramSizes[rom[HEADER_RAM_SIZE]]; !ok {
+		return h, fmt.Errorf("%w: unknown RAM size code $%02X", ErrBadHeader, rom[HEADER_RAM_SIZE])
+	}
+
+	if old := rom[HEADER_OLD_LICENSEE]; old == USE_NEW_LICENSEE {
+		h.Licensee = string(rom[HEADER_NEW_LICENSEE : HEADER_NEW_LICENSEE+2])
+	} else {
+		h.Licensee = fmt.Sprintf("%02X", old)
+	}
+
+	return h, nil
+}
+
+// Manufacturer codes are four uppercase letters or digits
+func isManufacturerCode(code string) bool {
+	for _, c := range code {
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Computed by the boot ROM over the title through the version
+func headerChecksum(rom []uint8) uint8 {
+	var sum uint8
+	for _, b := range rom[HEADER_TITLE:HEADER_CHECKSUM] {
+		sum = sum - b - 1
+	}
+
+	return sum
+}
+
+// Sum of every byte of the image but the global checksum itself, found
+// in the header at the given offset
+func globalChecksum(rom []uint8, header int) uint16 {
+	at := header + HEADER_GLOBAL_CHECKSUM
+	var sum uint16
+	for i, b := range rom {
+		if i != at && i != at+1 {
+			sum += uint16(b)
+		}
+	}
+
+	return sum
+}
+
+func (c *Cartridge) Read(addr uint16) uint8 {
+	return c.mbc.Read(addr)
+}
+
+func (c *Cartridge) Write(addr uint16, val uint8) {
+	c.mbc.Write(addr, val)
+
+	// Writes to the RAM area also cover registers such as the RTC and
+	// EEPROM, which are saved as well. MBC6 flash is written through
+	// the ROM area.
+	_, flash := c.mbc.(*mbc6)
+	if addr >= EXT_RAM_ADDR && addr < WRAM_ADDR || flash && addr >= ROM_BANK_SIZE && addr < VRAM_ADDR {
+		c.dirty = true
+	}
+}
+
+// Sets the function called whenever the rumble motor is switched on or
+// off. Only rumble cartridges ever call it.
+func (c *Cartridge) SetRumble(rumble func(on bool)) {
+	if m, ok := c.mbc.(*mbc5); ok {
+		m.rumble = rumble
+	}
+}
+
+// Sets the tilt reported by the MBC7 accelerometer, in g along each
+// axis
+func (c *Cartridge) SetAccelerometer(x, y float64) {
+	if m, ok := c.mbc.(*mbc7); ok {
+		m.tiltX, m.tiltY = x, y
+	}
+}
+
+// Connects the infrared port of HuC1 and HuC3 cartridges
+func (c *Cartridge) SetInfrared(ir Infrared) {
+	switch m := c.mbc.(type) {
+	case *huc1:
+		m.ir = ir
+	case *huc3:
+		m.ir = ir
+	}
+}
+
+// Sets where the Pocket Camera takes its pictures from
+func (c *Cartridge) SetCameraSource(source CameraSource) {
+	if m, ok := c.mbc.(*camera); ok {
+		m.source = source
+	}
+}
+
+// Runs the cartridge hardware that keeps time, such as the RTC, for the
+// given number of CPU cycles
+func (c *Cartridge) Tick(cycles int) {
+	if c.clock != nil {
+		c.clock.tick(cycles)
+	}
+
+	if m, ok := c.mbc.(cartTicker); ok {
+		m.tick(cycles)
+	}
+
+	if c.savePath != "" {
+		c.flushCycles += cycles
+		if c.flushCycles >= SAVE_FLUSH_CYCLES {
+			c.flushCycles = 0
+			// Failures leave the RAM dirty, to be retried and reported
+			// by Close
+			if c.dirty {
+				c.Flush()
+			}
+		}
+	}
+}
+
+// Reads a byte from a ROM bank, wrapping bank numbers past the end of
+// the ROM as the unconnected address lines do
+func romByte(rom []uint8, bank int, offset uint16) uint8 {
+	bank %= len(rom) / ROM_BANK_SIZE
+	return rom[bank*ROM_BANK_SIZE+int(offset)]
+}
+
+// Offset into cartridge RAM of an address in a RAM bank. RAM smaller
+// than a bank is mirrored across it.
+func ramOffset(ram []uint8, bank int, addr uint16) int {
+	return (bank*RAM_BANK_SIZE + int(addr-EXT_RAM_ADDR)) % len(ram)
+}
+
+// Cartridges without a controller, mapping 32KB of ROM and up to 8KB
+// of RAM directly
+type romOnly struct {
+	rom, ram []uint8
+}
+
+func (m *romOnly) Read(addr uint16) uint8 {
+	switch {
+	case addr < VRAM_ADDR:
+		return m.rom[addr]
+	case addr >= EXT_RAM_ADDR && addr < WRAM_ADDR && len(m.ram) > 0:
+		return m.ram[ramOffset(m.ram, 0, addr)]
+	}
+
+	return 0xff
+}
+
+// Only RAM can be written to
+func (m *romOnly) Write(addr uint16, val uint8) {
+	if addr >= EXT_RAM_ADDR && addr < WRAM_ADDR && len(m.ram) > 0 {
+		m.ram[ramOffset(m.ram, 0, addr)] = val
+	}
+}
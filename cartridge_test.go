@@ -0,0 +1,269 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Builds a valid ROM image of the given type and size codes
+func makeRom(cartType, romCode, ramCode uint8) []uint8 {
+	rom := make([]uint8, romBanks[romCode]*ROM_BANK_SIZE)
+	copy(rom[HEADER_LOGO:], nintendoLogo[:])
+	copy(rom[HEADER_TITLE:], "TEST")
+	rom[HEADER_TYPE] = cartType
+	rom[HEADER_ROM_SIZE] = romCode
+	rom[HEADER_RAM_SIZE] = ramCode
+
+	// Tag each bank with its number so that banking can be checked
+	for bank := 0; bank < len(rom)/ROM_BANK_SIZE; bank++ {
+		rom[bank*ROM_BANK_SIZE+ROM_BANK_SIZE-1] = uint8(bank)
+	}
+
+	fixChecksums(rom)
+	return rom
+}
+
+func fixChecksums(rom []uint8) {
+	offset := headerBase(rom)
+	header := rom[offset:]
+	header[HEADER_CHECKSUM] = headerChecksum(header)
+
+	sum := globalChecksum(rom, offset)
+	header[HEADER_GLOBAL_CHECKSUM] = uint8(sum >> 8)
+	header[HEADER_GLOBAL_CHECKSUM+1] = uint8(sum)
+}
+
+func TestParseHeader(t *testing.T) {
+	rom := makeRom(0x03, 0x05, 0x03)
+	copy(rom[HEADER_TITLE:], "POKEMON YELLOW\x00\x00")
+	rom[HEADER_CGB_FLAG] = 0x00
+	rom[HEADER_SGB_FLAG] = 0x03
+	rom[HEADER_OLD_LICENSEE] = 0x01
+	rom[HEADER_VERSION] = 0x01
+	fixChecksums(rom)
+
+	cart, err := NewCartridge(rom)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := Header{
+		Title:    "POKEMON YELLOW",
+		SgbFlag:  0x03,
+		Type:     0x03,
+		RomSize:  0x100000,
+		RamSize:  0x8000,
+		Licensee: "01",
+		Version:  0x01,
+	}
+	if cart.Header != expected {
+		t.Errorf("Expected %+v, got %+v", expected, cart.Header)
+	}
+
+	if !cart.Header.Sgb() || cart.Header.Cgb() || cart.Header.TypeName() != "MBC1+RAM+BATTERY" {
+		t.Errorf("Unexpected flags for %+v", cart.Header)
+	}
+}
+
+func TestParseCgbHeader(t *testing.T) {
+	for _, tt := range []struct {
+		testName             string
+		title                string
+		cgbFlag              uint8
+		expectedTitle        string
+		expectedManufacturer string
+	}{
+		{
+			testName:             "With manufacturer code",
+			title:                "ZELDA\x00\x00\x00\x00\x00\x00AZ7E",
+			cgbFlag:              CGB_SUPPORTED,
+			expectedTitle:        "ZELDA",
+			expectedManufacturer: "AZ7E",
+		},
+		{
+			testName:      "Without manufacturer code",
+			title:         "LONGER TITLE\x00\x00\x00",
+			cgbFlag:       CGB_ONLY,
+			expectedTitle: "LONGER TITLE",
+		},
+	} {
+		t.Log(tt.testName)
+
+		rom := makeRom(0x00, 0x00, 0x00)
+		copy(rom[HEADER_TITLE:], tt.title)
+		rom[HEADER_CGB_FLAG] = tt.cgbFlag
+		rom[HEADER_OLD_LICENSEE] = USE_NEW_LICENSEE
+		copy(rom[HEADER_NEW_LICENSEE:], "01")
+		fixChecksums(rom)
+
+		cart, err := NewCartridge(rom)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.testName, err)
+		}
+
+		h := cart.Header
+		if h.Title != tt.expectedTitle || h.Manufacturer != tt.expectedManufacturer || !h.Cgb() || h.Licensee != "01" {
+			t.Errorf("%s: unexpected header %+v", tt.testName, h)
+		}
+	}
+}
+
+func TestCartridgeValidation(t *testing.T) {
+	for _, tt := range []struct {
+		testName string
+		corrupt  func(rom []uint8) []uint8
+		expected error
+		warning  error // Loaded anyway
+	}{
+		{
+			testName: "Shorter than the header",
+			corrupt:  func(rom []uint8) []uint8 { return rom[:0x100] },
+			expected: ErrRomTruncated,
+		},
+		{
+			testName: "Shorter than the declared size",
+			corrupt:  func(rom []uint8) []uint8 { return rom[:ROM_BANK_SIZE] },
+			expected: ErrRomTruncated,
+		},
+		{
+			testName: "Larger than the declared size",
+			corrupt:  func(rom []uint8) []uint8 { return append(rom, make([]uint8, ROM_BANK_SIZE)...) },
+			warning:  ErrRomSizeMismatch,
+		},
+		{
+			testName: "Bad logo",
+			corrupt: func(rom []uint8) []uint8 {
+				rom[HEADER_LOGO] ^= 0xff
+				return rom
+			},
+			expected: ErrBadLogo,
+		},
+		{
+			testName: "Bad header checksum",
+			corrupt: func(rom []uint8) []uint8 {
+				rom[HEADER_CHECKSUM]++
+				return rom
+			},
+			expected: ErrHeaderChecksum,
+		},
+		{
+			testName: "Bad global checksum",
+			corrupt: func(rom []uint8) []uint8 {
+				rom[0x200]++
+				return rom
+			},
+			warning: ErrGlobalChecksum,
+		},
+		{
+			testName: "Unknown cartridge type",
+			corrupt: func(rom []uint8) []uint8 {
+				rom[HEADER_TYPE] = 0x04
+				fixChecksums(rom)
+				return rom
+			},
+			expected: ErrBadHeader,
+		},
+		{
+			testName: "Unsupported cartridge type",
+			corrupt: func(rom []uint8) []uint8 {
+				rom[HEADER_TYPE] = 0xfd
+				fixChecksums(rom)
+				return rom
+			},
+			expected: ErrUnsupportedType,
+		},
+		{
+			testName: "Unknown ROM size",
+			corrupt: func(rom []uint8) []uint8 {
+				rom[HEADER_ROM_SIZE] = 0x09
+				fixChecksums(rom)
+				return rom
+			},
+			expected: ErrBadHeader,
+		},
+		{
+			testName: "Unknown RAM size",
+			corrupt: func(rom []uint8) []uint8 {
+				rom[HEADER_RAM_SIZE] = 0x06
+				fixChecksums(rom)
+				return rom
+			},
+			expected: ErrBadHeader,
+		},
+	} {
+		t.Log(tt.testName)
+
+		cart, err := NewCartridge(tt.corrupt(makeRom(0x00, 0x00, 0x00)))
+		if !errors.Is(err, tt.expected) {
+			t.Errorf("%s: expected %v, got %v", tt.testName, tt.expected, err)
+		}
+
+		if tt.warning != nil && (cart == nil || len(cart.Warnings) != 1 || !errors.Is(cart.Warnings[0], tt.warning)) {
+			t.Errorf("%s: expected to load with warning %v, got %v", tt.testName, tt.warning, cart)
+		}
+	}
+}
+
+func TestLoadCartridge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.gb")
+	if err := os.WriteFile(path, makeRom(0x00, 0x00, 0x00), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cart, err := LoadCartridge(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if cart.Header.Title != "TEST" {
+		t.Errorf("Expected title TEST, got %q", cart.Header.Title)
+	}
+
+	if len(cart.Warnings) != 0 {
+		t.Errorf("Expected no warnings, got %v", cart.Warnings)
+	}
+
+	if _, err := LoadCartridge(filepath.Join(t.TempDir(), "missing.gb")); err == nil {
+		t.Errorf("Expected an error loading a missing file")
+	}
+}
+
+func TestCartridgeMapping(t *testing.T) {
+	rom := makeRom(0x00, 0x00, 0x00)
+	rom[0x0150] = 0xc3
+	fixChecksums(rom)
+
+	cart, err := NewCartridge(rom)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	m := NewMmu(cart)
+	m.Write(0x0150, 0x00)
+
+	if m.Read(0x0150) != 0xc3 || m.Read(0x3fff) != 0 || m.Read(0x7fff) != 1 {
+		t.Errorf("Expected the ROM to be mapped at 0x0000-0x7fff")
+	}
+
+	if m.Read(0xa000) != 0xff {
+		t.Errorf("Expected no cartridge RAM, got %#02x", m.Read(0xa000))
+	}
+}
+
+func TestRomRamCartridge(t *testing.T) {
+	cart := newTestCartridge(t, makeRom(0x09, 0x00, 0x02))
+
+	// RAM is always enabled and ROM cannot be written to
+	cart.Write(0xa123, 0x5a)
+	cart.Write(0x0150, 0x5a)
+
+	if val := cart.Read(0xa123); val != 0x5a {
+		t.Errorf("Expected RAM to be mapped, got %#02x", val)
+	}
+
+	if val := cart.Read(0x0150); val != 0x00 {
+		t.Errorf("Expected ROM to be read-only, got %#02x", val)
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+// CGB registers
+const (
+	VBK_ADDR  = 0xff4f // VRAM bank
+	BCPS_ADDR = 0xff68 // Background palette index
+	BCPD_ADDR = 0xff69 // Background palette data
+	OCPS_ADDR = 0xff6a // Sprite palette index
+	OCPD_ADDR = 0xff6b // Sprite palette data
+	SVBK_ADDR = 0xff70 // WRAM bank
+
+	VRAM_BANKS     = 2
+	WRAM_BANKS     = 8
+	WRAM_BANK_SIZE = 0x1000
+
+	// 8 palettes of 4 RGB555 colors, for each of the background and
+	// sprites
+	PALETTE_RAM_SIZE = 64
+
+	// Palette index bits: the index into palette RAM, and whether
+	// writes to the data register advance it
+	PALETTE_INDEX     = 0x3f
+	PALETTE_INCREMENT = BIT_7
+)
+
+// Palette RAM, accessed through an index and a data register
+type paletteRam struct {
+	data  [PALETTE_RAM_SIZE]uint8
+	index uint8 // As written, including PALETTE_INCREMENT
+}
+
+// RGB555 color of a color number in one of the palettes
+func (r *paletteRam) color(palette, color uint8) uint16 {
+	i := int(palette)*8 + int(color)*2
+	return (uint16(r.data[i]) | uint16(r.data[i+1])<<8) & 0x7fff
+}
+
+// Writes the data register, advancing the index if set to. The index
+// also advances when the PPU keeps the write from landing.
+func (r *paletteRam) write(val uint8, blocked bool) {
+	if !blocked {
+		r.data[r.index&PALETTE_INDEX] = val
+	}
+
+	if r.index&PALETTE_INCREMENT != 0 {
+		r.index = r.index&PALETTE_INCREMENT | (r.index+1)&PALETTE_INDEX
+	}
+}
+
+// Switches the hardware between CGB and DMG mode. PostBoot selects the
+// mode from the CGB flag in the cartridge header, as does the CGB boot
+// ROM when it is unmapped, and calling this afterwards forces either one.
+func SetCgbMode(cgb bool, cpu *Cpu, mmu *Mmu) {
+	cpu.cgb = cgb
+	mmu.cgb = cgb
+}
+
+// Implemented by buses that select the mode themselves, when running a
+// boot ROM
+type cgbModeBus interface {
+	cgbMode() bool
+}
+
+func (m *Mmu) cgbMode() bool {
+	return m.cgb
+}
+
+// Implemented by buses on which KEY1 cannot be written with the speed
+type speedSwitchBus interface {
+	switchSpeed(double bool)
+}
+
+// Shows the new speed in KEY1, clearing the prepared switch
+func (m *Mmu) switchSpeed(double bool) {
+	m.io[KEY1_ADDR-IO_ADDR] = uint8(flag(double)) << 7
+}
+
+// Whether the CPU runs in CGB mode, which the bus decides if it can
+func (cpu *Cpu) cgbMode() bool {
+	if bus, ok := cpu.m.(cgbModeBus); ok {
+		return bus.cgbMode()
+	}
+
+	return cpu.cgb
+}
+
+// VRAM bank the CPU accesses, only ever 0 in DMG mode
+func (m *Mmu) vramBank() int {
+	if !m.cgb {
+		return 0
+	}
+
+	return int(m.io[VBK_ADDR-IO_ADDR] & BIT_0)
+}
+
+// WRAM bank mapped at 0xd000, where bank 0 selects bank 1 and DMG mode
+// only has bank 1
+func (m *Mmu) wramBank() int {
+	if !m.cgb {
+		return 1
+	}
+
+	return max(1, int(m.io[SVBK_ADDR-IO_ADDR]&0x07))
+}
+
+func (m *Mmu) vramOffset(addr uint16) int {
+	return m.vramBank()*VRAM_SIZE + int(addr-VRAM_ADDR)
+}
+
+// Offset into WRAM of an address in 0xc000-0xdfff
+func (m *Mmu) wramOffset(addr uint16) int {
+	offset := int(addr - WRAM_ADDR)
+	if offset >= WRAM_BANK_SIZE {
+		offset += (m.wramBank() - 1) * WRAM_BANK_SIZE
+	}
+
+	return offset
+}
+
+// Whether the PPU keeps the CPU from accessing palette RAM, which it
+// reads from while drawing
+func (m *Mmu) paletteBlocked() bool {
+	return m.ppu != nil && m.ppu.enabled && m.ppu.mode == MODE_DRAWING
+}
+
+// Reads a CGB register, returning false for other addresses and for
+// all of them in DMG mode, where they read as unmapped
+func (m *Mmu) readCgbIo(addr uint16) (uint8, bool) {
+	if !m.cgb {
+		return 0, false
+	}
+
+	switch addr {
+	case VBK_ADDR:
+		return 0xfe | uint8(m.vramBank()), true
+	case SVBK_ADDR:
+		return 0xf8 | m.io[SVBK_ADDR-IO_ADDR]&0x07, true
+	case KEY1_ADDR:
+		// The current speed in bit 7 and whether a switch is prepared
+		return 0x7e | m.io[KEY1_ADDR-IO_ADDR]&(BIT_7|BIT_0), true
+	case BCPS_ADDR:
+		return m.bgPalettes.index | BIT_6, true
+	case OCPS_ADDR:
+		return m.objPalettes.index | BIT_6, true
+	case BCPD_ADDR, OCPD_ADDR:
+		palettes := &m.bgPalettes
+		if addr == OCPD_ADDR {
+			palettes = &m.objPalettes
+		}
+		if m.paletteBlocked() {
+			return 0xff, true
+		}
+
+		return palettes.data[palettes.index&PALETTE_INDEX], true
+	}
+
+	return 0, false
+}
+
+// Writes a CGB register, returning false for other addresses and for
+// all of them in DMG mode, where writes to them are ignored
+func (m *Mmu) writeCgbIo(addr uint16, val uint8) bool {
+	switch addr {
+	case VBK_ADDR, SVBK_ADDR:
+		if m.cgb {
+			m.io[addr-IO_ADDR] = val
+		}
+	case KEY1_ADDR:
+		// Only preparing a switch is writable, the speed changes on STOP
+		if m.cgb {
+			m.io[addr-IO_ADDR] = m.io[addr-IO_ADDR]&BIT_7 | val&BIT_0
+		}
+	case BCPS_ADDR:
+		if m.cgb {
+			m.bgPalettes.index = val &^ BIT_6
+		}
+	case OCPS_ADDR:
+		if m.cgb {
+			m.objPalettes.index = val &^ BIT_6
+		}
+	case BCPD_ADDR:
+		if m.cgb {
+			m.bgPalettes.write(val, m.paletteBlocked())
+		}
+	case OCPD_ADDR:
+		if m.cgb {
+			m.objPalettes.write(val, m.paletteBlocked())
+		}
+	default:
+		return false
+	}
+
+	return true
+}
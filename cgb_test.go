@@ -0,0 +1,216 @@
+package main
+
+import "testing"
+
+func newCgbMmu() *Mmu {
+	m := NewMmu(nil)
+	m.cgb = true
+
+	return m
+}
+
+func TestCgbVramBanks(t *testing.T) {
+	m := newCgbMmu()
+
+	m.Write(0x8000, 0x11)
+	m.Write(VBK_ADDR, 1)
+	m.Write(0x8000, 0x22)
+
+	if val := m.Read(0x8000); val != 0x22 {
+		t.Errorf("Expected 0x22 from bank 1, got %#02x", val)
+	}
+	if val := m.Read(VBK_ADDR); val != 0xff {
+		t.Errorf("Expected VBK to read 0xff, got %#02x", val)
+	}
+
+	m.Write(VBK_ADDR, 0xfe)
+	if val := m.Read(0x8000); val != 0x11 {
+		t.Errorf("Expected 0x11 from bank 0, got %#02x", val)
+	}
+	if val := m.Read(VBK_ADDR); val != 0xfe {
+		t.Errorf("Expected VBK to read 0xfe, got %#02x", val)
+	}
+}
+
+func TestCgbWramBanks(t *testing.T) {
+	m := newCgbMmu()
+
+	for bank := uint8(1); bank < WRAM_BANKS; bank++ {
+		m.Write(SVBK_ADDR, bank)
+		m.Write(0xd000, bank*0x10)
+	}
+	m.Write(0xc000, 0x99)
+
+	for _, tt := range []struct {
+		testName string
+		svbk     uint8
+		addr     uint16
+		expected uint8
+	}{
+		{testName: "Bank 0 is fixed", svbk: 5, addr: 0xc000, expected: 0x99},
+		{testName: "Bank 3", svbk: 3, addr: 0xd000, expected: 0x30},
+		{testName: "Bank 7", svbk: 7, addr: 0xd000, expected: 0x70},
+		{testName: "Selecting bank 0 maps bank 1", svbk: 0, addr: 0xd000, expected: 0x10},
+		{testName: "Upper bits ignored", svbk: 0xfa, addr: 0xd000, expected: 0x20},
+		{testName: "Echo of the switched bank", svbk: 6, addr: 0xf000, expected: 0x60},
+	} {
+		t.Log(tt.testName)
+
+		m.Write(SVBK_ADDR, tt.svbk)
+		if val := m.Read(tt.addr); val != tt.expected {
+			t.Errorf("%s: expected %#02x, got %#02x", tt.testName, tt.expected, val)
+		}
+		if val := m.Read(SVBK_ADDR); val != 0xf8|tt.svbk&0x07 {
+			t.Errorf("%s: expected SVBK to read %#02x, got %#02x", tt.testName, 0xf8|tt.svbk&0x07, val)
+		}
+	}
+}
+
+func TestCgbPaletteRam(t *testing.T) {
+	for _, tt := range []struct {
+		testName string
+		index    uint16
+		data     uint16
+	}{
+		{testName: "Background", index: BCPS_ADDR, data: BCPD_ADDR},
+		{testName: "Sprites", index: OCPS_ADDR, data: OCPD_ADDR},
+	} {
+		t.Log(tt.testName)
+
+		m := newCgbMmu()
+
+		// Writes advance the index, wrapping around
+		m.Write(tt.index, PALETTE_INCREMENT|0x3e)
+		for _, val := range []uint8{0x1f, 0x00, 0xe0, 0x03} {
+			m.Write(tt.data, val)
+		}
+		if val := m.Read(tt.index); val != PALETTE_INCREMENT|BIT_6|0x02 {
+			t.Errorf("%s: expected the index to wrap to 2, got %#02x", tt.testName, val)
+		}
+
+		palettes := &m.bgPalettes
+		if tt.data == OCPD_ADDR {
+			palettes = &m.objPalettes
+		}
+		if color := palettes.color(7, 3); color != 0x001f {
+			t.Errorf("%s: expected red in the last color, got %#04x", tt.testName, color)
+		}
+		if color := palettes.color(0, 0); color != 0x03e0 {
+			t.Errorf("%s: expected green in the first color, got %#04x", tt.testName, color)
+		}
+
+		// Reads and writes without auto-increment stay put
+		m.Write(tt.index, 0x01)
+		m.Write(tt.data, 0x7c)
+		m.Write(tt.data, 0x7c)
+		if val := m.Read(tt.data); val != 0x7c {
+			t.Errorf("%s: expected 0x7c read back, got %#02x", tt.testName, val)
+		}
+		if val := m.Read(tt.index); val != BIT_6|0x01 {
+			t.Errorf("%s: expected the index to stay at 1, got %#02x", tt.testName, val)
+		}
+	}
+}
+
+func TestCgbPaletteRamDuringDrawing(t *testing.T) {
+	m, p := newTestPpu(RENDER_SCANLINE)
+	m.cgb = true
+	m.Write(BCPS_ADDR, PALETTE_INCREMENT)
+	m.Write(BCPD_ADDR, 0x12)
+	m.Write(BCPS_ADDR, PALETTE_INCREMENT)
+
+	p.Tick(OAM_SCAN_DOTS + 10)
+	if val := m.Read(BCPD_ADDR); val != 0xff {
+		t.Errorf("Expected 0xff read while drawing, got %#02x", val)
+	}
+
+	// The write is lost, but the index still advances
+	m.Write(BCPD_ADDR, 0x34)
+	if m.bgPalettes.data[0] != 0x12 {
+		t.Errorf("Expected the write to be lost, got %#02x", m.bgPalettes.data[0])
+	}
+	if val := m.Read(BCPS_ADDR); val != PALETTE_INCREMENT|BIT_6|0x01 {
+		t.Errorf("Expected the index to advance to 1, got %#02x", val)
+	}
+}
+
+func TestCgbSpeedSwitch(t *testing.T) {
+	m := newCgbMmu()
+	cpu := NewCpu(m)
+
+	if val := m.Read(KEY1_ADDR); val != 0x7e {
+		t.Errorf("Expected KEY1 to read 0x7e in normal speed, got %#02x", val)
+	}
+
+	for _, tt := range []struct {
+		testName            string
+		key1                uint8
+		expectedPrepared    uint8
+		expectedStopped     bool
+		expectedDoubleSpeed bool
+		expectedAfter       uint8
+	}{
+		{testName: "Switch to double speed", key1: 0xff, expectedPrepared: 0x7f, expectedDoubleSpeed: true, expectedAfter: 0xfe},
+		{testName: "Not prepared", key1: 0x00, expectedPrepared: 0xfe, expectedStopped: true, expectedDoubleSpeed: true, expectedAfter: 0xfe},
+		{testName: "Switch to normal speed", key1: 0x01, expectedPrepared: 0xff, expectedAfter: 0x7e},
+	} {
+		t.Log(tt.testName)
+
+		cpu.stopped = false
+		m.Write(KEY1_ADDR, tt.key1)
+		if val := m.Read(KEY1_ADDR); val != tt.expectedPrepared {
+			t.Errorf("%s: expected KEY1 to read %#02x after writing %#02x, got %#02x", tt.testName, tt.expectedPrepared, tt.key1, val)
+		}
+
+		cpu.stop()
+		if cpu.stopped != tt.expectedStopped || cpu.DoubleSpeed() != tt.expectedDoubleSpeed {
+			t.Errorf("%s: expected stopped %v and double speed %v, got %v and %v", tt.testName,
+				tt.expectedStopped, tt.expectedDoubleSpeed, cpu.stopped, cpu.DoubleSpeed())
+		}
+
+		if val := m.Read(KEY1_ADDR); val != tt.expectedAfter {
+			t.Errorf("%s: expected KEY1 to read %#02x after STOP, got %#02x", tt.testName, tt.expectedAfter, val)
+		}
+	}
+}
+
+func TestCgbRegistersInDmgMode(t *testing.T) {
+	m := NewMmu(nil)
+
+	for _, addr := range []uint16{VBK_ADDR, BCPS_ADDR, BCPD_ADDR, OCPS_ADDR, OCPD_ADDR, SVBK_ADDR, KEY1_ADDR} {
+		m.Write(addr, 0x01)
+		if val := m.Read(addr); val != 0xff {
+			t.Errorf("Expected %#04x to read 0xff, got %#02x", addr, val)
+		}
+	}
+
+	// VRAM and WRAM stay unbanked
+	m.Write(0x8000, 0x12)
+	m.Write(0xd000, 0x34)
+	m.cgb = true
+	m.Write(VBK_ADDR, 0)
+	m.Write(SVBK_ADDR, 1)
+	if m.Read(0x8000) != 0x12 || m.Read(0xd000) != 0x34 {
+		t.Error("Expected writes in DMG mode to land in VRAM bank 0 and WRAM bank 1")
+	}
+}
+
+func TestSetCgbMode(t *testing.T) {
+	cart := &cartStub{}
+	cart.memory[HEADER_CGB_FLAG] = CGB_SUPPORTED
+
+	m := NewMmu(cart)
+	cpu := NewCpu(m)
+	PostBoot(MODEL_CGB, cpu, m)
+	if !cpu.cgb || !m.cgb {
+		t.Error("Expected a CGB game to select CGB mode")
+	}
+	if color := m.bgPalettes.color(3, 2); color != 0x7fff {
+		t.Errorf("Expected white background palettes, got %#04x", color)
+	}
+
+	SetCgbMode(false, cpu, m)
+	if cpu.cgb || m.cgb {
+		t.Error("Expected DMG mode to be forced")
+	}
+}
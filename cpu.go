@@ -4,16 +4,54 @@ import "fmt"
 
 type Cpu struct {
 	// Registers
-	pc, sp           int // Program counter, Stack pointer
-	a                int // Accumulator, Flag
-	b, c, d, e, h, l int // Auxiliary registers
+	pc, sp           uint16 // Program counter, Stack pointer
+	a                uint8  // Accumulator
+	b, c, d, e, h, l uint8  // Auxiliary registers
 	p                FlagReg
 
 	// Memory
-	m Memory
+	m Mem
 
 	// Next instruction to execute
 	nextInstr Instruction
+
+	// Whether the current instruction took its conditional branch
+	branchTaken bool
+
+	// Cycles run past the end of the previous frame
+	frameOverrun int
+
+	// Steps the hardware clocked alongside the CPU
+	clock  func(cycles int)
+	timing int
+	// Steps the hardware that keeps its speed in double speed mode
+	dotClock func(cycles int)
+	// Cycles of the current step already clocked in TIMING_MCYCLE mode
+	elapsed int
+
+	// CPU state
+	ime         bool // Interrupt master enable
+	eiDelay     int  // Instructions left until EI sets IME
+	halted      bool
+	haltBug     bool // PC is not incremented by the next fetch
+	stopped     bool
+	stall       int  // Cycles the current instruction stalls the CPU for
+	cgb         bool // Running in Game Boy Color mode
+	doubleSpeed bool
+
+	// Set once an illegal opcode locks the CPU up
+	lockup *IllegalOpcodeError
+}
+
+// Reported when the CPU runs into one of the unused opcodes, which
+// hang it until reset
+type IllegalOpcodeError struct {
+	Opcode int
+	Addr   uint16
+}
+
+func (e *IllegalOpcodeError) Error() string {
+	return fmt.Sprintf("illegal opcode $%02X at $%04X", e.Opcode, e.Addr)
 }
 
 type FlagReg struct {
@@ -32,6 +70,15 @@ const (
 	HL
 	DE
 	SP
+	AF
+)
+
+// Branch conditions
+const (
+	COND_NZ = iota
+	COND_Z
+	COND_NC
+	COND_C
 )
 
 func (f *FlagReg) toInt() int {
@@ -49,9 +96,21 @@ func fromInt(n int) FlagReg {
 	return f
 }
 
+// Converts a condition into a flag bit
+func flag(cond bool) int {
+	if cond {
+		return 1
+	}
+
+	return 0
+}
+
+// The bus the CPU reads and writes through. Anything implementing it
+// can be plugged in, such as the MMU or an instrumented wrapper around
+// it.
 type Mem interface {
-	Read(addr int) int
-	Write(val, addr int)
+	Read(addr uint16) uint8
+	Write(addr uint16, val uint8)
 }
 
 const (
@@ -65,18 +124,184 @@ const (
 	BIT_7
 )
 
-func (cpu *Cpu) tick() {
-	// Read current opcode from memory
-	// Decode instruction
-	// Build Instruction object
-	// Place it as current instruction inside CPU
-	// Call corresponding method
+// T-cycles in a frame: 154 lines of 456 cycles each
+const CYCLES_PER_FRAME = 70224
+
+// Timing modes
+const (
+	// The clock is stepped once per instruction, after it has executed
+	TIMING_INSTRUCTION = iota
+	// The clock is stepped on every M-cycle, so that each memory access
+	// sees the hardware in the state it would be in at that point
+	TIMING_MCYCLE
+)
+
+const (
+	KEY1_ADDR = 0xff4d // CGB speed switch
+
+	SPEED_SWITCH_CYCLES = 8200
+)
+
+func NewCpu(bus Mem) *Cpu {
+	return &Cpu{m: bus}
+}
+
+// Replaces the bus the CPU accesses memory through
+func (cpu *Cpu) SetBus(bus Mem) {
+	cpu.m = bus
+}
+
+// Sets the function stepping the hardware that keeps pace with the CPU,
+// such as OAM DMA, and how finely its steps are interleaved with the
+// CPU. It is given CPU T-cycles, which in double speed last half as long.
+func (cpu *Cpu) SetClock(clock func(cycles int), timing int) {
+	cpu.clock = clock
+	cpu.timing = timing
+}
+
+// Sets the function stepping the hardware that keeps its speed when the
+// CPU switches to double speed: the PPU and the cartridge clock. It is
+// interleaved with the CPU as the clock set by SetClock is, and given
+// T-cycles at normal speed, half the CPU cycles in double speed.
+func (cpu *Cpu) SetDotClock(clock func(cycles int)) {
+	cpu.dotClock = clock
+}
+
+// Steps both clocks by the given CPU T-cycles, which always come in
+// whole M-cycles and so halve exactly
+func (cpu *Cpu) advance(cycles int) {
+	if cpu.clock != nil {
+		cpu.clock(cycles)
+	}
+
+	if cpu.dotClock != nil {
+		if cpu.doubleSpeed {
+			cycles /= 2
+		}
+		cpu.dotClock(cycles)
+	}
+}
+
+// Runs a step of the CPU and clocks the rest of the hardware, returning
+// the number of T-cycles spent
+func (cpu *Cpu) tick() int {
+	cpu.elapsed = 0
+	cycles := cpu.step()
+
+	// Clock whatever the memory accesses and internal cycles did not
+	if cpu.timing == TIMING_MCYCLE {
+		for cpu.elapsed < cycles {
+			cpu.internal()
+		}
+	} else {
+		cpu.advance(cycles)
+	}
+
+	return cycles
+}
+
+// Ends the current M-cycle when running in TIMING_MCYCLE mode
+func (cpu *Cpu) mcycle() {
+	if cpu.timing != TIMING_MCYCLE {
+		return
+	}
+
+	cpu.elapsed += 4
+	cpu.advance(4)
+}
+
+// An M-cycle in which the CPU works internally without accessing memory
+func (cpu *Cpu) internal() {
+	cpu.mcycle()
+}
+
+// Dispatches a pending interrupt or otherwise fetches, decodes and
+// executes the next instruction, returning the number of T-cycles it
+// took
+func (cpu *Cpu) step() int {
+	// Nothing but a reset gets the CPU out of a lockup
+	if cpu.lockup != nil {
+		return 4
+	}
+
+	// Low-power states idle until woken up
+	if cpu.stopped {
+		if cpu.peek(IF_ADDR)&(1<<INT_JOYPAD) == 0 {
+			return 4
+		}
+		cpu.stopped = false
+	}
+
+	if cpu.halted {
+		if cpu.pendingInterrupts() == 0 {
+			return 4
+		}
+		cpu.halted = false
+	}
+
+	if cycles := cpu.handleInterrupts(); cycles > 0 {
+		return cycles
+	}
+
+	cpu.branchTaken = false
+	cpu.stall = 0
+
+	cpu.decode(cpu.fetch())
+	cpu.nextInstr.operation(cpu)
+	cpu.updateIme()
+
+	if cpu.branchTaken {
+		return cpu.nextInstr.branchCycles
+	}
+
+	return cpu.nextInstr.cycles + cpu.stall
+}
+
+// Executes instructions until at least the given number of T-cycles
+// have elapsed, returning the number of cycles actually run
+func (cpu *Cpu) Run(cycles int) int {
+	spent := 0
+	for spent < cycles {
+		spent += cpu.tick()
+	}
+
+	return spent
+}
+
+// Runs the CPU for one frame, which takes twice as many cycles in
+// double speed. Cycles run past the end of a frame are deducted from
+// the next one.
+func (cpu *Cpu) StepFrame() {
+	budget := CYCLES_PER_FRAME - cpu.frameOverrun
+	if cpu.doubleSpeed {
+		budget += CYCLES_PER_FRAME
+	}
+	cpu.frameOverrun = cpu.Run(budget) - budget
+}
+
+// Whether the CPU runs in CGB double speed mode
+func (cpu *Cpu) DoubleSpeed() bool {
+	return cpu.doubleSpeed
+}
+
+// Returns the error that locked the CPU up, if any
+func (cpu *Cpu) Err() error {
+	if cpu.lockup == nil {
+		return nil
+	}
+
+	return cpu.lockup
 }
 
 // Fetches the next instruction
 func (cpu *Cpu) fetch() int {
-	opcode := cpu.m.Read(cpu.pc)
-	cpu.pc++
+	opcode := int(cpu.read(cpu.pc))
+
+	if cpu.haltBug {
+		cpu.haltBug = false
+	} else {
+		cpu.pc++
+	}
 
 	return opcode
 }
@@ -86,22 +311,640 @@ func (cpu *Cpu) fetch() int {
 func (cpu *Cpu) decode(opcode int) {
 	instr := instructionSet[opcode]
 
+	// Prefixed instructions are indexed by the following byte, which is
+	// then read as their operand
+	if opcode == 0xcb {
+		instr = cbInstructionSet[int(cpu.peek(cpu.pc))]
+	}
+
+	// STOP skips the byte following it without spending a cycle on it
+	if opcode == 0x10 {
+		instr.operands[0] = int(cpu.peek(cpu.pc))
+		cpu.pc++
+		cpu.nextInstr = instr
+		return
+	}
+
 	for i := 0; i < instr.size-1; i++ {
-		instr.operands[i] = cpu.m.Read(cpu.pc)
+		instr.operands[i] = int(cpu.read(cpu.pc))
 		cpu.pc++
 	}
 
 	cpu.nextInstr = instr
 }
 
+// Reads a byte from memory, taking an M-cycle
+func (cpu *Cpu) read(addr uint16) uint8 {
+	cpu.mcycle()
+	cpu.oamBug(addr, OAM_BUG_READ)
+	return cpu.peek(addr)
+}
+
+// Reads a byte through a register the increment/decrement unit changes
+// in the same M-cycle
+func (cpu *Cpu) readInc(addr uint16) uint8 {
+	cpu.mcycle()
+	cpu.oamBug(addr, OAM_BUG_READ_INC)
+	return cpu.peek(addr)
+}
+
+// Writes a byte to memory, taking an M-cycle
+func (cpu *Cpu) write(addr uint16, val uint8) {
+	cpu.mcycle()
+	cpu.oamBug(addr, OAM_BUG_WRITE)
+	cpu.poke(addr, val)
+}
+
+// An internal M-cycle in which the increment/decrement unit changes a
+// 16-bit register, putting its value on the address bus
+func (cpu *Cpu) idu(addr uint16) {
+	cpu.internal()
+	cpu.oamBug(addr, OAM_BUG_WRITE)
+}
+
+// Reads a byte from memory without going through the bus, as the CPU
+// does with its own registers such as IF and IE
+func (cpu *Cpu) peek(addr uint16) uint8 {
+	return cpu.m.Read(addr)
+}
+
+// Writes a byte to memory without going through the bus
+func (cpu *Cpu) poke(addr uint16, val uint8) {
+	cpu.m.Write(addr, val)
+}
+
+func (cpu *Cpu) bc() uint16 {
+	return uint16(cpu.b)<<8 | uint16(cpu.c)
+}
+
+func (cpu *Cpu) setBC(val uint16) {
+	cpu.b, cpu.c = uint8(val>>8), uint8(val)
+}
+
+func (cpu *Cpu) de() uint16 {
+	return uint16(cpu.d)<<8 | uint16(cpu.e)
+}
+
+func (cpu *Cpu) setDE(val uint16) {
+	cpu.d, cpu.e = uint8(val>>8), uint8(val)
+}
+
+func (cpu *Cpu) hl() uint16 {
+	return uint16(cpu.h)<<8 | uint16(cpu.l)
+}
+
+func (cpu *Cpu) setHL(val uint16) {
+	cpu.h, cpu.l = uint8(val>>8), uint8(val)
+}
+
+func (cpu *Cpu) af() uint16 {
+	return uint16(cpu.a)<<8 | uint16(cpu.p.toInt())
+}
+
+// The low nibble of F always reads as zero
+func (cpu *Cpu) setAF(val uint16) {
+	cpu.a, cpu.p = uint8(val>>8), fromInt(int(val&0xf0))
+}
+
+// Returns a pointer to the given 8-bit register
+func (cpu *Cpu) reg8(r int) *uint8 {
+	switch r {
+	case A:
+		return &cpu.a
+	case B:
+		return &cpu.b
+	case C:
+		return &cpu.c
+	case D:
+		return &cpu.d
+	case E:
+		return &cpu.e
+	case H:
+		return &cpu.h
+	case L:
+		return &cpu.l
+	}
+
+	panic(fmt.Sprintf("invalid 8-bit register %d", r))
+}
+
+// Reads the given 16-bit register
+func (cpu *Cpu) reg16(r int) uint16 {
+	switch r {
+	case BC:
+		return cpu.bc()
+	case DE:
+		return cpu.de()
+	case HL:
+		return cpu.hl()
+	case SP:
+		return cpu.sp
+	case AF:
+		return cpu.af()
+	}
+
+	panic(fmt.Sprintf("invalid 16-bit register %d", r))
+}
+
+// Writes the given 16-bit register
+func (cpu *Cpu) setReg16(r int, val uint16) {
+	switch r {
+	case BC:
+		cpu.setBC(val)
+	case DE:
+		cpu.setDE(val)
+	case HL:
+		cpu.setHL(val)
+	case SP:
+		cpu.sp = val
+	case AF:
+		cpu.setAF(val)
+	default:
+		panic(fmt.Sprintf("invalid 16-bit register %d", r))
+	}
+}
+
+// Reads an 8-bit operand: either a register or, when given a
+// 16-bit register, the byte it points to
+func (cpu *Cpu) load(r int) uint8 {
+	if r >= BC {
+		return cpu.read(cpu.reg16(r))
+	}
+
+	return *cpu.reg8(r)
+}
+
+// Writes an 8-bit operand, following the same rules as load
+func (cpu *Cpu) store(r int, val uint8) {
+	if r >= BC {
+		cpu.write(cpu.reg16(r), val)
+		return
+	}
+
+	*cpu.reg8(r) = val
+}
+
+// Returns the 8-bit immediate operand of the current instruction
+func (cpu *Cpu) imm8() uint8 {
+	return uint8(cpu.nextInstr.operands[0])
+}
+
+// Returns the 16-bit immediate operand of the current instruction
+func (cpu *Cpu) imm16() uint16 {
+	return uint16(cpu.nextInstr.operands[1])<<8 | uint16(cpu.nextInstr.operands[0])
+}
+
+// Returns the source operand of an 8-bit instruction: the immediate
+// value for 2-byte instructions, the second register otherwise
+func (cpu *Cpu) source() uint8 {
+	if cpu.nextInstr.size == 2 {
+		return cpu.imm8()
+	}
+
+	return cpu.load(cpu.nextInstr.registers[1])
+}
+
+// Evaluates a branch condition against the flag register
+func (cpu *Cpu) condition(cc int) bool {
+	switch cc {
+	case COND_NZ:
+		return cpu.p.z == 0
+	case COND_Z:
+		return cpu.p.z == 1
+	case COND_NC:
+		return cpu.p.c == 0
+	case COND_C:
+		return cpu.p.c == 1
+	}
+
+	panic(fmt.Sprintf("invalid condition %d", cc))
+}
+
+// Pushes a 16-bit value onto the stack. SP is decremented in an
+// internal cycle ahead of the writes.
+func (cpu *Cpu) push(val uint16) {
+	cpu.idu(cpu.sp)
+	cpu.sp--
+	cpu.write(cpu.sp, uint8(val>>8))
+	cpu.sp--
+	cpu.write(cpu.sp, uint8(val))
+}
+
+// Pops a 16-bit value from the stack
+func (cpu *Cpu) pop() uint16 {
+	lo := cpu.readInc(cpu.sp)
+	cpu.sp++
+	hi := cpu.read(cpu.sp)
+	cpu.sp++
+
+	return uint16(hi)<<8 | uint16(lo)
+}
+
 func (cpu *Cpu) nop() {
-	fmt.Println("NOP")
 }
 
-//func (cpu *Cpu) ld_n_nn(reg *int, val int) {
-//	*reg = val
-//}
-//
-//func (cpu *Cpu) ld_r1_r2(reg1, reg2 *int) {
-//	*reg1 = *reg2
-//}
+// 8-bit loads
+
+func (cpu *Cpu) ld_r1_r2() {
+	cpu.store(cpu.nextInstr.registers[0], cpu.source())
+}
+
+// LD (HL+),A and LD A,(HL+)
+func (cpu *Cpu) ldi() {
+	cpu.ld_hl_idu()
+	cpu.setHL(cpu.hl() + 1)
+}
+
+// LD (HL-),A and LD A,(HL-)
+func (cpu *Cpu) ldd() {
+	cpu.ld_hl_idu()
+	cpu.setHL(cpu.hl() - 1)
+}
+
+// Loads through HL while it is incremented or decremented
+func (cpu *Cpu) ld_hl_idu() {
+	if cpu.nextInstr.registers[0] == A {
+		cpu.a = cpu.readInc(cpu.hl())
+		return
+	}
+
+	cpu.ld_r1_r2()
+}
+
+func (cpu *Cpu) ld_nn_a() {
+	cpu.write(cpu.imm16(), cpu.a)
+}
+
+func (cpu *Cpu) ld_a_nn() {
+	cpu.a = cpu.read(cpu.imm16())
+}
+
+func (cpu *Cpu) ldh_n_a() {
+	cpu.write(0xff00+uint16(cpu.imm8()), cpu.a)
+}
+
+func (cpu *Cpu) ldh_a_n() {
+	cpu.a = cpu.read(0xff00 + uint16(cpu.imm8()))
+}
+
+func (cpu *Cpu) ld_c_a() {
+	cpu.write(0xff00+uint16(cpu.c), cpu.a)
+}
+
+func (cpu *Cpu) ld_a_c() {
+	cpu.a = cpu.read(0xff00 + uint16(cpu.c))
+}
+
+// 16-bit loads
+
+func (cpu *Cpu) ld_n_nn() {
+	cpu.setReg16(cpu.nextInstr.registers[0], cpu.imm16())
+}
+
+func (cpu *Cpu) ld_nn_sp() {
+	addr := cpu.imm16()
+	cpu.write(addr, uint8(cpu.sp))
+	cpu.write(addr+1, uint8(cpu.sp>>8))
+}
+
+func (cpu *Cpu) ld_sp_hl() {
+	cpu.sp = cpu.hl()
+}
+
+func (cpu *Cpu) ldhl_sp_n() {
+	var res uint16
+	res, cpu.p = aluAddSp(cpu.sp, cpu.imm8())
+	cpu.setHL(res)
+}
+
+func (cpu *Cpu) push_nn() {
+	cpu.push(cpu.reg16(cpu.nextInstr.registers[0]))
+}
+
+func (cpu *Cpu) pop_nn() {
+	cpu.setReg16(cpu.nextInstr.registers[0], cpu.pop())
+}
+
+// 8-bit ALU
+
+func (cpu *Cpu) add_a_n() {
+	cpu.a, cpu.p = aluAdd(cpu.a, cpu.source(), 0)
+}
+
+func (cpu *Cpu) adc_a_n() {
+	cpu.a, cpu.p = aluAdd(cpu.a, cpu.source(), cpu.p.c)
+}
+
+func (cpu *Cpu) sub_n() {
+	cpu.a, cpu.p = aluSub(cpu.a, cpu.source(), 0)
+}
+
+func (cpu *Cpu) sbc_a_n() {
+	cpu.a, cpu.p = aluSub(cpu.a, cpu.source(), cpu.p.c)
+}
+
+func (cpu *Cpu) cp_n() {
+	_, cpu.p = aluSub(cpu.a, cpu.source(), 0)
+}
+
+func (cpu *Cpu) and_n() {
+	cpu.a, cpu.p = aluAnd(cpu.a, cpu.source())
+}
+
+func (cpu *Cpu) or_n() {
+	cpu.a, cpu.p = aluOr(cpu.a, cpu.source())
+}
+
+func (cpu *Cpu) xor_n() {
+	cpu.a, cpu.p = aluXor(cpu.a, cpu.source())
+}
+
+func (cpu *Cpu) inc_n() {
+	r := cpu.nextInstr.registers[0]
+
+	var res uint8
+	res, cpu.p = aluInc(cpu.load(r), cpu.p)
+	cpu.store(r, res)
+}
+
+func (cpu *Cpu) dec_n() {
+	r := cpu.nextInstr.registers[0]
+
+	var res uint8
+	res, cpu.p = aluDec(cpu.load(r), cpu.p)
+	cpu.store(r, res)
+}
+
+// 16-bit arithmetic
+
+func (cpu *Cpu) add_hl_n() {
+	var res uint16
+	res, cpu.p = aluAdd16(cpu.hl(), cpu.reg16(cpu.nextInstr.registers[1]), cpu.p)
+	cpu.setHL(res)
+}
+
+func (cpu *Cpu) add_sp_n() {
+	cpu.sp, cpu.p = aluAddSp(cpu.sp, cpu.imm8())
+}
+
+func (cpu *Cpu) inc_nn() {
+	r := cpu.nextInstr.registers[0]
+	cpu.idu(cpu.reg16(r))
+	cpu.setReg16(r, cpu.reg16(r)+1)
+}
+
+func (cpu *Cpu) dec_nn() {
+	r := cpu.nextInstr.registers[0]
+	cpu.idu(cpu.reg16(r))
+	cpu.setReg16(r, cpu.reg16(r)-1)
+}
+
+// Miscellaneous
+
+// Adjusts A to a valid BCD number after an addition or subtraction
+func (cpu *Cpu) daa() {
+	cpu.a, cpu.p = aluDaa(cpu.a, cpu.p)
+}
+
+func (cpu *Cpu) cpl() {
+	cpu.a ^= 0xff
+	cpu.p.n = 1
+	cpu.p.h = 1
+}
+
+func (cpu *Cpu) ccf() {
+	cpu.p.n = 0
+	cpu.p.h = 0
+	cpu.p.c ^= 1
+}
+
+func (cpu *Cpu) scf() {
+	cpu.p.n = 0
+	cpu.p.h = 0
+	cpu.p.c = 1
+}
+
+// Halts until an interrupt is pending. If one already is while IME is
+// clear, HALT is not entered and the next byte is read twice instead.
+func (cpu *Cpu) halt() {
+	if !cpu.ime && cpu.pendingInterrupts() != 0 {
+		cpu.haltBug = true
+		return
+	}
+
+	cpu.halted = true
+}
+
+// Stops until a joypad input. On CGB, when a speed switch has been
+// prepared through KEY1, switches speed instead.
+func (cpu *Cpu) stop() {
+	if cpu.cgbMode() && cpu.peek(KEY1_ADDR)&BIT_0 != 0 {
+		cpu.doubleSpeed = !cpu.doubleSpeed
+		if bus, ok := cpu.m.(speedSwitchBus); ok {
+			bus.switchSpeed(cpu.doubleSpeed)
+		} else {
+			cpu.poke(KEY1_ADDR, uint8(flag(cpu.doubleSpeed))<<7)
+		}
+		cpu.stall = SPEED_SWITCH_CYCLES
+		return
+	}
+
+	cpu.stopped = true
+}
+
+func (cpu *Cpu) di() {
+	cpu.ime = false
+	cpu.eiDelay = 0
+}
+
+// IME is set after the next instruction, see updateIme
+func (cpu *Cpu) ei() {
+	cpu.eiDelay = 2
+}
+
+// Locks the CPU up, as any of the unused opcodes does on hardware
+func (cpu *Cpu) illegal() {
+	addr := cpu.pc - 1
+	cpu.lockup = &IllegalOpcodeError{Opcode: int(cpu.peek(addr)), Addr: addr}
+}
+
+// Rotates
+
+func (cpu *Cpu) rlca() {
+	carry := cpu.a >> 7
+	cpu.a = cpu.a<<1 | carry
+	cpu.p = FlagReg{c: int(carry)}
+}
+
+func (cpu *Cpu) rla() {
+	carry := cpu.a >> 7
+	cpu.a = cpu.a<<1 | uint8(cpu.p.c)
+	cpu.p = FlagReg{c: int(carry)}
+}
+
+func (cpu *Cpu) rrca() {
+	carry := cpu.a & 1
+	cpu.a = cpu.a>>1 | carry<<7
+	cpu.p = FlagReg{c: int(carry)}
+}
+
+func (cpu *Cpu) rra() {
+	carry := cpu.a & 1
+	cpu.a = cpu.a>>1 | uint8(cpu.p.c)<<7
+	cpu.p = FlagReg{c: int(carry)}
+}
+
+// Jumps
+
+func (cpu *Cpu) jp_nn() {
+	cpu.pc = cpu.imm16()
+}
+
+func (cpu *Cpu) jp_cc_nn() {
+	if cpu.condition(cpu.nextInstr.registers[0]) {
+		cpu.jp_nn()
+		cpu.branchTaken = true
+	}
+}
+
+func (cpu *Cpu) jp_hl() {
+	cpu.pc = cpu.hl()
+}
+
+func (cpu *Cpu) jr_n() {
+	cpu.pc += uint16(int8(cpu.imm8()))
+}
+
+func (cpu *Cpu) jr_cc_n() {
+	if cpu.condition(cpu.nextInstr.registers[0]) {
+		cpu.jr_n()
+		cpu.branchTaken = true
+	}
+}
+
+// Calls
+
+func (cpu *Cpu) call_nn() {
+	cpu.push(cpu.pc)
+	cpu.pc = cpu.imm16()
+}
+
+func (cpu *Cpu) call_cc_nn() {
+	if cpu.condition(cpu.nextInstr.registers[0]) {
+		cpu.call_nn()
+		cpu.branchTaken = true
+	}
+}
+
+func (cpu *Cpu) rst_n(addr uint16) {
+	cpu.push(cpu.pc)
+	cpu.pc = addr
+}
+
+// Returns
+
+func (cpu *Cpu) ret() {
+	cpu.pc = cpu.pop()
+}
+
+// The condition is evaluated in an internal cycle
+func (cpu *Cpu) ret_cc() {
+	cpu.internal()
+
+	if cpu.condition(cpu.nextInstr.registers[0]) {
+		cpu.ret()
+		cpu.branchTaken = true
+	}
+}
+
+func (cpu *Cpu) reti() {
+	cpu.ret()
+	cpu.ime = true
+}
+
+// CB-prefixed rotates and shifts
+
+// Applies a rotate or shift to the operand of the current instruction.
+// op returns the shifted value and the bit shifted out into carry.
+func (cpu *Cpu) rotate(op func(val uint8) (uint8, uint8)) {
+	r := cpu.nextInstr.registers[0]
+	res, carry := op(cpu.load(r))
+
+	cpu.p = FlagReg{z: flag(res == 0), c: int(carry)}
+	cpu.store(r, res)
+}
+
+func (cpu *Cpu) rlc_n() {
+	cpu.rotate(func(val uint8) (uint8, uint8) {
+		return val<<1 | val>>7, val >> 7
+	})
+}
+
+func (cpu *Cpu) rrc_n() {
+	cpu.rotate(func(val uint8) (uint8, uint8) {
+		return val>>1 | val<<7, val & 1
+	})
+}
+
+func (cpu *Cpu) rl_n() {
+	carry := uint8(cpu.p.c)
+	cpu.rotate(func(val uint8) (uint8, uint8) {
+		return val<<1 | carry, val >> 7
+	})
+}
+
+func (cpu *Cpu) rr_n() {
+	carry := uint8(cpu.p.c)
+	cpu.rotate(func(val uint8) (uint8, uint8) {
+		return val>>1 | carry<<7, val & 1
+	})
+}
+
+func (cpu *Cpu) sla_n() {
+	cpu.rotate(func(val uint8) (uint8, uint8) {
+		return val << 1, val >> 7
+	})
+}
+
+func (cpu *Cpu) sra_n() {
+	cpu.rotate(func(val uint8) (uint8, uint8) {
+		return val>>1 | val&0x80, val & 1
+	})
+}
+
+func (cpu *Cpu) swap_n() {
+	cpu.rotate(func(val uint8) (uint8, uint8) {
+		return val<<4 | val>>4, 0
+	})
+}
+
+func (cpu *Cpu) srl_n() {
+	cpu.rotate(func(val uint8) (uint8, uint8) {
+		return val >> 1, val & 1
+	})
+}
+
+// CB-prefixed bit operations
+
+// Returns the bit index encoded in bits 3-5 of a CB opcode
+func (cpu *Cpu) bitIndex() uint8 {
+	return (cpu.imm8() >> 3) & 7
+}
+
+func (cpu *Cpu) bit_b_r() {
+	val := cpu.load(cpu.nextInstr.registers[0])
+
+	cpu.p.z = flag(val&(1<<cpu.bitIndex()) == 0)
+	cpu.p.n = 0
+	cpu.p.h = 1
+}
+
+func (cpu *Cpu) res_b_r() {
+	r := cpu.nextInstr.registers[0]
+	cpu.store(r, cpu.load(r)&^(1<<cpu.bitIndex()))
+}
+
+func (cpu *Cpu) set_b_r() {
+	r := cpu.nextInstr.registers[0]
+	cpu.store(r, cpu.load(r)|1<<cpu.bitIndex())
+}
@@ -1,259 +1,533 @@
 package main
 
 type Instruction struct {
-	name      string
-	size      int
-	cycles    int
-	operands  [2]int
-	registers [2]int
-	operation func(*Cpu)
+	name         string
+	size         int
+	cycles       int
+	branchCycles int // Cycles of a conditional branch when it is taken
+	operands     [2]int
+	registers    [2]int
+	operation    func(*Cpu)
 }
 
 // Instruction set info extracted from http://www.pastraiser.com/cpu/gameboy/gameboy_opcodes.html
 var instructionSet = map[int]Instruction{
 	0x0:  Instruction{name: "NOP", size: 1, cycles: 4, operation: (*Cpu).nop},
-	0x1:  Instruction{name: "LD BC,d16", size: 3, cycles: 12, registers: [2]int{}},
-	0x2:  Instruction{name: "LD (BC),A", size: 1, cycles: 8, registers: [2]int{}},
-	0x3:  Instruction{name: "INC BC", size: 1, cycles: 8, registers: [2]int{}},
-	0x4:  Instruction{name: "INC B", size: 1, cycles: 4, registers: [2]int{}},
-	0x5:  Instruction{name: "DEC B", size: 1, cycles: 4, registers: [2]int{}},
-	0x6:  Instruction{name: "LD B,d8", size: 2, cycles: 8, registers: [2]int{}},
-	0x7:  Instruction{name: "RLCA", size: 1, cycles: 4, registers: [2]int{}},
-	0x8:  Instruction{name: "LD (a16),SP", size: 3, cycles: 20, registers: [2]int{}},
-	0x9:  Instruction{name: "ADD HL,BC", size: 1, cycles: 8, registers: [2]int{}},
-	0xa:  Instruction{name: "LD A,(BC)", size: 1, cycles: 8, registers: [2]int{}},
-	0xb:  Instruction{name: "DEC BC", size: 1, cycles: 8, registers: [2]int{}},
-	0xc:  Instruction{name: "INC C", size: 1, cycles: 4, registers: [2]int{}},
-	0xd:  Instruction{name: "DEC C", size: 1, cycles: 4, registers: [2]int{}},
-	0xe:  Instruction{name: "LD C,d8", size: 2, cycles: 8, registers: [2]int{}},
-	0xf:  Instruction{name: "RRCA", size: 1, cycles: 4, registers: [2]int{}},
-	0x10: Instruction{name: "STOP 0", size: 2, cycles: 4, registers: [2]int{}},
-	0x11: Instruction{name: "LD DE,d16", size: 3, cycles: 12, registers: [2]int{}},
-	0x12: Instruction{name: "LD (DE),A", size: 1, cycles: 8, registers: [2]int{}},
-	0x13: Instruction{name: "INC DE", size: 1, cycles: 8, registers: [2]int{}},
-	0x14: Instruction{name: "INC D", size: 1, cycles: 4, registers: [2]int{}},
-	0x15: Instruction{name: "DEC D", size: 1, cycles: 4, registers: [2]int{}},
-	0x16: Instruction{name: "LD D,d8", size: 2, cycles: 8, registers: [2]int{}},
-	0x17: Instruction{name: "RLA", size: 1, cycles: 4, registers: [2]int{}},
-	0x18: Instruction{name: "JR r8", size: 2, cycles: 12, registers: [2]int{}},
-	0x19: Instruction{name: "ADD HL,DE", size: 1, cycles: 8, registers: [2]int{}},
-	0x1a: Instruction{name: "LD A,(DE)", size: 1, cycles: 8, registers: [2]int{}},
-	0x1b: Instruction{name: "DEC DE", size: 1, cycles: 8, registers: [2]int{}},
-	0x1c: Instruction{name: "INC E", size: 1, cycles: 4, registers: [2]int{}},
-	0x1d: Instruction{name: "DEC E", size: 1, cycles: 4, registers: [2]int{}},
-	0x1e: Instruction{name: "LD E,d8", size: 2, cycles: 8, registers: [2]int{}},
-	0x1f: Instruction{name: "RRA", size: 1, cycles: 4, registers: [2]int{}},
-	0x20: Instruction{name: "JR NZ,r8", size: 2, cycles: 12 / 8, registers: [2]int{}},
-	0x21: Instruction{name: "LD HL,d16", size: 3, cycles: 12, registers: [2]int{}},
-	0x22: Instruction{name: "LD (HL+),A", size: 1, cycles: 8, registers: [2]int{}},
-	0x23: Instruction{name: "INC HL", size: 1, cycles: 8, registers: [2]int{}},
-	0x24: Instruction{name: "INC H", size: 1, cycles: 4, registers: [2]int{}},
-	0x25: Instruction{name: "DEC H", size: 1, cycles: 4, registers: [2]int{}},
-	0x26: Instruction{name: "LD H,d8", size: 2, cycles: 8, registers: [2]int{}},
-	0x27: Instruction{name: "DAA", size: 1, cycles: 4, registers: [2]int{}},
-	0x28: Instruction{name: "JR Z,r8", size: 2, cycles: 12 / 8, registers: [2]int{}},
-	0x29: Instruction{name: "ADD HL,HL", size: 1, cycles: 8, registers: [2]int{}},
-	0x2a: Instruction{name: "LD A,(HL+)", size: 1, cycles: 8, registers: [2]int{}},
-	0x2b: Instruction{name: "DEC HL", size: 1, cycles: 8, registers: [2]int{}},
-	0x2c: Instruction{name: "INC L", size: 1, cycles: 4, registers: [2]int{}},
-	0x2d: Instruction{name: "DEC L", size: 1, cycles: 4, registers: [2]int{}},
-	0x2e: Instruction{name: "LD L,d8", size: 2, cycles: 8, registers: [2]int{}},
-	0x2f: Instruction{name: "CPL", size: 1, cycles: 4, registers: [2]int{}},
-	0x30: Instruction{name: "JR NC,r8", size: 2, cycles: 12 / 8, registers: [2]int{}},
-	0x31: Instruction{name: "LD SP,d16", size: 3, cycles: 12, registers: [2]int{}},
-	0x32: Instruction{name: "LD (HL-),A", size: 1, cycles: 8, registers: [2]int{}},
-	0x33: Instruction{name: "INC SP", size: 1, cycles: 8, registers: [2]int{}},
-	0x34: Instruction{name: "INC (HL)", size: 1, cycles: 12, registers: [2]int{}},
-	0x35: Instruction{name: "DEC (HL)", size: 1, cycles: 12, registers: [2]int{}},
-	0x36: Instruction{name: "LD (HL),d8", size: 2, cycles: 12, registers: [2]int{}},
-	0x37: Instruction{name: "SCF", size: 1, cycles: 4, registers: [2]int{}},
-	0x38: Instruction{name: "JR C,r8", size: 2, cycles: 12 / 8, registers: [2]int{}},
-	0x39: Instruction{name: "ADD HL,SP", size: 1, cycles: 8, registers: [2]int{}},
-	0x3a: Instruction{name: "LD A,(HL-)", size: 1, cycles: 8, registers: [2]int{}},
-	0x3b: Instruction{name: "DEC SP", size: 1, cycles: 8, registers: [2]int{}},
-	0x3c: Instruction{name: "INC A", size: 1, cycles: 4, registers: [2]int{}},
-	0x3d: Instruction{name: "DEC A", size: 1, cycles: 4, registers: [2]int{}},
-	0x3e: Instruction{name: "LD A,d8", size: 2, cycles: 8, registers: [2]int{}},
-	0x3f: Instruction{name: "CCF", size: 1, cycles: 4, registers: [2]int{}},
-	0x40: Instruction{name: "LD B,B", size: 1, cycles: 4, registers: [2]int{}},
-	0x41: Instruction{name: "LD B,C", size: 1, cycles: 4, registers: [2]int{}},
-	0x42: Instruction{name: "LD B,D", size: 1, cycles: 4, registers: [2]int{}},
-	0x43: Instruction{name: "LD B,E", size: 1, cycles: 4, registers: [2]int{}},
-	0x44: Instruction{name: "LD B,H", size: 1, cycles: 4, registers: [2]int{}},
-	0x45: Instruction{name: "LD B,L", size: 1, cycles: 4, registers: [2]int{}},
-	0x46: Instruction{name: "LD B,(HL)", size: 1, cycles: 8, registers: [2]int{}},
-	0x47: Instruction{name: "LD B,A", size: 1, cycles: 4, registers: [2]int{}},
-	0x48: Instruction{name: "LD C,B", size: 1, cycles: 4, registers: [2]int{}},
-	0x49: Instruction{name: "LD C,C", size: 1, cycles: 4, registers: [2]int{}},
-	0x4a: Instruction{name: "LD C,D", size: 1, cycles: 4, registers: [2]int{}},
-	0x4b: Instruction{name: "LD C,E", size: 1, cycles: 4, registers: [2]int{}},
-	0x4c: Instruction{name: "LD C,H", size: 1, cycles: 4, registers: [2]int{}},
-	0x4d: Instruction{name: "LD C,L", size: 1, cycles: 4, registers: [2]int{}},
-	0x4e: Instruction{name: "LD C,(HL)", size: 1, cycles: 8, registers: [2]int{}},
-	0x4f: Instruction{name: "LD C,A", size: 1, cycles: 4, registers: [2]int{}},
-	0x50: Instruction{name: "LD D,B", size: 1, cycles: 4, registers: [2]int{}},
-	0x51: Instruction{name: "LD D,C", size: 1, cycles: 4, registers: [2]int{}},
-	0x52: Instruction{name: "LD D,D", size: 1, cycles: 4, registers: [2]int{}},
-	0x53: Instruction{name: "LD D,E", size: 1, cycles: 4, registers: [2]int{}},
-	0x54: Instruction{name: "LD D,H", size: 1, cycles: 4, registers: [2]int{}},
-	0x55: Instruction{name: "LD D,L", size: 1, cycles: 4, registers: [2]int{}},
-	0x56: Instruction{name: "LD D,(HL)", size: 1, cycles: 8, registers: [2]int{}},
-	0x57: Instruction{name: "LD D,A", size: 1, cycles: 4, registers: [2]int{}},
-	0x58: Instruction{name: "LD E,B", size: 1, cycles: 4, registers: [2]int{}},
-	0x59: Instruction{name: "LD E,C", size: 1, cycles: 4, registers: [2]int{}},
-	0x5a: Instruction{name: "LD E,D", size: 1, cycles: 4, registers: [2]int{}},
-	0x5b: Instruction{name: "LD E,E", size: 1, cycles: 4, registers: [2]int{}},
-	0x5c: Instruction{name: "LD E,H", size: 1, cycles: 4, registers: [2]int{}},
-	0x5d: Instruction{name: "LD E,L", size: 1, cycles: 4, registers: [2]int{}},
-	0x5e: Instruction{name: "LD E,(HL)", size: 1, cycles: 8, registers: [2]int{}},
-	0x5f: Instruction{name: "LD E,A", size: 1, cycles: 4, registers: [2]int{}},
-	0x60: Instruction{name: "LD H,B", size: 1, cycles: 4, registers: [2]int{}},
-	0x61: Instruction{name: "LD H,C", size: 1, cycles: 4, registers: [2]int{}},
-	0x62: Instruction{name: "LD H,D", size: 1, cycles: 4, registers: [2]int{}},
-	0x63: Instruction{name: "LD H,E", size: 1, cycles: 4, registers: [2]int{}},
-	0x64: Instruction{name: "LD H,H", size: 1, cycles: 4, registers: [2]int{}},
-	0x65: Instruction{name: "LD H,L", size: 1, cycles: 4, registers: [2]int{}},
-	0x66: Instruction{name: "LD H,(HL)", size: 1, cycles: 8, registers: [2]int{}},
-	0x67: Instruction{name: "LD H,A", size: 1, cycles: 4, registers: [2]int{}},
-	0x68: Instruction{name: "LD L,B", size: 1, cycles: 4, registers: [2]int{}},
-	0x69: Instruction{name: "LD L,C", size: 1, cycles: 4, registers: [2]int{}},
-	0x6a: Instruction{name: "LD L,D", size: 1, cycles: 4, registers: [2]int{}},
-	0x6b: Instruction{name: "LD L,E", size: 1, cycles: 4, registers: [2]int{}},
-	0x6c: Instruction{name: "LD L,H", size: 1, cycles: 4, registers: [2]int{}},
-	0x6d: Instruction{name: "LD L,L", size: 1, cycles: 4, registers: [2]int{}},
-	0x6e: Instruction{name: "LD L,(HL)", size: 1, cycles: 8, registers: [2]int{}},
-	0x6f: Instruction{name: "LD L,A", size: 1, cycles: 4, registers: [2]int{}},
-	0x70: Instruction{name: "LD (HL),B", size: 1, cycles: 8, registers: [2]int{}},
-	0x71: Instruction{name: "LD (HL),C", size: 1, cycles: 8, registers: [2]int{}},
-	0x72: Instruction{name: "LD (HL),D", size: 1, cycles: 8, registers: [2]int{}},
-	0x73: Instruction{name: "LD (HL),E", size: 1, cycles: 8, registers: [2]int{}},
-	0x74: Instruction{name: "LD (HL),H", size: 1, cycles: 8, registers: [2]int{}},
-	0x75: Instruction{name: "LD (HL),L", size: 1, cycles: 8, registers: [2]int{}},
-	0x76: Instruction{name: "HALT", size: 1, cycles: 4, registers: [2]int{}},
-	0x77: Instruction{name: "LD (HL),A", size: 1, cycles: 8, registers: [2]int{}},
-	0x78: Instruction{name: "LD A,B", size: 1, cycles: 4, registers: [2]int{}},
-	0x79: Instruction{name: "LD A,C", size: 1, cycles: 4, registers: [2]int{}},
-	0x7a: Instruction{name: "LD A,D", size: 1, cycles: 4, registers: [2]int{}},
-	0x7b: Instruction{name: "LD A,E", size: 1, cycles: 4, registers: [2]int{}},
-	0x7c: Instruction{name: "LD A,H", size: 1, cycles: 4, registers: [2]int{}},
-	0x7d: Instruction{name: "LD A,L", size: 1, cycles: 4, registers: [2]int{}},
-	0x7e: Instruction{name: "LD A,(HL)", size: 1, cycles: 8, registers: [2]int{}},
-	0x7f: Instruction{name: "LD A,A", size: 1, cycles: 4, registers: [2]int{}},
-	0x80: Instruction{name: "ADD A,B", size: 1, cycles: 4, registers: [2]int{}},
-	0x81: Instruction{name: "ADD A,C", size: 1, cycles: 4, registers: [2]int{}},
-	0x82: Instruction{name: "ADD A,D", size: 1, cycles: 4, registers: [2]int{}},
-	0x83: Instruction{name: "ADD A,E", size: 1, cycles: 4, registers: [2]int{}},
-	0x84: Instruction{name: "ADD A,H", size: 1, cycles: 4, registers: [2]int{}},
-	0x85: Instruction{name: "ADD A,L", size: 1, cycles: 4, registers: [2]int{}},
-	0x86: Instruction{name: "ADD A,(HL)", size: 1, cycles: 8, registers: [2]int{}},
-	0x87: Instruction{name: "ADD A,A", size: 1, cycles: 4, registers: [2]int{}},
-	0x88: Instruction{name: "ADC A,B", size: 1, cycles: 4, registers: [2]int{}},
-	0x89: Instruction{name: "ADC A,C", size: 1, cycles: 4, registers: [2]int{}},
-	0x8a: Instruction{name: "ADC A,D", size: 1, cycles: 4, registers: [2]int{}},
-	0x8b: Instruction{name: "ADC A,E", size: 1, cycles: 4, registers: [2]int{}},
-	0x8c: Instruction{name: "ADC A,H", size: 1, cycles: 4, registers: [2]int{}},
-	0x8d: Instruction{name: "ADC A,L", size: 1, cycles: 4, registers: [2]int{}},
-	0x8e: Instruction{name: "ADC A,(HL)", size: 1, cycles: 8, registers: [2]int{}},
-	0x8f: Instruction{name: "ADC A,A", size: 1, cycles: 4, registers: [2]int{}},
-	0x90: Instruction{name: "SUB B", size: 1, cycles: 4, registers: [2]int{}},
-	0x91: Instruction{name: "SUB C", size: 1, cycles: 4, registers: [2]int{}},
-	0x92: Instruction{name: "SUB D", size: 1, cycles: 4, registers: [2]int{}},
-	0x93: Instruction{name: "SUB E", size: 1, cycles: 4, registers: [2]int{}},
-	0x94: Instruction{name: "SUB H", size: 1, cycles: 4, registers: [2]int{}},
-	0x95: Instruction{name: "SUB L", size: 1, cycles: 4, registers: [2]int{}},
-	0x96: Instruction{name: "SUB (HL)", size: 1, cycles: 8, registers: [2]int{}},
-	0x97: Instruction{name: "SUB A", size: 1, cycles: 4, registers: [2]int{}},
-	0x98: Instruction{name: "SBC A,B", size: 1, cycles: 4, registers: [2]int{}},
-	0x99: Instruction{name: "SBC A,C", size: 1, cycles: 4, registers: [2]int{}},
-	0x9a: Instruction{name: "SBC A,D", size: 1, cycles: 4, registers: [2]int{}},
-	0x9b: Instruction{name: "SBC A,E", size: 1, cycles: 4, registers: [2]int{}},
-	0x9c: Instruction{name: "SBC A,H", size: 1, cycles: 4, registers: [2]int{}},
-	0x9d: Instruction{name: "SBC A,L", size: 1, cycles: 4, registers: [2]int{}},
-	0x9e: Instruction{name: "SBC A,(HL)", size: 1, cycles: 8, registers: [2]int{}},
-	0x9f: Instruction{name: "SBC A,A", size: 1, cycles: 4, registers: [2]int{}},
-	0xa0: Instruction{name: "AND B", size: 1, cycles: 4, registers: [2]int{}},
-	0xa1: Instruction{name: "AND C", size: 1, cycles: 4, registers: [2]int{}},
-	0xa2: Instruction{name: "AND D", size: 1, cycles: 4, registers: [2]int{}},
-	0xa3: Instruction{name: "AND E", size: 1, cycles: 4, registers: [2]int{}},
-	0xa4: Instruction{name: "AND H", size: 1, cycles: 4, registers: [2]int{}},
-	0xa5: Instruction{name: "AND L", size: 1, cycles: 4, registers: [2]int{}},
-	0xa6: Instruction{name: "AND (HL)", size: 1, cycles: 8, registers: [2]int{}},
-	0xa7: Instruction{name: "AND A", size: 1, cycles: 4, registers: [2]int{}},
-	0xa8: Instruction{name: "XOR B", size: 1, cycles: 4, registers: [2]int{}},
-	0xa9: Instruction{name: "XOR C", size: 1, cycles: 4, registers: [2]int{}},
-	0xaa: Instruction{name: "XOR D", size: 1, cycles: 4, registers: [2]int{}},
-	0xab: Instruction{name: "XOR E", size: 1, cycles: 4, registers: [2]int{}},
-	0xac: Instruction{name: "XOR H", size: 1, cycles: 4, registers: [2]int{}},
-	0xad: Instruction{name: "XOR L", size: 1, cycles: 4, registers: [2]int{}},
-	0xae: Instruction{name: "XOR (HL)", size: 1, cycles: 8, registers: [2]int{}},
-	0xaf: Instruction{name: "XOR A", size: 1, cycles: 4, registers: [2]int{}},
-	0xb0: Instruction{name: "OR B", size: 1, cycles: 4, registers: [2]int{}},
-	0xb1: Instruction{name: "OR C", size: 1, cycles: 4, registers: [2]int{}},
-	0xb2: Instruction{name: "OR D", size: 1, cycles: 4, registers: [2]int{}},
-	0xb3: Instruction{name: "OR E", size: 1, cycles: 4, registers: [2]int{}},
-	0xb4: Instruction{name: "OR H", size: 1, cycles: 4, registers: [2]int{}},
-	0xb5: Instruction{name: "OR L", size: 1, cycles: 4, registers: [2]int{}},
-	0xb6: Instruction{name: "OR (HL)", size: 1, cycles: 8, registers: [2]int{}},
-	0xb7: Instruction{name: "OR A", size: 1, cycles: 4, registers: [2]int{}},
-	0xb8: Instruction{name: "CP B", size: 1, cycles: 4, registers: [2]int{}},
-	0xb9: Instruction{name: "CP C", size: 1, cycles: 4, registers: [2]int{}},
-	0xba: Instruction{name: "CP D", size: 1, cycles: 4, registers: [2]int{}},
-	0xbb: Instruction{name: "CP E", size: 1, cycles: 4, registers: [2]int{}},
-	0xbc: Instruction{name: "CP H", size: 1, cycles: 4, registers: [2]int{}},
-	0xbd: Instruction{name: "CP L", size: 1, cycles: 4, registers: [2]int{}},
-	0xbe: Instruction{name: "CP (HL)", size: 1, cycles: 8, registers: [2]int{}},
-	0xbf: Instruction{name: "CP A", size: 1, cycles: 4, registers: [2]int{}},
-	0xc0: Instruction{name: "RET NZ", size: 1, cycles: 20 / 8, registers: [2]int{}},
-	0xc1: Instruction{name: "POP BC", size: 1, cycles: 12, registers: [2]int{}},
-	0xc2: Instruction{name: "JP NZ,a16", size: 3, cycles: 16 / 12, registers: [2]int{}},
-	0xc3: Instruction{name: "JP a16", size: 3, cycles: 16, registers: [2]int{}},
-	0xc4: Instruction{name: "CALL NZ,a16", size: 3, cycles: 24 / 12, registers: [2]int{}},
-	0xc5: Instruction{name: "PUSH BC", size: 1, cycles: 16, registers: [2]int{}},
-	0xc6: Instruction{name: "ADD A,d8", size: 2, cycles: 8, registers: [2]int{}},
-	0xc7: Instruction{name: "RST 00H", size: 1, cycles: 16, registers: [2]int{}},
-	0xc8: Instruction{name: "RET Z", size: 1, cycles: 20 / 8, registers: [2]int{}},
-	0xc9: Instruction{name: "RET", size: 1, cycles: 16, registers: [2]int{}},
-	0xca: Instruction{name: "JP Z,a16", size: 3, cycles: 16 / 12, registers: [2]int{}},
+	0x1:  Instruction{name: "LD BC,d16", size: 3, cycles: 12, registers: [2]int{BC}, operation: (*Cpu).ld_n_nn},
+	0x2:  Instruction{name: "LD (BC),A", size: 1, cycles: 8, registers: [2]int{BC, A}, operation: (*Cpu).ld_r1_r2},
+	0x3:  Instruction{name: "INC BC", size: 1, cycles: 8, registers: [2]int{BC}, operation: (*Cpu).inc_nn},
+	0x4:  Instruction{name: "INC B", size: 1, cycles: 4, registers: [2]int{B}, operation: (*Cpu).inc_n},
+	0x5:  Instruction{name: "DEC B", size: 1, cycles: 4, registers: [2]int{B}, operation: (*Cpu).dec_n},
+	0x6:  Instruction{name: "LD B,d8", size: 2, cycles: 8, registers: [2]int{B}, operation: (*Cpu).ld_r1_r2},
+	0x7:  Instruction{name: "RLCA", size: 1, cycles: 4, registers: [2]int{}, operation: (*Cpu).rlca},
+	0x8:  Instruction{name: "LD (a16),SP", size: 3, cycles: 20, registers: [2]int{}, operation: (*Cpu).ld_nn_sp},
+	0x9:  Instruction{name: "ADD HL,BC", size: 1, cycles: 8, registers: [2]int{HL, BC}, operation: (*Cpu).add_hl_n},
+	0xa:  Instruction{name: "LD A,(BC)", size: 1, cycles: 8, registers: [2]int{A, BC}, operation: (*Cpu).ld_r1_r2},
+	0xb:  Instruction{name: "DEC BC", size: 1, cycles: 8, registers: [2]int{BC}, operation: (*Cpu).dec_nn},
+	0xc:  Instruction{name: "INC C", size: 1, cycles: 4, registers: [2]int{C}, operation: (*Cpu).inc_n},
+	0xd:  Instruction{name: "DEC C", size: 1, cycles: 4, registers: [2]int{C}, operation: (*Cpu).dec_n},
+	0xe:  Instruction{name: "LD C,d8", size: 2, cycles: 8, registers: [2]int{C}, operation: (*Cpu).ld_r1_r2},
+	0xf:  Instruction{name: "RRCA", size: 1, cycles: 4, registers: [2]int{}, operation: (*Cpu).rrca},
+	0x10: Instruction{name: "STOP 0", size: 2, cycles: 4, registers: [2]int{}, operation: (*Cpu).stop},
+	0x11: Instruction{name: "LD DE,d16", size: 3, cycles: 12, registers: [2]int{DE}, operation: (*Cpu).ld_n_nn},
+	0x12: Instruction{name: "LD (DE),A", size: 1, cycles: 8, registers: [2]int{DE, A}, operation: (*Cpu).ld_r1_r2},
+	0x13: Instruction{name: "INC DE", size: 1, cycles: 8, registers: [2]int{DE}, operation: (*Cpu).inc_nn},
+	0x14: Instruction{name: "INC D", size: 1, cycles: 4, registers: [2]int{D}, operation: (*Cpu).inc_n},
+	0x15: Instruction{name: "DEC D", size: 1, cycles: 4, registers: [2]int{D}, operation: (*Cpu).dec_n},
+	0x16: Instruction{name: "LD D,d8", size: 2, cycles: 8, registers: [2]int{D}, operation: (*Cpu).ld_r1_r2},
+	0x17: Instruction{name: "RLA", size: 1, cycles: 4, registers: [2]int{}, operation: (*Cpu).rla},
+	0x18: Instruction{name: "JR r8", size: 2, cycles: 12, registers: [2]int{}, operation: (*Cpu).jr_n},
+	0x19: Instruction{name: "ADD HL,DE", size: 1, cycles: 8, registers: [2]int{HL, DE}, operation: (*Cpu).add_hl_n},
+	0x1a: Instruction{name: "LD A,(DE)", size: 1, cycles: 8, registers: [2]int{A, DE}, operation: (*Cpu).ld_r1_r2},
+	0x1b: Instruction{name: "DEC DE", size: 1, cycles: 8, registers: [2]int{DE}, operation: (*Cpu).dec_nn},
+	0x1c: Instruction{name: "INC E", size: 1, cycles: 4, registers: [2]int{E}, operation: (*Cpu).inc_n},
+	0x1d: Instruction{name: "DEC E", size: 1, cycles: 4, registers: [2]int{E}, operation: (*Cpu).dec_n},
+	0x1e: Instruction{name: "LD E,d8", size: 2, cycles: 8, registers: [2]int{E}, operation: (*Cpu).ld_r1_r2},
+	0x1f: Instruction{name: "RRA", size: 1, cycles: 4, registers: [2]int{}, operation: (*Cpu).rra},
+	0x20: Instruction{name: "JR NZ,r8", size: 2, cycles: 8, branchCycles: 12, registers: [2]int{COND_NZ}, operation: (*Cpu).jr_cc_n},
+	0x21: Instruction{name: "LD HL,d16", size: 3, cycles: 12, registers: [2]int{HL}, operation: (*Cpu).ld_n_nn},
+	0x22: Instruction{name: "LD (HL+),A", size: 1, cycles: 8, registers: [2]int{HL, A}, operation: (*Cpu).ldi},
+	0x23: Instruction{name: "INC HL", size: 1, cycles: 8, registers: [2]int{HL}, operation: (*Cpu).inc_nn},
+	0x24: Instruction{name: "INC H", size: 1, cycles: 4, registers: [2]int{H}, operation: (*Cpu).inc_n},
+	0x25: Instruction{name: "DEC H", size: 1, cycles: 4, registers: [2]int{H}, operation: (*Cpu).dec_n},
+	0x26: Instruction{name: "LD H,d8", size: 2, cycles: 8, registers: [2]int{H}, operation: (*Cpu).ld_r1_r2},
+	0x27: Instruction{name: "DAA", size: 1, cycles: 4, registers: [2]int{}, operation: (*Cpu).daa},
+	0x28: Instruction{name: "JR Z,r8", size: 2, cycles: 8, branchCycles: 12, registers: [2]int{COND_Z}, operation: (*Cpu).jr_cc_n},
+	0x29: Instruction{name: "ADD HL,HL", size: 1, cycles: 8, registers: [2]int{HL, HL}, operation: (*Cpu).add_hl_n},
+	0x2a: Instruction{name: "LD A,(HL+)", size: 1, cycles: 8, registers: [2]int{A, HL}, operation: (*Cpu).ldi},
+	0x2b: Instruction{name: "DEC HL", size: 1, cycles: 8, registers: [2]int{HL}, operation: (*Cpu).dec_nn},
+	0x2c: Instruction{name: "INC L", size: 1, cycles: 4, registers: [2]int{L}, operation: (*Cpu).inc_n},
+	0x2d: Instruction{name: "DEC L", size: 1, cycles: 4, registers: [2]int{L}, operation: (*Cpu).dec_n},
+	0x2e: Instruction{name: "LD L,d8", size: 2, cycles: 8, registers: [2]int{L}, operation: (*Cpu).ld_r1_r2},
+	0x2f: Instruction{name: "CPL", size: 1, cycles: 4, registers: [2]int{}, operation: (*Cpu).cpl},
+	0x30: Instruction{name: "JR NC,r8", size: 2, cycles: 8, branchCycles: 12, registers: [2]int{COND_NC}, operation: (*Cpu).jr_cc_n},
+	0x31: Instruction{name: "LD SP,d16", size: 3, cycles: 12, registers: [2]int{SP}, operation: (*Cpu).ld_n_nn},
+	0x32: Instruction{name: "LD (HL-),A", size: 1, cycles: 8, registers: [2]int{HL, A}, operation: (*Cpu).ldd},
+	0x33: Instruction{name: "INC SP", size: 1, cycles: 8, registers: [2]int{SP}, operation: (*Cpu).inc_nn},
+	0x34: Instruction{name: "INC (HL)", size: 1, cycles: 12, registers: [2]int{HL}, operation: (*Cpu).inc_n},
+	0x35: Instruction{name: "DEC (HL)", size: 1, cycles: 12, registers: [2]int{HL}, operation: (*Cpu).dec_n},
+	0x36: Instruction{name: "LD (HL),d8", size: 2, cycles: 12, registers: [2]int{HL}, operation: (*Cpu).ld_r1_r2},
+	0x37: Instruction{name: "SCF", size: 1, cycles: 4, registers: [2]int{}, operation: (*Cpu).scf},
+	0x38: Instruction{name: "JR C,r8", size: 2, cycles: 8, branchCycles: 12, registers: [2]int{COND_C}, operation: (*Cpu).jr_cc_n},
+	0x39: Instruction{name: "ADD HL,SP", size: 1, cycles: 8, registers: [2]int{HL, SP}, operation: (*Cpu).add_hl_n},
+	0x3a: Instruction{name: "LD A,(HL-)", size: 1, cycles: 8, registers: [2]int{A, HL}, operation: (*Cpu).ldd},
+	0x3b: Instruction{name: "DEC SP", size: 1, cycles: 8, registers: [2]int{SP}, operation: (*Cpu).dec_nn},
+	0x3c: Instruction{name: "INC A", size: 1, cycles: 4, registers: [2]int{A}, operation: (*Cpu).inc_n},
+	0x3d: Instruction{name: "DEC A", size: 1, cycles: 4, registers: [2]int{A}, operation: (*Cpu).dec_n},
+	0x3e: Instruction{name: "LD A,d8", size: 2, cycles: 8, registers: [2]int{A}, operation: (*Cpu).ld_r1_r2},
+	0x3f: Instruction{name: "CCF", size: 1, cycles: 4, registers: [2]int{}, operation: (*Cpu).ccf},
+	0x40: Instruction{name: "LD B,B", size: 1, cycles: 4, registers: [2]int{B, B}, operation: (*Cpu).ld_r1_r2},
+	0x41: Instruction{name: "LD B,C", size: 1, cycles: 4, registers: [2]int{B, C}, operation: (*Cpu).ld_r1_r2},
+	0x42: Instruction{name: "LD B,D", size: 1, cycles: 4, registers: [2]int{B, D}, operation: (*Cpu).ld_r1_r2},
+	0x43: Instruction{name: "LD B,E", size: 1, cycles: 4, registers: [2]int{B, E}, operation: (*Cpu).ld_r1_r2},
+	0x44: Instruction{name: "LD B,H", size: 1, cycles: 4, registers: [2]int{B, H}, operation: (*Cpu).ld_r1_r2},
+	0x45: Instruction{name: "LD B,L", size: 1, cycles: 4, registers: [2]int{B, L}, operation: (*Cpu).ld_r1_r2},
+	0x46: Instruction{name: "LD B,(HL)", size: 1, cycles: 8, registers: [2]int{B, HL}, operation: (*Cpu).ld_r1_r2},
+	0x47: Instruction{name: "LD B,A", size: 1, cycles: 4, registers: [2]int{B, A}, operation: (*Cpu).ld_r1_r2},
+	0x48: Instruction{name: "LD C,B", size: 1, cycles: 4, registers: [2]int{C, B}, operation: (*Cpu).ld_r1_r2},
+	0x49: Instruction{name: "LD C,C", size: 1, cycles: 4, registers: [2]int{C, C}, operation: (*Cpu).ld_r1_r2},
+	0x4a: Instruction{name: "LD C,D", size: 1, cycles: 4, registers: [2]int{C, D}, operation: (*Cpu).ld_r1_r2},
+	0x4b: Instruction{name: "LD C,E", size: 1, cycles: 4, registers: [2]int{C, E}, operation: (*Cpu).ld_r1_r2},
+	0x4c: Instruction{name: "LD C,H", size: 1, cycles: 4, registers: [2]int{C, H}, operation: (*Cpu).ld_r1_r2},
+	0x4d: Instruction{name: "LD C,L", size: 1, cycles: 4, registers: [2]int{C, L}, operation: (*Cpu).ld_r1_r2},
+	0x4e: Instruction{name: "LD C,(HL)", size: 1, cycles: 8, registers: [2]int{C, HL}, operation: (*Cpu).ld_r1_r2},
+	0x4f: Instruction{name: "LD C,A", size: 1, cycles: 4, registers: [2]int{C, A}, operation: (*Cpu).ld_r1_r2},
+	0x50: Instruction{name: "LD D,B", size: 1, cycles: 4, registers: [2]int{D, B}, operation: (*Cpu).ld_r1_r2},
+	0x51: Instruction{name: "LD D,C", size: 1, cycles: 4, registers: [2]int{D, C}, operation: (*Cpu).ld_r1_r2},
+	0x52: Instruction{name: "LD D,D", size: 1, cycles: 4, registers: [2]int{D, D}, operation: (*Cpu).ld_r1_r2},
+	0x53: Instruction{name: "LD D,E", size: 1, cycles: 4, registers: [2]int{D, E}, operation: (*Cpu).ld_r1_r2},
+	0x54: Instruction{name: "LD D,H", size: 1, cycles: 4, registers: [2]int{D, H}, operation: (*Cpu).ld_r1_r2},
+	0x55: Instruction{name: "LD D,L", size: 1, cycles: 4, registers: [2]int{D, L}, operation: (*Cpu).ld_r1_r2},
+	0x56: Instruction{name: "LD D,(HL)", size: 1, cycles: 8, registers: [2]int{D, HL}, operation: (*Cpu).ld_r1_r2},
+	0x57: Instruction{name: "LD D,A", size: 1, cycles: 4, registers: [2]int{D, A}, operation: (*Cpu).ld_r1_r2},
+	0x58: Instruction{name: "LD E,B", size: 1, cycles: 4, registers: [2]int{E, B}, operation: (*Cpu).ld_r1_r2},
+	0x59: Instruction{name: "LD E,C", size: 1, cycles: 4, registers: [2]int{E, C}, operation: (*Cpu).ld_r1_r2},
+	0x5a: Instruction{name: "LD E,D", size: 1, cycles: 4, registers: [2]int{E, D}, operation: (*Cpu).ld_r1_r2},
+	0x5b: Instruction{name: "LD E,E", size: 1, cycles: 4, registers: [2]int{E, E}, operation: (*Cpu).ld_r1_r2},
+	0x5c: Instruction{name: "LD E,H", size: 1, cycles: 4, registers: [2]int{E, H}, operation: (*Cpu).ld_r1_r2},
+	0x5d: Instruction{name: "LD E,L", size: 1, cycles: 4, registers: [2]int{E, L}, operation: (*Cpu).ld_r1_r2},
+	0x5e: Instruction{name: "LD E,(HL)", size: 1, cycles: 8, registers: [2]int{E, HL}, operation: (*Cpu).ld_r1_r2},
+	0x5f: Instruction{name: "LD E,A", size: 1, cycles: 4, registers: [2]int{E, A}, operation: (*Cpu).ld_r1_r2},
+	0x60: Instruction{name: "LD H,B", size: 1, cycles: 4, registers: [2]int{H, B}, operation: (*Cpu).ld_r1_r2},
+	0x61: Instruction{name: "LD H,C", size: 1, cycles: 4, registers: [2]int{H, C}, operation: (*Cpu).ld_r1_r2},
+	0x62: Instruction{name: "LD H,D", size: 1, cycles: 4, registers: [2]int{H, D}, operation: (*Cpu).ld_r1_r2},
+	0x63: Instruction{name: "LD H,E", size: 1, cycles: 4, registers: [2]int{H, E}, operation: (*Cpu).ld_r1_r2},
+	0x64: Instruction{name: "LD H,H", size: 1, cycles: 4, registers: [2]int{H, H}, operation: (*Cpu).ld_r1_r2},
+	0x65: Instruction{name: "LD H,L", size: 1, cycles: 4, registers: [2]int{H, L}, operation: (*Cpu).ld_r1_r2},
+	0x66: Instruction{name: "LD H,(HL)", size: 1, cycles: 8, registers: [2]int{H, HL}, operation: (*Cpu).ld_r1_r2},
+	0x67: Instruction{name: "LD H,A", size: 1, cycles: 4, registers: [2]int{H, A}, operation: (*Cpu).ld_r1_r2},
+	0x68: Instruction{name: "LD L,B", size: 1, cycles: 4, registers: [2]int{L, B}, operation: (*Cpu).ld_r1_r2},
+	0x69: Instruction{name: "LD L,C", size: 1, cycles: 4, registers: [2]int{L, C}, operation: (*Cpu).ld_r1_r2},
+	0x6a: Instruction{name: "LD L,D", size: 1, cycles: 4, registers: [2]int{L, D}, operation: (*Cpu).ld_r1_r2},
+	0x6b: Instruction{name: "LD L,E", size: 1, cycles: 4, registers: [2]int{L, E}, operation: (*Cpu).ld_r1_r2},
+	0x6c: Instruction{name: "LD L,H", size: 1, cycles: 4, registers: [2]int{L, H}, operation: (*Cpu).ld_r1_r2},
+	0x6d: Instruction{name: "LD L,L", size: 1, cycles: 4, registers: [2]int{L, L}, operation: (*Cpu).ld_r1_r2},
+	0x6e: Instruction{name: "LD L,(HL)", size: 1, cycles: 8, registers: [2]int{L, HL}, operation: (*Cpu).ld_r1_r2},
+	0x6f: Instruction{name: "LD L,A", size: 1, cycles: 4, registers: [2]int{L, A}, operation: (*Cpu).ld_r1_r2},
+	0x70: Instruction{name: "LD (HL),B", size: 1, cycles: 8, registers: [2]int{HL, B}, operation: (*Cpu).ld_r1_r2},
+	0x71: Instruction{name: "LD (HL),C", size: 1, cycles: 8, registers: [2]int{HL, C}, operation: (*Cpu).ld_r1_r2},
+	0x72: Instruction{name: "LD (HL),D", size: 1, cycles: 8, registers: [2]int{HL, D}, operation: (*Cpu).ld_r1_r2},
+	0x73: Instruction{name: "LD (HL),E", size: 1, cycles: 8, registers: [2]int{HL, E}, operation: (*Cpu).ld_r1_r2},
+	0x74: Instruction{name: "LD (HL),H", size: 1, cycles: 8, registers: [2]int{HL, H}, operation: (*Cpu).ld_r1_r2},
+	0x75: Instruction{name: "LD (HL),L", size: 1, cycles: 8, registers: [2]int{HL, L}, operation: (*Cpu).ld_r1_r2},
+	0x76: Instruction{name: "HALT", size: 1, cycles: 4, registers: [2]int{}, operation: (*Cpu).halt},
+	0x77: Instruction{name: "LD (HL),A", size: 1, cycles: 8, registers: [2]int{HL, A}, operation: (*Cpu).ld_r1_r2},
+	0x78: Instruction{name: "LD A,B", size: 1, cycles: 4, registers: [2]int{A, B}, operation: (*Cpu).ld_r1_r2},
+	0x79: Instruction{name: "LD A,C", size: 1, cycles: 4, registers: [2]int{A, C}, operation: (*Cpu).ld_r1_r2},
+	0x7a: Instruction{name: "LD A,D", size: 1, cycles: 4, registers: [2]int{A, D}, operation: (*Cpu).ld_r1_r2},
+	0x7b: Instruction{name: "LD A,E", size: 1, cycles: 4, registers: [2]int{A, E}, operation: (*Cpu).ld_r1_r2},
+	0x7c: Instruction{name: "LD A,H", size: 1, cycles: 4, registers: [2]int{A, H}, operation: (*Cpu).ld_r1_r2},
+	0x7d: Instruction{name: "LD A,L", size: 1, cycles: 4, registers: [2]int{A, L}, operation: (*Cpu).ld_r1_r2},
+	0x7e: Instruction{name: "LD A,(HL)", size: 1, cycles: 8, registers: [2]int{A, HL}, operation: (*Cpu).ld_r1_r2},
+	0x7f: Instruction{name: "LD A,A", size: 1, cycles: 4, registers: [2]int{A, A}, operation: (*Cpu).ld_r1_r2},
+	0x80: Instruction{name: "ADD A,B", size: 1, cycles: 4, registers: [2]int{A, B}, operation: (*Cpu).add_a_n},
+	0x81: Instruction{name: "ADD A,C", size: 1, cycles: 4, registers: [2]int{A, C}, operation: (*Cpu).add_a_n},
+	0x82: Instruction{name: "ADD A,D", size: 1, cycles: 4, registers: [2]int{A, D}, operation: (*Cpu).add_a_n},
+	0x83: Instruction{name: "ADD A,E", size: 1, cycles: 4, registers: [2]int{A, E}, operation: (*Cpu).add_a_n},
+	0x84: Instruction{name: "ADD A,H", size: 1, cycles: 4, registers: [2]int{A, H}, operation: (*Cpu).add_a_n},
+	0x85: Instruction{name: "ADD A,L", size: 1, cycles: 4, registers: [2]int{A, L}, operation: (*Cpu).add_a_n},
+	0x86: Instruction{name: "ADD A,(HL)", size: 1, cycles: 8, registers: [2]int{A, HL}, operation: (*Cpu).add_a_n},
+	0x87: Instruction{name: "ADD A,A", size: 1, cycles: 4, registers: [2]int{A, A}, operation: (*Cpu).add_a_n},
+	0x88: Instruction{name: "ADC A,B", size: 1, cycles: 4, registers: [2]int{A, B}, operation: (*Cpu).adc_a_n},
+	0x89: Instruction{name: "ADC A,C", size: 1, cycles: 4, registers: [2]int{A, C}, operation: (*Cpu).adc_a_n},
+	0x8a: Instruction{name: "ADC A,D", size: 1, cycles: 4, registers: [2]int{A, D}, operation: (*Cpu).adc_a_n},
+	0x8b: Instruction{name: "ADC A,E", size: 1, cycles: 4, registers: [2]int{A, E}, operation: (*Cpu).adc_a_n},
+	0x8c: Instruction{name: "ADC A,H", size: 1, cycles: 4, registers: [2]int{A, H}, operation: (*Cpu).adc_a_n},
+	0x8d: Instruction{name: "ADC A,L", size: 1, cycles: 4, registers: [2]int{A, L}, operation: (*Cpu).adc_a_n},
+	0x8e: Instruction{name: "ADC A,(HL)", size: 1, cycles: 8, registers: [2]int{A, HL}, operation: (*Cpu).adc_a_n},
+	0x8f: Instruction{name: "ADC A,A", size: 1, cycles: 4, registers: [2]int{A, A}, operation: (*Cpu).adc_a_n},
+	0x90: Instruction{name: "SUB B", size: 1, cycles: 4, registers: [2]int{A, B}, operation: (*Cpu).sub_n},
+	0x91: Instruction{name: "SUB C", size: 1, cycles: 4, registers: [2]int{A, C}, operation: (*Cpu).sub_n},
+	0x92: Instruction{name: "SUB D", size: 1, cycles: 4, registers: [2]int{A, D}, operation: (*Cpu).sub_n},
+	0x93: Instruction{name: "SUB E", size: 1, cycles: 4, registers: [2]int{A, E}, operation: (*Cpu).sub_n},
+	0x94: Instruction{name: "SUB H", size: 1, cycles: 4, registers: [2]int{A, H}, operation: (*Cpu).sub_n},
+	0x95: Instruction{name: "SUB L", size: 1, cycles: 4, registers: [2]int{A, L}, operation: (*Cpu).sub_n},
+	0x96: Instruction{name: "SUB (HL)", size: 1, cycles: 8, registers: [2]int{A, HL}, operation: (*Cpu).sub_n},
+	0x97: Instruction{name: "SUB A", size: 1, cycles: 4, registers: [2]int{A, A}, operation: (*Cpu).sub_n},
+	0x98: Instruction{name: "SBC A,B", size: 1, cycles: 4, registers: [2]int{A, B}, operation: (*Cpu).sbc_a_n},
+	0x99: Instruction{name: "SBC A,C", size: 1, cycles: 4, registers: [2]int{A, C}, operation: (*Cpu).sbc_a_n},
+	0x9a: Instruction{name: "SBC A,D", size: 1, cycles: 4, registers: [2]int{A, D}, operation: (*Cpu).sbc_a_n},
+	0x9b: Instruction{name: "SBC A,E", size: 1, cycles: 4, registers: [2]int{A, E}, operation: (*Cpu).sbc_a_n},
+	0x9c: Instruction{name: "SBC A,H", size: 1, cycles: 4, registers: [2]int{A, H}, operation: (*Cpu).sbc_a_n},
+	0x9d: Instruction{name: "SBC A,L", size: 1, cycles: 4, registers: [2]int{A, L}, operation: (*Cpu).sbc_a_n},
+	0x9e: Instruction{name: "SBC A,(HL)", size: 1, cycles: 8, registers: [2]int{A, HL}, operation: (*Cpu).sbc_a_n},
+	0x9f: Instruction{name: "SBC A,A", size: 1, cycles: 4, registers: [2]int{A, A}, operation: (*Cpu).sbc_a_n},
+	0xa0: Instruction{name: "AND B", size: 1, cycles: 4, registers: [2]int{A, B}, operation: (*Cpu).and_n},
+	0xa1: Instruction{name: "AND C", size: 1, cycles: 4, registers: [2]int{A, C}, operation: (*Cpu).and_n},
+	0xa2: Instruction{name: "AND D", size: 1, cycles: 4, registers: [2]int{A, D}, operation: (*Cpu).and_n},
+	0xa3: Instruction{name: "AND E", size: 1, cycles: 4, registers: [2]int{A, E}, operation: (*Cpu).and_n},
+	0xa4: Instruction{name: "AND H", size: 1, cycles: 4, registers: [2]int{A, H}, operation: (*Cpu).and_n},
+	0xa5: Instruction{name: "AND L", size: 1, cycles: 4, registers: [2]int{A, L}, operation: (*Cpu).and_n},
+	0xa6: Instruction{name: "AND (HL)", size: 1, cycles: 8, registers: [2]int{A, HL}, operation: (*Cpu).and_n},
+	0xa7: Instruction{name: "AND A", size: 1, cycles: 4, registers: [2]int{A, A}, operation: (*Cpu).and_n},
+	0xa8: Instruction{name: "XOR B", size: 1, cycles: 4, registers: [2]int{A, B}, operation: (*Cpu).xor_n},
+	0xa9: Instruction{name: "XOR C", size: 1, cycles: 4, registers: [2]int{A, C}, operation: (*Cpu).xor_n},
+	0xaa: Instruction{name: "XOR D", size: 1, cycles: 4, registers: [2]int{A, D}, operation: (*Cpu).xor_n},
+	0xab: Instruction{name: "XOR E", size: 1, cycles: 4, registers: [2]int{A, E}, operation: (*Cpu).xor_n},
+	0xac: Instruction{name: "XOR H", size: 1, cycles: 4, registers: [2]int{A, H}, operation: (*Cpu).xor_n},
+	0xad: Instruction{name: "XOR L", size: 1, cycles: 4, registers: [2]int{A, L}, operation: (*Cpu).xor_n},
+	0xae: Instruction{name: "XOR (HL)", size: 1, cycles: 8, registers: [2]int{A, HL}, operation: (*Cpu).xor_n},
+	0xaf: Instruction{name: "XOR A", size: 1, cycles: 4, registers: [2]int{A, A}, operation: (*Cpu).xor_n},
+	0xb0: Instruction{name: "OR B", size: 1, cycles: 4, registers: [2]int{A, B}, operation: (*Cpu).or_n},
+	0xb1: Instruction{name: "OR C", size: 1, cycles: 4, registers: [2]int{A, C}, operation: (*Cpu).or_n},
+	0xb2: Instruction{name: "OR D", size: 1, cycles: 4, registers: [2]int{A, D}, operation: (*Cpu).or_n},
+	0xb3: Instruction{name: "OR E", size: 1, cycles: 4, registers: [2]int{A, E}, operation: (*Cpu).or_n},
+	0xb4: Instruction{name: "OR H", size: 1, cycles: 4, registers: [2]int{A, H}, operation: (*Cpu).or_n},
+	0xb5: Instruction{name: "OR L", size: 1, cycles: 4, registers: [2]int{A, L}, operation: (*Cpu).or_n},
+	0xb6: Instruction{name: "OR (HL)", size: 1, cycles: 8, registers: [2]int{A, HL}, operation: (*Cpu).or_n},
+	0xb7: Instruction{name: "OR A", size: 1, cycles: 4, registers: [2]int{A, A}, operation: (*Cpu).or_n},
+	0xb8: Instruction{name: "CP B", size: 1, cycles: 4, registers: [2]int{A, B}, operation: (*Cpu).cp_n},
+	0xb9: Instruction{name: "CP C", size: 1, cycles: 4, registers: [2]int{A, C}, operation: (*Cpu).cp_n},
+	0xba: Instruction{name: "CP D", size: 1, cycles: 4, registers: [2]int{A, D}, operation: (*Cpu).cp_n},
+	0xbb: Instruction{name: "CP E", size: 1, cycles: 4, registers: [2]int{A, E}, operation: (*Cpu).cp_n},
+	0xbc: Instruction{name: "CP H", size: 1, cycles: 4, registers: [2]int{A, H}, operation: (*Cpu).cp_n},
+	0xbd: Instruction{name: "CP L", size: 1, cycles: 4, registers: [2]int{A, L}, operation: (*Cpu).cp_n},
+	0xbe: Instruction{name: "CP (HL)", size: 1, cycles: 8, registers: [2]int{A, HL}, operation: (*Cpu).cp_n},
+	0xbf: Instruction{name: "CP A", size: 1, cycles: 4, registers: [2]int{A, A}, operation: (*Cpu).cp_n},
+	0xc0: Instruction{name: "RET NZ", size: 1, cycles: 8, branchCycles: 20, registers: [2]int{COND_NZ}, operation: (*Cpu).ret_cc},
+	0xc1: Instruction{name: "POP BC", size: 1, cycles: 12, registers: [2]int{BC}, operation: (*Cpu).pop_nn},
+	0xc2: Instruction{name: "JP NZ,a16", size: 3, cycles: 12, branchCycles: 16, registers: [2]int{COND_NZ}, operation: (*Cpu).jp_cc_nn},
+	0xc3: Instruction{name: "JP a16", size: 3, cycles: 16, registers: [2]int{}, operation: (*Cpu).jp_nn},
+	0xc4: Instruction{name: "CALL NZ,a16", size: 3, cycles: 12, branchCycles: 24, registers: [2]int{COND_NZ}, operation: (*Cpu).call_cc_nn},
+	0xc5: Instruction{name: "PUSH BC", size: 1, cycles: 16, registers: [2]int{BC}, operation: (*Cpu).push_nn},
+	0xc6: Instruction{name: "ADD A,d8", size: 2, cycles: 8, registers: [2]int{A}, operation: (*Cpu).add_a_n},
+	0xc7: Instruction{name: "RST 00H", size: 1, cycles: 16, registers: [2]int{}, operation: func(cpu *Cpu) { cpu.rst_n(0x00) }},
+	0xc8: Instruction{name: "RET Z", size: 1, cycles: 8, branchCycles: 20, registers: [2]int{COND_Z}, operation: (*Cpu).ret_cc},
+	0xc9: Instruction{name: "RET", size: 1, cycles: 16, registers: [2]int{}, operation: (*Cpu).ret},
+	0xca: Instruction{name: "JP Z,a16", size: 3, cycles: 12, branchCycles: 16, registers: [2]int{COND_Z}, operation: (*Cpu).jp_cc_nn},
 	0xcb: Instruction{name: "PREFIX CB", size: 1, cycles: 4, registers: [2]int{}},
-	0xcc: Instruction{name: "CALL Z,a16", size: 3, cycles: 24 / 12, registers: [2]int{}},
-	0xcd: Instruction{name: "CALL a16", size: 3, cycles: 24, registers: [2]int{}},
-	0xce: Instruction{name: "ADC A,d8", size: 2, cycles: 8, registers: [2]int{}},
-	0xcf: Instruction{name: "RST 08H", size: 1, cycles: 16, registers: [2]int{}},
-	0xd0: Instruction{name: "RET NC", size: 1, cycles: 20 / 8, registers: [2]int{}},
-	0xd1: Instruction{name: "POP DE", size: 1, cycles: 12, registers: [2]int{}},
-	0xd2: Instruction{name: "JP NC,a16", size: 3, cycles: 16 / 12, registers: [2]int{}},
-	0xd4: Instruction{name: "CALL NC,a16", size: 3, cycles: 24 / 12, registers: [2]int{}},
-	0xd5: Instruction{name: "PUSH DE", size: 1, cycles: 16, registers: [2]int{}},
-	0xd6: Instruction{name: "SUB d8", size: 2, cycles: 8, registers: [2]int{}},
-	0xd7: Instruction{name: "RST 10H", size: 1, cycles: 16, registers: [2]int{}},
-	0xd8: Instruction{name: "RET C", size: 1, cycles: 20 / 8, registers: [2]int{}},
-	0xd9: Instruction{name: "RETI", size: 1, cycles: 16, registers: [2]int{}},
-	0xda: Instruction{name: "JP C,a16", size: 3, cycles: 16 / 12, registers: [2]int{}},
-	0xdc: Instruction{name: "CALL C,a16", size: 3, cycles: 24 / 12, registers: [2]int{}},
-	0xde: Instruction{name: "SBC A,d8", size: 2, cycles: 8, registers: [2]int{}},
-	0xdf: Instruction{name: "RST 18H", size: 1, cycles: 16, registers: [2]int{}},
-	0xe0: Instruction{name: "LDH (a8),A", size: 2, cycles: 12, registers: [2]int{}},
-	0xe1: Instruction{name: "POP HL", size: 1, cycles: 12, registers: [2]int{}},
-	0xe2: Instruction{name: "LD (C),A", size: 2, cycles: 8, registers: [2]int{}},
-	0xe5: Instruction{name: "PUSH HL", size: 1, cycles: 16, registers: [2]int{}},
-	0xe6: Instruction{name: "AND d8", size: 2, cycles: 8, registers: [2]int{}},
-	0xe7: Instruction{name: "RST 20H", size: 1, cycles: 16, registers: [2]int{}},
-	0xe8: Instruction{name: "ADD SP,r8", size: 2, cycles: 16, registers: [2]int{}},
-	0xe9: Instruction{name: "JP (HL)", size: 1, cycles: 4, registers: [2]int{}},
-	0xea: Instruction{name: "LD (a16),A", size: 3, cycles: 16, registers: [2]int{}},
-	0xee: Instruction{name: "XOR d8", size: 2, cycles: 8, registers: [2]int{}},
-	0xef: Instruction{name: "RST 28H", size: 1, cycles: 16, registers: [2]int{}},
-	0xf0: Instruction{name: "LDH A,(a8)", size: 2, cycles: 12, registers: [2]int{}},
-	0xf1: Instruction{name: "POP AF", size: 1, cycles: 12, registers: [2]int{}},
-	0xf2: Instruction{name: "LD A,(C)", size: 2, cycles: 8, registers: [2]int{}},
-	0xf3: Instruction{name: "DI", size: 1, cycles: 4, registers: [2]int{}},
-	0xf5: Instruction{name: "PUSH AF", size: 1, cycles: 16, registers: [2]int{}},
-	0xf6: Instruction{name: "OR d8", size: 2, cycles: 8, registers: [2]int{}},
-	0xf7: Instruction{name: "RST 30H", size: 1, cycles: 16, registers: [2]int{}},
-	0xf8: Instruction{name: "LD HL,SP+r8", size: 2, cycles: 12, registers: [2]int{}},
-	0xf9: Instruction{name: "LD SP,HL", size: 1, cycles: 8, registers: [2]int{}},
-	0xfa: Instruction{name: "LD A,(a16)", size: 3, cycles: 16, registers: [2]int{}},
-	0xfb: Instruction{name: "EI", size: 1, cycles: 4, registers: [2]int{}},
-	0xfe: Instruction{name: "CP d8", size: 2, cycles: 8, registers: [2]int{}},
-	0xff: Instruction{name: "RST 38H", size: 1, cycles: 16, registers: [2]int{}},
+	0xcc: Instruction{name: "CALL Z,a16", size: 3, cycles: 12, branchCycles: 24, registers: [2]int{COND_Z}, operation: (*Cpu).call_cc_nn},
+	0xcd: Instruction{name: "CALL a16", size: 3, cycles: 24, registers: [2]int{}, operation: (*Cpu).call_nn},
+	0xce: Instruction{name: "ADC A,d8", size: 2, cycles: 8, registers: [2]int{A}, operation: (*Cpu).adc_a_n},
+	0xcf: Instruction{name: "RST 08H", size: 1, cycles: 16, registers: [2]int{}, operation: func(cpu *Cpu) { cpu.rst_n(0x08) }},
+	0xd0: Instruction{name: "RET NC", size: 1, cycles: 8, branchCycles: 20, registers: [2]int{COND_NC}, operation: (*Cpu).ret_cc},
+	0xd1: Instruction{name: "POP DE", size: 1, cycles: 12, registers: [2]int{DE}, operation: (*Cpu).pop_nn},
+	0xd2: Instruction{name: "JP NC,a16", size: 3, cycles: 12, branchCycles: 16, registers: [2]int{COND_NC}, operation: (*Cpu).jp_cc_nn},
+	0xd3: Instruction{name: "ILLEGAL", size: 1, cycles: 4, registers: [2]int{}, operation: (*Cpu).illegal},
+	0xd4: Instruction{name: "CALL NC,a16", size: 3, cycles: 12, branchCycles: 24, registers: [2]int{COND_NC}, operation: (*Cpu).call_cc_nn},
+	0xd5: Instruction{name: "PUSH DE", size: 1, cycles: 16, registers: [2]int{DE}, operation: (*Cpu).push_nn},
+	0xd6: Instruction{name: "SUB d8", size: 2, cycles: 8, registers: [2]int{A}, operation: (*Cpu).sub_n},
+	0xd7: Instruction{name: "RST 10H", size: 1, cycles: 16, registers: [2]int{}, operation: func(cpu *Cpu) { cpu.rst_n(0x10) }},
+	0xd8: Instruction{name: "RET C", size: 1, cycles: 8, branchCycles: 20, registers: [2]int{COND_C}, operation: (*Cpu).ret_cc},
+	0xd9: Instruction{name: "RETI", size: 1, cycles: 16, registers: [2]int{}, operation: (*Cpu).reti},
+	0xda: Instruction{name: "JP C,a16", size: 3, cycles: 12, branchCycles: 16, registers: [2]int{COND_C}, operation: (*Cpu).jp_cc_nn},
+	0xdb: Instruction{name: "ILLEGAL", size: 1, cycles: 4, registers: [2]int{}, operation: (*Cpu).illegal},
+	0xdc: Instruction{name: "CALL C,a16", size: 3, cycles: 12, branchCycles: 24, registers: [2]int{COND_C}, operation: (*Cpu).call_cc_nn},
+	0xdd: Instruction{name: "ILLEGAL", size: 1, cycles: 4, registers: [2]int{}, operation: (*Cpu).illegal},
+	0xde: Instruction{name: "SBC A,d8", size: 2, cycles: 8, registers: [2]int{A}, operation: (*Cpu).sbc_a_n},
+	0xdf: Instruction{name: "RST 18H", size: 1, cycles: 16, registers: [2]int{}, operation: func(cpu *Cpu) { cpu.rst_n(0x18) }},
+	0xe0: Instruction{name: "LDH (a8),A", size: 2, cycles: 12, registers: [2]int{}, operation: (*Cpu).ldh_n_a},
+	0xe1: Instruction{name: "POP HL", size: 1, cycles: 12, registers: [2]int{HL}, operation: (*Cpu).pop_nn},
+	0xe2: Instruction{name: "LD (C),A", size: 1, cycles: 8, registers: [2]int{}, operation: (*Cpu).ld_c_a},
+	0xe3: Instruction{name: "ILLEGAL", size: 1, cycles: 4, registers: [2]int{}, operation: (*Cpu).illegal},
+	0xe4: Instruction{name: "ILLEGAL", size: 1, cycles: 4, registers: [2]int{}, operation: (*Cpu).illegal},
+	0xe5: Instruction{name: "PUSH HL", size: 1, cycles: 16, registers: [2]int{HL}, operation: (*Cpu).push_nn},
+	0xe6: Instruction{name: "AND d8", size: 2, cycles: 8, registers: [2]int{A}, operation: (*Cpu).and_n},
+	0xe7: Instruction{name: "RST 20H", size: 1, cycles: 16, registers: [2]int{}, operation: func(cpu *Cpu) { cpu.rst_n(0x20) }},
+	0xe8: Instruction{name: "ADD SP,r8", size: 2, cycles: 16, registers: [2]int{}, operation: (*Cpu).add_sp_n},
+	0xe9: Instruction{name: "JP (HL)", size: 1, cycles: 4, registers: [2]int{}, operation: (*Cpu).jp_hl},
+	0xea: Instruction{name: "LD (a16),A", size: 3, cycles: 16, registers: [2]int{}, operation: (*Cpu).ld_nn_a},
+	0xeb: Instruction{name: "ILLEGAL", size: 1, cycles: 4, registers: [2]int{}, operation: (*Cpu).illegal},
+	0xec: Instruction{name: "ILLEGAL", size: 1, cycles: 4, registers: [2]int{}, operation: (*Cpu).illegal},
+	0xed: Instruction{name: "ILLEGAL", size: 1, cycles: 4, registers: [2]int{}, operation: (*Cpu).illegal},
+	0xee: Instruction{name: "XOR d8", size: 2, cycles: 8, registers: [2]int{A}, operation: (*Cpu).xor_n},
+	0xef: Instruction{name: "RST 28H", size: 1, cycles: 16, registers: [2]int{}, operation: func(cpu *Cpu) { cpu.rst_n(0x28) }},
+	0xf0: Instruction{name: "LDH A,(a8)", size: 2, cycles: 12, registers: [2]int{}, operation: (*Cpu).ldh_a_n},
+	0xf1: Instruction{name: "POP AF", size: 1, cycles: 12, registers: [2]int{AF}, operation: (*Cpu).pop_nn},
+	0xf2: Instruction{name: "LD A,(C)", size: 1, cycles: 8, registers: [2]int{}, operation: (*Cpu).ld_a_c},
+	0xf3: Instruction{name: "DI", size: 1, cycles: 4, registers: [2]int{}, operation: (*Cpu).di},
+	0xf4: Instruction{name: "ILLEGAL", size: 1, cycles: 4, registers: [2]int{}, operation: (*Cpu).illegal},
+	0xf5: Instruction{name: "PUSH AF", size: 1, cycles: 16, registers: [2]int{AF}, operation: (*Cpu).push_nn},
+	0xf6: Instruction{name: "OR d8", size: 2, cycles: 8, registers: [2]int{A}, operation: (*Cpu).or_n},
+	0xf7: Instruction{name: "RST 30H", size: 1, cycles: 16, registers: [2]int{}, operation: func(cpu *Cpu) { cpu.rst_n(0x30) }},
+	0xf8: Instruction{name: "LD HL,SP+r8", size: 2, cycles: 12, registers: [2]int{}, operation: (*Cpu).ldhl_sp_n},
+	0xf9: Instruction{name: "LD SP,HL", size: 1, cycles: 8, registers: [2]int{}, operation: (*Cpu).ld_sp_hl},
+	0xfa: Instruction{name: "LD A,(a16)", size: 3, cycles: 16, registers: [2]int{}, operation: (*Cpu).ld_a_nn},
+	0xfb: Instruction{name: "EI", size: 1, cycles: 4, registers: [2]int{}, operation: (*Cpu).ei},
+	0xfc: Instruction{name: "ILLEGAL", size: 1, cycles: 4, registers: [2]int{}, operation: (*Cpu).illegal},
+	0xfd: Instruction{name: "ILLEGAL", size: 1, cycles: 4, registers: [2]int{}, operation: (*Cpu).illegal},
+	0xfe: Instruction{name: "CP d8", size: 2, cycles: 8, registers: [2]int{A}, operation: (*Cpu).cp_n},
+	0xff: Instruction{name: "RST 38H", size: 1, cycles: 16, registers: [2]int{}, operation: func(cpu *Cpu) { cpu.rst_n(0x38) }},
+}
+
+// CB-prefixed instructions, indexed by the byte following 0xcb. Sizes
+// and cycles include the prefix, and decode stores the indexing byte as
+// the first operand.
+var cbInstructionSet = map[int]Instruction{
+	0x0:  Instruction{name: "RLC B", size: 2, cycles: 8, registers: [2]int{B}, operation: (*Cpu).rlc_n},
+	0x1:  Instruction{name: "RLC C", size: 2, cycles: 8, registers: [2]int{C}, operation: (*Cpu).rlc_n},
+	0x2:  Instruction{name: "RLC D", size: 2, cycles: 8, registers: [2]int{D}, operation: (*Cpu).rlc_n},
+	0x3:  Instruction{name: "RLC E", size: 2, cycles: 8, registers: [2]int{E}, operation: (*Cpu).rlc_n},
+	0x4:  Instruction{name: "RLC H", size: 2, cycles: 8, registers: [2]int{H}, operation: (*Cpu).rlc_n},
+	0x5:  Instruction{name: "RLC L", size: 2, cycles: 8, registers: [2]int{L}, operation: (*Cpu).rlc_n},
+	0x6:  Instruction{name: "RLC (HL)", size: 2, cycles: 16, registers: [2]int{HL}, operation: (*Cpu).rlc_n},
+	0x7:  Instruction{name: "RLC A", size: 2, cycles: 8, registers: [2]int{A}, operation: (*Cpu).rlc_n},
+	0x8:  Instruction{name: "RRC B", size: 2, cycles: 8, registers: [2]int{B}, operation: (*Cpu).rrc_n},
+	0x9:  Instruction{name: "RRC C", size: 2, cycles: 8, registers: [2]int{C}, operation: (*Cpu).rrc_n},
+	0xa:  Instruction{name: "RRC D", size: 2, cycles: 8, registers: [2]int{D}, operation: (*Cpu).rrc_n},
+	0xb:  Instruction{name: "RRC E", size: 2, cycles: 8, registers: [2]int{E}, operation: (*Cpu).rrc_n},
+	0xc:  Instruction{name: "RRC H", size: 2, cycles: 8, registers: [2]int{H}, operation: (*Cpu).rrc_n},
+	0xd:  Instruction{name: "RRC L", size: 2, cycles: 8, registers: [2]int{L}, operation: (*Cpu).rrc_n},
+	0xe:  Instruction{name: "RRC (HL)", size: 2, cycles: 16, registers: [2]int{HL}, operation: (*Cpu).rrc_n},
+	0xf:  Instruction{name: "RRC A", size: 2, cycles: 8, registers: [2]int{A}, operation: (*Cpu).rrc_n},
+	0x10: Instruction{name: "RL B", size: 2, cycles: 8, registers: [2]int{B}, operation: (*Cpu).rl_n},
+	0x11: Instruction{name: "RL C", size: 2, cycles: 8, registers: [2]int{C}, operation: (*Cpu).rl_n},
+	0x12: Instruction{name: "RL D", size: 2, cycles: 8, registers: [2]int{D}, operation: (*Cpu).rl_n},
+	0x13: Instruction{name: "RL E", size: 2, cycles: 8, registers: [2]int{E}, operation: (*Cpu).rl_n},
+	0x14: Instruction{name: "RL H", size: 2, cycles: 8, registers: [2]int{H}, operation: (*Cpu).rl_n},
+	0x15: Instruction{name: "RL L", size: 2, cycles: 8, registers: [2]int{L}, operation: (*Cpu).rl_n},
+	0x16: Instruction{name: "RL (HL)", size: 2, cycles: 16, registers: [2]int{HL}, operation: (*Cpu).rl_n},
+	0x17: Instruction{name: "RL A", size: 2, cycles: 8, registers: [2]int{A}, operation: (*Cpu).rl_n},
+	0x18: Instruction{name: "RR B", size: 2, cycles: 8, registers: [2]int{B}, operation: (*Cpu).rr_n},
+	0x19: Instruction{name: "RR C", size: 2, cycles: 8, registers: [2]int{C}, operation: (*Cpu).rr_n},
+	0x1a: Instruction{name: "RR D", size: 2, cycles: 8, registers: [2]int{D}, operation: (*Cpu).rr_n},
+	0x1b: Instruction{name: "RR E", size: 2, cycles: 8, registers: [2]int{E}, operation: (*Cpu).rr_n},
+	0x1c: Instruction{name: "RR H", size: 2, cycles: 8, registers: [2]int{H}, operation: (*Cpu).rr_n},
+	0x1d: Instruction{name: "RR L", size: 2, cycles: 8, registers: [2]int{L}, operation: (*Cpu).rr_n},
+	0x1e: Instruction{name: "RR (HL)", size: 2, cycles: 16, registers: [2]int{HL}, operation: (*Cpu).rr_n},
+	0x1f: Instruction{name: "RR A", size: 2, cycles: 8, registers: [2]int{A}, operation: (*Cpu).rr_n},
+	0x20: Instruction{name: "SLA B", size: 2, cycles: 8, registers: [2]int{B}, operation: (*Cpu).sla_n},
+	0x21: Instruction{name: "SLA C", size: 2, cycles: 8, registers: [2]int{C}, operation: (*Cpu).sla_n},
+	0x22: Instruction{name: "SLA D", size: 2, cycles: 8, registers: [2]int{D}, operation: (*Cpu).sla_n},
+	0x23: Instruction{name: "SLA E", size: 2, cycles: 8, registers: [2]int{E}, operation: (*Cpu).sla_n},
+	0x24: Instruction{name: "SLA H", size: 2, cycles: 8, registers: [2]int{H}, operation: (*Cpu).sla_n},
+	0x25: Instruction{name: "SLA L", size: 2, cycles: 8, registers: [2]int{L}, operation: (*Cpu).sla_n},
+	0x26: Instruction{name: "SLA (HL)", size: 2, cycles: 16, registers: [2]int{HL}, operation: (*Cpu).sla_n},
+	0x27: Instruction{name: "SLA A", size: 2, cycles: 8, registers: [2]int{A}, operation: (*Cpu).sla_n},
+	0x28: Instruction{name: "SRA B", size: 2, cycles: 8, registers: [2]int{B}, operation: (*Cpu).sra_n},
+	0x29: Instruction{name: "SRA C", size: 2, cycles: 8, registers: [2]int{C}, operation: (*Cpu).sra_n},
+	0x2a: Instruction{name: "SRA D", size: 2, cycles: 8, registers: [2]int{D}, operation: (*Cpu).sra_n},
+	0x2b: Instruction{name: "SRA E", size: 2, cycles: 8, registers: [2]int{E}, operation: (*Cpu).sra_n},
+	0x2c: Instruction{name: "SRA H", size: 2, cycles: 8, registers: [2]int{H}, operation: (*Cpu).sra_n},
+	0x2d: Instruction{name: "SRA L", size: 2, cycles: 8, registers: [2]int{L}, operation: (*Cpu).sra_n},
+	0x2e: Instruction{name: "SRA (HL)", size: 2, cycles: 16, registers: [2]int{HL}, operation: (*Cpu).sra_n},
+	0x2f: Instruction{name: "SRA A", size: 2, cycles: 8, registers: [2]int{A}, operation: (*Cpu).sra_n},
+	0x30: Instruction{name: "SWAP B", size: 2, cycles: 8, registers: [2]int{B}, operation: (*Cpu).swap_n},
+	0x31: Instruction{name: "SWAP C", size: 2, cycles: 8, registers: [2]int{C}, operation: (*Cpu).swap_n},
+	0x32: Instruction{name: "SWAP D", size: 2, cycles: 8, registers: [2]int{D}, operation: (*Cpu).swap_n},
+	0x33: Instruction{name: "SWAP E", size: 2, cycles: 8, registers: [2]int{E}, operation: (*Cpu).swap_n},
+	0x34: Instruction{name: "SWAP H", size: 2, cycles: 8, registers: [2]int{H}, operation: (*Cpu).swap_n},
+	0x35: Instruction{name: "SWAP L", size: 2, cycles: 8, registers: [2]int{L}, operation: (*Cpu).swap_n},
+	0x36: Instruction{name: "SWAP (HL)", size: 2, cycles: 16, registers: [2]int{HL}, operation: (*Cpu).swap_n},
+	0x37: Instruction{name: "SWAP A", size: 2, cycles: 8, registers: [2]int{A}, operation: (*Cpu).swap_n},
+	0x38: Instruction{name: "SRL B", size: 2, cycles: 8, registers: [2]int{B}, operation: (*Cpu).srl_n},
+	0x39: Instruction{name: "SRL C", size: 2, cycles: 8, registers: [2]int{C}, operation: (*Cpu).srl_n},
+	0x3a: Instruction{name: "SRL D", size: 2, cycles: 8, registers: [2]int{D}, operation: (*Cpu).srl_n},
+	0x3b: Instruction{name: "SRL E", size: 2, cycles: 8, registers: [2]int{E}, operation: (*Cpu).srl_n},
+	0x3c: Instruction{name: "SRL H", size: 2, cycles: 8, registers: [2]int{H}, operation: (*Cpu).srl_n},
+	0x3d: Instruction{name: "SRL L", size: 2, cycles: 8, registers: [2]int{L}, operation: (*Cpu).srl_n},
+	0x3e: Instruction{name: "SRL (HL)", size: 2, cycles: 16, registers: [2]int{HL}, operation: (*Cpu).srl_n},
+	0x3f: Instruction{name: "SRL A", size: 2, cycles: 8, registers: [2]int{A}, operation: (*Cpu).srl_n},
+	0x40: Instruction{name: "BIT 0,B", size: 2, cycles: 8, registers: [2]int{B}, operation: (*Cpu).bit_b_r},
+	0x41: Instruction{name: "BIT 0,C", size: 2, cycles: 8, registers: [2]int{C}, operation: (*Cpu).bit_b_r},
+	0x42: Instruction{name: "BIT 0,D", size: 2, cycles: 8, registers: [2]int{D}, operation: (*Cpu).bit_b_r},
+	0x43: Instruction{name: "BIT 0,E", size: 2, cycles: 8, registers: [2]int{E}, operation: (*Cpu).bit_b_r},
+	0x44: Instruction{name: "BIT 0,H", size: 2, cycles: 8, registers: [2]int{H}, operation: (*Cpu).bit_b_r},
+	0x45: Instruction{name: "BIT 0,L", size: 2, cycles: 8, registers: [2]int{L}, operation: (*Cpu).bit_b_r},
+	0x46: Instruction{name: "BIT 0,(HL)", size: 2, cycles: 12, registers: [2]int{HL}, operation: (*Cpu).bit_b_r},
+	0x47: Instruction{name: "BIT 0,A", size: 2, cycles: 8, registers: [2]int{A}, operation: (*Cpu).bit_b_r},
+	0x48: Instruction{name: "BIT 1,B", size: 2, cycles: 8, registers: [2]int{B}, operation: (*Cpu).bit_b_r},
+	0x49: Instruction{name: "BIT 1,C", size: 2, cycles: 8, registers: [2]int{C}, operation: (*Cpu).bit_b_r},
+	0x4a: Instruction{name: "BIT 1,D", size: 2, cycles: 8, registers: [2]int{D}, operation: (*Cpu).bit_b_r},
+	0x4b: Instruction{name: "BIT 1,E", size: 2, cycles: 8, registers: [2]int{E}, operation: (*Cpu).bit_b_r},
+	0x4c: Instruction{name: "BIT 1,H", size: 2, cycles: 8, registers: [2]int{H}, operation: (*Cpu).bit_b_r},
+	0x4d: Instruction{name: "BIT 1,L", size: 2, cycles: 8, registers: [2]int{L}, operation: (*Cpu).bit_b_r},
+	0x4e: Instruction{name: "BIT 1,(HL)", size: 2, cycles: 12, registers: [2]int{HL}, operation: (*Cpu).bit_b_r},
+	0x4f: Instruction{name: "BIT 1,A", size: 2, cycles: 8, registers: [2]int{A}, operation: (*Cpu).bit_b_r},
+	0x50: Instruction{name: "BIT 2,B", size: 2, cycles: 8, registers: [2]int{B}, operation: (*Cpu).bit_b_r},
+	0x51: Instruction{name: "BIT 2,C", size: 2, cycles: 8, registers: [2]int{C}, operation: (*Cpu).bit_b_r},
+	0x52: Instruction{name: "BIT 2,D", size: 2, cycles: 8, registers: [2]int{D}, operation: (*Cpu).bit_b_r},
+	0x53: Instruction{name: "BIT 2,E", size: 2, cycles: 8, registers: [2]int{E}, operation: (*Cpu).bit_b_r},
+	0x54: Instruction{name: "BIT 2,H", size: 2, cycles: 8, registers: [2]int{H}, operation: (*Cpu).bit_b_r},
+	0x55: Instruction{name: "BIT 2,L", size: 2, cycles: 8, registers: [2]int{L}, operation: (*Cpu).bit_b_r},
+	0x56: Instruction{name: "BIT 2,(HL)", size: 2, cycles: 12, registers: [2]int{HL}, operation: (*Cpu).bit_b_r},
+	0x57: Instruction{name: "BIT 2,A", size: 2, cycles: 8, registers: [2]int{A}, operation: (*Cpu).bit_b_r},
+	0x58: Instruction{name: "BIT 3,B", size: 2, cycles: 8, registers: [2]int{B}, operation: (*Cpu).bit_b_r},
+	0x59: Instruction{name: "BIT 3,C", size: 2, cycles: 8, registers: [2]int{C}, operation: (*Cpu).bit_b_r},
+	0x5a: Instruction{name: "BIT 3,D", size: 2, cycles: 8, registers: [2]int{D}, operation: (*Cpu).bit_b_r},
+	0x5b: Instruction{name: "BIT 3,E", size: 2, cycles: 8, registers: [2]int{E}, operation: (*Cpu).bit_b_r},
+	0x5c: Instruction{name: "BIT 3,H", size: 2, cycles: 8, registers: [2]int{H}, operation: (*Cpu).bit_b_r},
+	0x5d: Instruction{name: "BIT 3,L", size: 2, cycles: 8, registers: [2]int{L}, operation: (*Cpu).bit_b_r},
+	0x5e: Instruction{name: "BIT 3,(HL)", size: 2, cycles: 12, registers: [2]int{HL}, operation: (*Cpu).bit_b_r},
+	0x5f: Instruction{name: "BIT 3,A", size: 2, cycles: 8, registers: [2]int{A}, operation: (*Cpu).bit_b_r},
+	0x60: Instruction{name: "BIT 4,B", size: 2, cycles: 8, registers: [2]int{B}, operation: (*Cpu).bit_b_r},
+	0x61: Instruction{name: "BIT 4,C", size: 2, cycles: 8, registers: [2]int{C}, operation: (*Cpu).bit_b_r},
+	0x62: Instruction{name: "BIT 4,D", size: 2, cycles: 8, registers: [2]int{D}, operation: (*Cpu).bit_b_r},
+	0x63: Instruction{name: "BIT 4,E", size: 2, cycles: 8, registers: [2]int{E}, operation: (*Cpu).bit_b_r},
+	0x64: Instruction{name: "BIT 4,H", size: 2, cycles: 8, registers: [2]int{H}, operation: (*Cpu).bit_b_r},
+	0x65: Instruction{name: "BIT 4,L", size: 2, cycles: 8, registers: [2]int{L}, operation: (*Cpu).bit_b_r},
+	0x66: Instruction{name: "BIT 4,(HL)", size: 2, cycles: 12, registers: [2]int{HL}, operation: (*Cpu).bit_b_r},
+	0x67: Instruction{name: "BIT 4,A", size: 2, cycles: 8, registers: [2]int{A}, operation: (*Cpu).bit_b_r},
+	0x68: Instruction{name: "BIT 5,B", size: 2, cycles: 8, registers: [2]int{B}, operation: (*Cpu).bit_b_r},
+	0x69: Instruction{name: "BIT 5,C", size: 2, cycles: 8, registers: [2]int{C}, operation: (*Cpu).bit_b_r},
+	0x6a: Instruction{name: "BIT 5,D", size: 2, cycles: 8, registers: [2]int{D}, operation: (*Cpu).bit_b_r},
+	0x6b: Instruction{name: "BIT 5,E", size: 2, cycles: 8, registers: [2]int{E}, operation: (*Cpu).bit_b_r},
+	0x6c: Instruction{name: "BIT 5,H", size: 2, cycles: 8, registers: [2]int{H}, operation: (*Cpu).bit_b_r},
+	0x6d: Instruction{name: "BIT 5,L", size: 2, cycles: 8, registers: [2]int{L}, operation: (*Cpu).bit_b_r},
+	0x6e: Instruction{name: "BIT 5,(HL)", size: 2, cycles: 12, registers: [2]int{HL}, operation: (*Cpu).bit_b_r},
+	0x6f: Instruction{name: "BIT 5,A", size: 2, cycles: 8, registers: [2]int{A}, operation: (*Cpu).bit_b_r},
+	0x70: Instruction{name: "BIT 6,B", size: 2, cycles: 8, registers: [2]int{B}, operation: (*Cpu).bit_b_r},
+	0x71: Instruction{name: "BIT 6,C", size: 2, cycles: 8, registers: [2]int{C}, operation: (*Cpu).bit_b_r},
+	0x72: Instruction{name: "BIT 6,D", size: 2, cycles: 8, registers: [2]int{D}, operation: (*Cpu).bit_b_r},
+	0x73: Instruction{name: "BIT 6,E", size: 2, cycles: 8, registers: [2]int{E}, operation: (*Cpu).bit_b_r},
+	0x74: Instruction{name: "BIT 6,H", size: 2, cycles: 8, registers: [2]int{H}, operation: (*Cpu).bit_b_r},
+	0x75: Instruction{name: "BIT 6,L", size: 2, cycles: 8, registers: [2]int{L}, operation: (*Cpu).bit_b_r},
+	0x76: Instruction{name: "BIT 6,(HL)", size: 2, cycles: 12, registers: [2]int{HL}, operation: (*Cpu).bit_b_r},
+	0x77: Instruction{name: "BIT 6,A", size: 2, cycles: 8, registers: [2]int{A}, operation: (*Cpu).bit_b_r},
+	0x78: Instruction{name: "BIT 7,B", size: 2, cycles: 8, registers: [2]int{B}, operation: (*Cpu).bit_b_r},
+	0x79: Instruction{name: "BIT 7,C", size: 2, cycles: 8, registers: [2]int{C}, operation: (*Cpu).bit_b_r},
+	0x7a: Instruction{name: "BIT 7,D", size: 2, cycles: 8, registers: [2]int{D}, operation: (*Cpu).bit_b_r},
+	0x7b: Instruction{name: "BIT 7,E", size: 2, cycles: 8, registers: [2]int{E}, operation: (*Cpu).bit_b_r},
+	0x7c: Instruction{name: "BIT 7,H", size: 2, cycles: 8, registers: [2]int{H}, operation: (*Cpu).bit_b_r},
+	0x7d: Instruction{name: "BIT 7,L", size: 2, cycles: 8, registers: [2]int{L}, operation: (*Cpu).bit_b_r},
+	0x7e: Instruction{name: "BIT 7,(HL)", size: 2, cycles: 12, registers: [2]int{HL}, operation: (*Cpu).bit_b_r},
+	0x7f: Instruction{name: "BIT 7,A", size: 2, cycles: 8, registers: [2]int{A}, operation: (*Cpu).bit_b_r},
+	0x80: Instruction{name: "RES 0,B", size: 2, cycles: 8, registers: [2]int{B}, operation: (*Cpu).res_b_r},
+	0x81: Instruction{name: "RES 0,C", size: 2, cycles: 8, registers: [2]int{C}, operation: (*Cpu).res_b_r},
+	0x82: Instruction{name: "RES 0,D", size: 2, cycles: 8, registers: [2]int{D}, operation: (*Cpu).res_b_r},
+	0x83: Instruction{name: "RES 0,E", size: 2, cycles: 8, registers: [2]int{E}, operation: (*Cpu).res_b_r},
+	0x84: Instruction{name: "RES 0,H", size: 2, cycles: 8, registers: [2]int{H}, operation: (*Cpu).res_b_r},
+	0x85: Instruction{name: "RES 0,L", size: 2, cycles: 8, registers: [2]int{L}, operation: (*Cpu).res_b_r},
+	0x86: Instruction{name: "RES 0,(HL)", size: 2, cycles: 16, registers: [2]int{HL}, operation: (*Cpu).res_b_r},
+	0x87: Instruction{name: "RES 0,A", size: 2, cycles: 8, registers: [2]int{A}, operation: (*Cpu).res_b_r},
+	0x88: Instruction{name: "RES 1,B", size: 2, cycles: 8, registers: [2]int{B}, operation: (*Cpu).res_b_r},
+	0x89: Instruction{name: "RES 1,C", size: 2, cycles: 8, registers: [2]int{C}, operation: (*Cpu).res_b_r},
+	0x8a: Instruction{name: "RES 1,D", size: 2, cycles: 8, registers: [2]int{D}, operation: (*Cpu).res_b_r},
+	0x8b: Instruction{name: "RES 1,E", size: 2, cycles: 8, registers: [2]int{E}, operation: (*Cpu).res_b_r},
+	0x8c: Instruction{name: "RES 1,H", size: 2, cycles: 8, registers: [2]int{H}, operation: (*Cpu).res_b_r},
+	0x8d: Instruction{name: "RES 1,L", size: 2, cycles: 8, registers: [2]int{L}, operation: (*Cpu).res_b_r},
+	0x8e: Instruction{name: "RES 1,(HL)", size: 2, cycles: 16, registers: [2]int{HL}, operation: (*Cpu).res_b_r},
+	0x8f: Instruction{name: "RES 1,A", size: 2, cycles: 8, registers: [2]int{A}, operation: (*Cpu).res_b_r},
+	0x90: Instruction{name: "RES 2,B", size: 2, cycles: 8, registers: [2]int{B}, operation: (*Cpu).res_b_r},
+	0x91: Instruction{name: "RES 2,C", size: 2, cycles: 8, registers: [2]int{C}, operation: (*Cpu).res_b_r},
+	0x92: Instruction{name: "RES 2,D", size: 2, cycles: 8, registers: [2]int{D}, operation: (*Cpu).res_b_r},
+	0x93: Instruction{name: "RES 2,E", size: 2, cycles: 8, registers: [2]int{E}, operation: (*Cpu).res_b_r},
+	0x94: Instruction{name: "RES 2,H", size: 2, cycles: 8, registers: [2]int{H}, operation: (*Cpu).res_b_r},
+	0x95: Instruction{name: "RES 2,L", size: 2, cycles: 8, registers: [2]int{L}, operation: (*Cpu).res_b_r},
+	0x96: Instruction{name: "RES 2,(HL)", size: 2, cycles: 16, registers: [2]int{HL}, operation: (*Cpu).res_b_r},
+	0x97: Instruction{name: "RES 2,A", size: 2, cycles: 8, registers: [2]int{A}, operation: (*Cpu).res_b_r},
+	0x98: Instruction{name: "RES 3,B", size: 2, cycles: 8, registers: [2]int{B}, operation: (*Cpu).res_b_r},
+	0x99: Instruction{name: "RES 3,C", size: 2, cycles: 8, registers: [2]int{C}, operation: (*Cpu).res_b_r},
+	0x9a: Instruction{name: "RES 3,D", size: 2, cycles: 8, registers: [2]int{D}, operation: (*Cpu).res_b_r},
+	0x9b: Instruction{name: "RES 3,E", size: 2, cycles: 8, registers: [2]int{E}, operation: (*Cpu).res_b_r},
+	0x9c: Instruction{name: "RES 3,H", size: 2, cycles: 8, registers: [2]int{H}, operation: (*Cpu).res_b_r},
+	0x9d: Instruction{name: "RES 3,L", size: 2, cycles: 8, registers: [2]int{L}, operation: (*Cpu).res_b_r},
+	0x9e: Instruction{name: "RES 3,(HL)", size: 2, cycles: 16, registers: [2]int{HL}, operation: (*Cpu).res_b_r},
+	0x9f: Instruction{name: "RES 3,A", size: 2, cycles: 8, registers: [2]int{A}, operation: (*Cpu).res_b_r},
+	0xa0: Instruction{name: "RES 4,B", size: 2, cycles: 8, registers: [2]int{B}, operation: (*Cpu).res_b_r},
+	0xa1: Instruction{name: "RES 4,C", size: 2, cycles: 8, registers: [2]int{C}, operation: (*Cpu).res_b_r},
+	0xa2: Instruction{name: "RES 4,D", size: 2, cycles: 8, registers: [2]int{D}, operation: (*Cpu).res_b_r},
+	0xa3: Instruction{name: "RES 4,E", size: 2, cycles: 8, registers: [2]int{E}, operation: (*Cpu).res_b_r},
+	0xa4: Instruction{name: "RES 4,H", size: 2, cycles: 8, registers: [2]int{H}, operation: (*Cpu).res_b_r},
+	0xa5: Instruction{name: "RES 4,L", size: 2, cycles: 8, registers: [2]int{L}, operation: (*Cpu).res_b_r},
+	0xa6: Instruction{name: "RES 4,(HL)", size: 2, cycles: 16, registers: [2]int{HL}, operation: (*Cpu).res_b_r},
+	0xa7: Instruction{name: "RES 4,A", size: 2, cycles: 8, registers: [2]int{A}, operation: (*Cpu).res_b_r},
+	0xa8: Instruction{name: "RES 5,B", size: 2, cycles: 8, registers: [2]int{B}, operation: (*Cpu).res_b_r},
+	0xa9: Instruction{name: "RES 5,C", size: 2, cycles: 8, registers: [2]int{C}, operation: (*Cpu).res_b_r},
+	0xaa: Instruction{name: "RES 5,D", size: 2, cycles: 8, registers: [2]int{D}, operation: (*Cpu).res_b_r},
+	0xab: Instruction{name: "RES 5,E", size: 2, cycles: 8, registers: [2]int{E}, operation: (*Cpu).res_b_r},
+	0xac: Instruction{name: "RES 5,H", size: 2, cycles: 8, registers: [2]int{H}, operation: (*Cpu).res_b_r},
+	0xad: Instruction{name: "RES 5,L", size: 2, cycles: 8, registers: [2]int{L}, operation: (*Cpu).res_b_r},
+	0xae: Instruction{name: "RES 5,(HL)", size: 2, cycles: 16, registers: [2]int{HL}, operation: (*Cpu).res_b_r},
+	0xaf: Instruction{name: "RES 5,A", size: 2, cycles: 8, registers: [2]int{A}, operation: (*Cpu).res_b_r},
+	0xb0: Instruction{name: "RES 6,B", size: 2, cycles: 8, registers: [2]int{B}, operation: (*Cpu).res_b_r},
+	0xb1: Instruction{name: "RES 6,C", size: 2, cycles: 8, registers: [2]int{C}, operation: (*Cpu).res_b_r},
+	0xb2: Instruction{name: "RES 6,D", size: 2, cycles: 8, registers: [2]int{D}, operation: (*Cpu).res_b_r},
+	0xb3: Instruction{name: "RES 6,E", size: 2, cycles: 8, registers: [2]int{E}, operation: (*Cpu).res_b_r},
+	0xb4: Instruction{name: "RES 6,H", size: 2, cycles: 8, registers: [2]int{H}, operation: (*Cpu).res_b_r},
+	0xb5: Instruction{name: "RES 6,L", size: 2, cycles: 8, registers: [2]int{L}, operation: (*Cpu).res_b_r},
+	0xb6: Instruction{name: "RES 6,(HL)", size: 2, cycles: 16, registers: [2]int{HL}, operation: (*Cpu).res_b_r},
+	0xb7: Instruction{name: "RES 6,A", size: 2, cycles: 8, registers: [2]int{A}, operation: (*Cpu).res_b_r},
+	0xb8: Instruction{name: "RES 7,B", size: 2, cycles: 8, registers: [2]int{B}, operation: (*Cpu).res_b_r},
+	0xb9: Instruction{name: "RES 7,C", size: 2, cycles: 8, registers: [2]int{C}, operation: (*Cpu).res_b_r},
+	0xba: Instruction{name: "RES 7,D", size: 2, cycles: 8, registers: [2]int{D}, operation: (*Cpu).res_b_r},
+	0xbb: Instruction{name: "RES 7,E", size: 2, cycles: 8, registers: [2]int{E}, operation: (*Cpu).res_b_r},
+	0xbc: Instruction{name: "RES 7,H", size: 2, cycles: 8, registers: [2]int{H}, operation: (*Cpu).res_b_r},
+	0xbd: Instruction{name: "RES 7,L", size: 2, cycles: 8, registers: [2]int{L}, operation: (*Cpu).res_b_r},
+	0xbe: Instruction{name: "RES 7,(HL)", size: 2, cycles: 16, registers: [2]int{HL}, operation: (*Cpu).res_b_r},
+	0xbf: Instruction{name: "RES 7,A", size: 2, cycles: 8, registers: [2]int{A}, operation: (*Cpu).res_b_r},
+	0xc0: Instruction{name: "SET 0,B", size: 2, cycles: 8, registers: [2]int{B}, operation: (*Cpu).set_b_r},
+	0xc1: Instruction{name: "SET 0,C", size: 2, cycles: 8, registers: [2]int{C}, operation: (*Cpu).set_b_r},
+	0xc2: Instruction{name: "SET 0,D", size: 2, cycles: 8, registers: [2]int{D}, operation: (*Cpu).set_b_r},
+	0xc3: Instruction{name: "SET 0,E", size: 2, cycles: 8, registers: [2]int{E}, operation: (*Cpu).set_b_r},
+	0xc4: Instruction{name: "SET 0,H", size: 2, cycles: 8, registers: [2]int{H}, operation: (*Cpu).set_b_r},
+	0xc5: Instruction{name: "SET 0,L", size: 2, cycles: 8, registers: [2]int{L}, operation: (*Cpu).set_b_r},
+	0xc6: Instruction{name: "SET 0,(HL)", size: 2, cycles: 16, registers: [2]int{HL}, operation: (*Cpu).set_b_r},
+	0xc7: Instruction{name: "SET 0,A", size: 2, cycles: 8, registers: [2]int{A}, operation: (*Cpu).set_b_r},
+	0xc8: Instruction{name: "SET 1,B", size: 2, cycles: 8, registers: [2]int{B}, operation: (*Cpu).set_b_r},
+	0xc9: Instruction{name: "SET 1,C", size: 2, cycles: 8, registers: [2]int{C}, operation: (*Cpu).set_b_r},
+	0xca: Instruction{name: "SET 1,D", size: 2, cycles: 8, registers: [2]int{D}, operation: (*Cpu).set_b_r},
+	0xcb: Instruction{name: "SET 1,E", size: 2, cycles: 8, registers: [2]int{E}, operation: (*Cpu).set_b_r},
+	0xcc: Instruction{name: "SET 1,H", size: 2, cycles: 8, registers: [2]int{H}, operation: (*Cpu).set_b_r},
+	0xcd: Instruction{name: "SET 1,L", size: 2, cycles: 8, registers: [2]int{L}, operation: (*Cpu).set_b_r},
+	0xce: Instruction{name: "SET 1,(HL)", size: 2, cycles: 16, registers: [2]int{HL}, operation: (*Cpu).set_b_r},
+	0xcf: Instruction{name: "SET 1,A", size: 2, cycles: 8, registers: [2]int{A}, operation: (*Cpu).set_b_r},
+	0xd0: Instruction{name: "SET 2,B", size: 2, cycles: 8, registers: [2]int{B}, operation: (*Cpu).set_b_r},
+	0xd1: Instruction{name: "SET 2,C", size: 2, cycles: 8, registers: [2]int{C}, operation: (*Cpu).set_b_r},
+	0xd2: Instruction{name: "SET 2,D", size: 2, cycles: 8, registers: [2]int{D}, operation: (*Cpu).set_b_r},
+	0xd3: Instruction{name: "SET 2,E", size: 2, cycles: 8, registers: [2]int{E}, operation: (*Cpu).set_b_r},
+	0xd4: Instruction{name: "SET 2,H", size: 2, cycles: 8, registers: [2]int{H}, operation: (*Cpu).set_b_r},
+	0xd5: Instruction{name: "SET 2,L", size: 2, cycles: 8, registers: [2]int{L}, operation: (*Cpu).set_b_r},
+	0xd6: Instruction{name: "SET 2,(HL)", size: 2, cycles: 16, registers: [2]int{HL}, operation: (*Cpu).set_b_r},
+	0xd7: Instruction{name: "SET 2,A", size: 2, cycles: 8, registers: [2]int{A}, operation: (*Cpu).set_b_r},
+	0xd8: Instruction{name: "SET 3,B", size: 2, cycles: 8, registers: [2]int{B}, operation: (*Cpu).set_b_r},
+	0xd9: Instruction{name: "SET 3,C", size: 2, cycles: 8, registers: [2]int{C}, operation: (*Cpu).set_b_r},
+	0xda: Instruction{name: "SET 3,D", size: 2, cycles: 8, registers: [2]int{D}, operation: (*Cpu).set_b_r},
+	0xdb: Instruction{name: "SET 3,E", size: 2, cycles: 8, registers: [2]int{E}, operation: (*Cpu).set_b_r},
+	0xdc: Instruction{name: "SET 3,H", size: 2, cycles: 8, registers: [2]int{H}, operation: (*Cpu).set_b_r},
+	0xdd: Instruction{name: "SET 3,L", size: 2, cycles: 8, registers: [2]int{L}, operation: (*Cpu).set_b_r},
+	0xde: Instruction{name: "SET 3,(HL)", size: 2, cycles: 16, registers: [2]int{HL}, operation: (*Cpu).set_b_r},
+	0xdf: Instruction{name: "SET 3,A", size: 2, cycles: 8, registers: [2]int{A}, operation: (*Cpu).set_b_r},
+	0xe0: Instruction{name: "SET 4,B", size: 2, cycles: 8, registers: [2]int{B}, operation: (*Cpu).set_b_r},
+	0xe1: Instruction{name: "SET 4,C", size: 2, cycles: 8, registers: [2]int{C}, operation: (*Cpu).set_b_r},
+	0xe2: Instruction{name: "SET 4,D", size: 2, cycles: 8, registers: [2]int{D}, operation: (*Cpu).set_b_r},
+	0xe3: Instruction{name: "SET 4,E", size: 2, cycles: 8, registers: [2]int{E}, operation: (*Cpu).set_b_r},
+	0xe4: Instruction{name: "SET 4,H", size: 2, cycles: 8, registers: [2]int{H}, operation: (*Cpu).set_b_r},
+	0xe5: Instruction{name: "SET 4,L", size: 2, cycles: 8, registers: [2]int{L}, operation: (*Cpu).set_b_r},
+	0xe6: Instruction{name: "SET 4,(HL)", size: 2, cycles: 16, registers: [2]int{HL}, operation: (*Cpu).set_b_r},
+	0xe7: Instruction{name: "SET 4,A", size: 2, cycles: 8, registers: [2]int{A}, operation: (*Cpu).set_b_r},
+	0xe8: Instruction{name: "SET 5,B", size: 2, cycles: 8, registers: [2]int{B}, operation: (*Cpu).set_b_r},
+	0xe9: Instruction{name: "SET 5,C", size: 2, cycles: 8, registers: [2]int{C}, operation: (*Cpu).set_b_r},
+	0xea: Instruction{name: "SET 5,D", size: 2, cycles: 8, registers: [2]int{D}, operation: (*Cpu).set_b_r},
+	0xeb: Instruction{name: "SET 5,E", size: 2, cycles: 8, registers: [2]int{E}, operation: (*Cpu).set_b_r},
+	0xec: Instruction{name: "SET 5,H", size: 2, cycles: 8, registers: [2]int{H}, operation: (*Cpu).set_b_r},
+	0xed: Instruction{name: "SET 5,L", size: 2, cycles: 8, registers: [2]int{L}, operation: (*Cpu).set_b_r},
+	0xee: Instruction{name: "SET 5,(HL)", size: 2, cycles: 16, registers: [2]int{HL}, operation: (*Cpu).set_b_r},
+	0xef: Instruction{name: "SET 5,A", size: 2, cycles: 8, registers: [2]int{A}, operation: (*Cpu).set_b_r},
+	0xf0: Instruction{name: "SET 6,B", size: 2, cycles: 8, registers: [2]int{B}, operation: (*Cpu).set_b_r},
+	0xf1: Instruction{name: "SET 6,C", size: 2, cycles: 8, registers: [2]int{C}, operation: (*Cpu).set_b_r},
+	0xf2: Instruction{name: "SET 6,D", size: 2, cycles: 8, registers: [2]int{D}, operation: (*Cpu).set_b_r},
+	0xf3: Instruction{name: "SET 6,E", size: 2, cycles: 8, registers: [2]int{E}, operation: (*Cpu).set_b_r},
+	0xf4: Instruction{name: "SET 6,H", size: 2, cycles: 8, registers: [2]int{H}, operation: (*Cpu).set_b_r},
+	0xf5: Instruction{name: "SET 6,L", size: 2, cycles: 8, registers: [2]int{L}, operation: (*Cpu).set_b_r},
+	0xf6: Instruction{name: "SET 6,(HL)", size: 2, cycles: 16, registers: [2]int{HL}, operation: (*Cpu).set_b_r},
+	0xf7: Instruction{name: "SET 6,A", size: 2, cycles: 8, registers: [2]int{A}, operation: (*Cpu).set_b_r},
+	0xf8: Instruction{name: "SET 7,B", size: 2, cycles: 8, registers: [2]int{B}, operation: (*Cpu).set_b_r},
+	0xf9: Instruction{name: "SET 7,C", size: 2, cycles: 8, registers: [2]int{C}, operation: (*Cpu).set_b_r},
+	0xfa: Instruction{name: "SET 7,D", size: 2, cycles: 8, registers: [2]int{D}, operation: (*Cpu).set_b_r},
+	0xfb: Instruction{name: "SET 7,E", size: 2, cycles: 8, registers: [2]int{E}, operation: (*Cpu).set_b_r},
+	0xfc: Instruction{name: "SET 7,H", size: 2, cycles: 8, registers: [2]int{H}, operation: (*Cpu).set_b_r},
+	0xfd: Instruction{name: "SET 7,L", size: 2, cycles: 8, registers: [2]int{L}, operation: (*Cpu).set_b_r},
+	0xfe: Instruction{name: "SET 7,(HL)", size: 2, cycles: 16, registers: [2]int{HL}, operation: (*Cpu).set_b_r},
+	0xff: Instruction{name: "SET 7,A", size: 2, cycles: 8, registers: [2]int{A}, operation: (*Cpu).set_b_r},
 }
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"testing"
+)
+
+// Instruction timings in M-cycles, as published with blargg's
+// instr_timing test ROM, with STOP, HALT, the CB prefix and the
+// illegal opcodes, which lock up right after their fetch, filled in.
+var opcodeTimings = [256]int{
+	1, 3, 2, 2, 1, 1, 2, 1, 5, 2, 2, 2, 1, 1, 2, 1,
+	1, 3, 2, 2, 1, 1, 2, 1, 3, 2, 2, 2, 1, 1, 2, 1,
+	2, 3, 2, 2, 1, 1, 2, 1, 2, 2, 2, 2, 1, 1, 2, 1,
+	2, 3, 2, 2, 3, 3, 3, 1, 2, 2, 2, 2, 1, 1, 2, 1,
+	1, 1, 1, 1, 1, 1, 2, 1, 1, 1, 1, 1, 1, 1, 2, 1,
+	1, 1, 1, 1, 1, 1, 2, 1, 1, 1, 1, 1, 1, 1, 2, 1,
+	1, 1, 1, 1, 1, 1, 2, 1, 1, 1, 1, 1, 1, 1, 2, 1,
+	2, 2, 2, 2, 2, 2, 1, 2, 1, 1, 1, 1, 1, 1, 2, 1,
+	1, 1, 1, 1, 1, 1, 2, 1, 1, 1, 1, 1, 1, 1, 2, 1,
+	1, 1, 1, 1, 1, 1, 2, 1, 1, 1, 1, 1, 1, 1, 2, 1,
+	1, 1, 1, 1, 1, 1, 2, 1, 1, 1, 1, 1, 1, 1, 2, 1,
+	1, 1, 1, 1, 1, 1, 2, 1, 1, 1, 1, 1, 1, 1, 2, 1,
+	2, 3, 3, 4, 3, 4, 2, 4, 2, 4, 3, 1, 3, 6, 2, 4,
+	2, 3, 3, 1, 3, 4, 2, 4, 2, 4, 3, 1, 3, 1, 2, 4,
+	3, 3, 2, 1, 1, 4, 2, 4, 4, 1, 4, 1, 1, 1, 2, 4,
+	3, 3, 2, 1, 1, 4, 2, 4, 3, 2, 4, 1, 1, 1, 2, 4,
+}
+
+// Timings of conditional branches when taken, in M-cycles
+var branchTimings = map[int]int{
+	0x20: 3, 0x28: 3, 0x30: 3, 0x38: 3,
+	0xc0: 5, 0xc8: 5, 0xd0: 5, 0xd8: 5,
+	0xc2: 4, 0xca: 4, 0xd2: 4, 0xda: 4,
+	0xc4: 6, 0xcc: 6, 0xd4: 6, 0xdc: 6,
+}
+
+// Timing of a CB-prefixed instruction, prefix included, in M-cycles
+func cbTiming(opcode int) int {
+	switch {
+	case opcode&7 != 6:
+		return 2
+	case opcode>>6 == 1:
+		return 3
+	}
+
+	return 4
+}
+
+func TestInstructionTimings(t *testing.T) {
+	for opcode, instr := range instructionSet {
+		if instr.cycles != opcodeTimings[opcode]*4 {
+			t.Errorf("%#02x %s: expected %d cycles, got %d", opcode, instr.name, opcodeTimings[opcode]*4, instr.cycles)
+		}
+
+		if instr.branchCycles != branchTimings[opcode]*4 {
+			t.Errorf("%#02x %s: expected %d branch cycles, got %d", opcode, instr.name, branchTimings[opcode]*4, instr.branchCycles)
+		}
+	}
+
+	for opcode, instr := range cbInstructionSet {
+		if instr.cycles != cbTiming(opcode)*4 {
+			t.Errorf("CB %#02x %s: expected %d cycles, got %d", opcode, instr.name, cbTiming(opcode)*4, instr.cycles)
+		}
+	}
+}
+
+func TestBranchTimings(t *testing.T) {
+	for opcode, timing := range branchTimings {
+		instr := instructionSet[opcode]
+
+		for _, taken := range []bool{true, false} {
+			cpu := Cpu{m: &Memory{}, pc: 0x100, sp: 0xfffe}
+
+			// Set the flags so the condition evaluates to taken
+			cpu.p.z = flag(instr.registers[0] == COND_Z)
+			cpu.p.c = flag(instr.registers[0] == COND_C)
+			if !taken {
+				cpu.p.z ^= 1
+				cpu.p.c ^= 1
+			}
+
+			expected := opcodeTimings[opcode] * 4
+			if taken {
+				expected = timing * 4
+			}
+
+			cycles := step(&cpu, opcode, [2]int{})
+			if cycles != expected {
+				t.Errorf("%#02x %s taken=%v: expected %d cycles, got %d", opcode, instr.name, taken, expected, cycles)
+			}
+		}
+	}
+}
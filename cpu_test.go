@@ -1,18 +1,19 @@
 package main
 
 import (
+	"fmt"
 	"testing"
 )
 
 type Memory struct {
-	memory [1 << 16]int
+	memory [1 << 16]uint8
 }
 
-func (m *Memory) Read(addr int) int {
+func (m *Memory) Read(addr uint16) uint8 {
 	return m.memory[addr]
 }
 
-func (m *Memory) Write(addr, val int) {
+func (m *Memory) Write(addr uint16, val uint8) {
 	m.memory[addr] = val
 }
 
@@ -40,14 +41,20 @@ func TestDecode(t *testing.T) {
 			expectedInstr:    instructionSet[0x08],
 			expectedOperands: [2]int{2, 4},
 		},
+		{
+			testName:         "Decode CB prefix",
+			opcode:           0xcb,
+			expectedInstr:    cbInstructionSet[0x37],
+			expectedOperands: [2]int{0x37},
+		},
 	} {
 		t.Log(tt.testName)
 
-		cpu := Cpu{m: Memory{}}
+		cpu := Cpu{m: &Memory{}}
 
 		// Populate memory with operands
 		for i := 0; i < tt.expectedInstr.size-1; i++ {
-			cpu.m.Write(i, tt.expectedOperands[i])
+			cpu.poke(uint16(i), uint8(tt.expectedOperands[i]))
 		}
 
 		// Populate expected instruction with operands
@@ -60,14 +67,14 @@ func TestDecode(t *testing.T) {
 			t.Errorf("Expected %+v, got %+v\n", tt.expectedInstr, instr)
 		}
 
-		if cpu.pc != tt.expectedInstr.size-1 {
+		if int(cpu.pc) != tt.expectedInstr.size-1 {
 			t.Errorf("Expected %+v, got %+v\n", tt.expectedInstr.size-1, cpu.pc)
 		}
 	}
 }
 
 func TestNop(t *testing.T) {
-	cpu := Cpu{m: Memory{}}
+	cpu := Cpu{m: &Memory{}}
 
 	cpu.decode(0x00)
 
@@ -77,11 +84,1351 @@ func TestNop(t *testing.T) {
 	operation(&cpu)
 }
 
+// Register state used to set up and check instruction tests
+type regs struct {
+	pc, sp              uint16
+	a, b, c, d, e, h, l uint8
+	p                   FlagReg
+}
+
+func (cpu *Cpu) setRegs(r regs) {
+	cpu.pc, cpu.sp, cpu.p = r.pc, r.sp, r.p
+	cpu.a, cpu.b, cpu.c, cpu.d, cpu.e, cpu.h, cpu.l = r.a, r.b, r.c, r.d, r.e, r.h, r.l
+}
+
+func (cpu *Cpu) regs() regs {
+	return regs{
+		pc: cpu.pc, sp: cpu.sp, p: cpu.p,
+		a: cpu.a, b: cpu.b, c: cpu.c, d: cpu.d, e: cpu.e, h: cpu.h, l: cpu.l,
+	}
+}
+
+// Places the opcode and its operands at PC and executes it, returning
+// the cycles spent
+func step(cpu *Cpu, opcode int, operands [2]int) int {
+	cpu.poke(cpu.pc, uint8(opcode))
+	cpu.poke(cpu.pc+1, uint8(operands[0]))
+	cpu.poke(cpu.pc+2, uint8(operands[1]))
+
+	return cpu.tick()
+}
+
+func TestInstructions(t *testing.T) {
+	for _, tt := range []struct {
+		testName    string
+		opcode      int
+		operands    [2]int
+		mem         map[uint16]uint8
+		before      regs
+		after       regs
+		expectedMem map[uint16]uint8
+	}{
+		// 8-bit loads
+		{
+			testName: "LD B,C",
+			opcode:   0x41,
+			before:   regs{c: 0x12},
+			after:    regs{pc: 1, b: 0x12, c: 0x12},
+		},
+		{
+			testName: "LD B,d8",
+			opcode:   0x06,
+			operands: [2]int{0x42},
+			after:    regs{pc: 2, b: 0x42},
+		},
+		{
+			testName:    "LD (HL),A",
+			opcode:      0x77,
+			before:      regs{a: 0x5a, h: 0xc0},
+			after:       regs{pc: 1, a: 0x5a, h: 0xc0},
+			expectedMem: map[uint16]uint8{0xc000: 0x5a},
+		},
+		{
+			testName: "LD A,(HL)",
+			opcode:   0x7e,
+			mem:      map[uint16]uint8{0xc000: 0x99},
+			before:   regs{h: 0xc0},
+			after:    regs{pc: 1, a: 0x99, h: 0xc0},
+		},
+		{
+			testName:    "LD (HL),d8",
+			opcode:      0x36,
+			operands:    [2]int{0x77},
+			before:      regs{h: 0xc0, l: 0x10},
+			after:       regs{pc: 2, h: 0xc0, l: 0x10},
+			expectedMem: map[uint16]uint8{0xc010: 0x77},
+		},
+		{
+			testName:    "LD (BC),A",
+			opcode:      0x02,
+			before:      regs{a: 0x01, b: 0xc0, c: 0x01},
+			after:       regs{pc: 1, a: 0x01, b: 0xc0, c: 0x01},
+			expectedMem: map[uint16]uint8{0xc001: 0x01},
+		},
+		{
+			testName: "LD A,(DE)",
+			opcode:   0x1a,
+			mem:      map[uint16]uint8{0xc002: 0x21},
+			before:   regs{d: 0xc0, e: 0x02},
+			after:    regs{pc: 1, a: 0x21, d: 0xc0, e: 0x02},
+		},
+		{
+			testName:    "LD (HL+),A wraps L into H",
+			opcode:      0x22,
+			before:      regs{a: 0x03, h: 0xc0, l: 0xff},
+			after:       regs{pc: 1, a: 0x03, h: 0xc1},
+			expectedMem: map[uint16]uint8{0xc0ff: 0x03},
+		},
+		{
+			testName: "LD A,(HL-)",
+			opcode:   0x3a,
+			mem:      map[uint16]uint8{0xc000: 0x07},
+			before:   regs{h: 0xc0},
+			after:    regs{pc: 1, a: 0x07, h: 0xbf, l: 0xff},
+		},
+		{
+			testName:    "LD (a16),A",
+			opcode:      0xea,
+			operands:    [2]int{0x00, 0xc0},
+			before:      regs{a: 0x11},
+			after:       regs{pc: 3, a: 0x11},
+			expectedMem: map[uint16]uint8{0xc000: 0x11},
+		},
+		{
+			testName: "LD A,(a16)",
+			opcode:   0xfa,
+			operands: [2]int{0x34, 0xc1},
+			mem:      map[uint16]uint8{0xc134: 0x56},
+			after:    regs{pc: 3, a: 0x56},
+		},
+		{
+			testName:    "LDH (a8),A",
+			opcode:      0xe0,
+			operands:    [2]int{0x80},
+			before:      regs{a: 0x22},
+			after:       regs{pc: 2, a: 0x22},
+			expectedMem: map[uint16]uint8{0xff80: 0x22},
+		},
+		{
+			testName: "LDH A,(a8)",
+			opcode:   0xf0,
+			operands: [2]int{0x44},
+			mem:      map[uint16]uint8{0xff44: 0x90},
+			after:    regs{pc: 2, a: 0x90},
+		},
+		{
+			testName:    "LD (C),A",
+			opcode:      0xe2,
+			before:      regs{a: 0x33, c: 0x81},
+			after:       regs{pc: 1, a: 0x33, c: 0x81},
+			expectedMem: map[uint16]uint8{0xff81: 0x33},
+		},
+		{
+			testName: "LD A,(C)",
+			opcode:   0xf2,
+			mem:      map[uint16]uint8{0xff81: 0x44},
+			before:   regs{c: 0x81},
+			after:    regs{pc: 1, a: 0x44, c: 0x81},
+		},
+		// 16-bit loads
+		{
+			testName: "LD BC,d16",
+			opcode:   0x01,
+			operands: [2]int{0x34, 0x12},
+			after:    regs{pc: 3, b: 0x12, c: 0x34},
+		},
+		{
+			testName: "LD SP,d16",
+			opcode:   0x31,
+			operands: [2]int{0xfe, 0xff},
+			after:    regs{pc: 3, sp: 0xfffe},
+		},
+		{
+			testName:    "LD (a16),SP",
+			opcode:      0x08,
+			operands:    [2]int{0x00, 0xc0},
+			before:      regs{sp: 0xbeef},
+			after:       regs{pc: 3, sp: 0xbeef},
+			expectedMem: map[uint16]uint8{0xc000: 0xef, 0xc001: 0xbe},
+		},
+		{
+			testName: "LD SP,HL",
+			opcode:   0xf9,
+			before:   regs{h: 0x12, l: 0x34},
+			after:    regs{pc: 1, sp: 0x1234, h: 0x12, l: 0x34},
+		},
+		{
+			testName: "LD HL,SP+r8 with carries",
+			opcode:   0xf8,
+			operands: [2]int{0x01},
+			before:   regs{sp: 0x00ff, p: FlagReg{z: 1, n: 1}},
+			after:    regs{pc: 2, sp: 0x00ff, h: 0x01, p: FlagReg{h: 1, c: 1}},
+		},
+		{
+			testName: "LD HL,SP+r8 negative offset",
+			opcode:   0xf8,
+			operands: [2]int{0xff},
+			after:    regs{pc: 2, h: 0xff, l: 0xff},
+		},
+		{
+			testName: "ADD SP,r8 negative offset",
+			opcode:   0xe8,
+			operands: [2]int{0xfe},
+			before:   regs{sp: 0x1002},
+			after:    regs{pc: 2, sp: 0x1000, p: FlagReg{h: 1, c: 1}},
+		},
+		{
+			testName:    "PUSH BC",
+			opcode:      0xc5,
+			before:      regs{sp: 0xfffe, b: 0x12, c: 0x34},
+			after:       regs{pc: 1, sp: 0xfffc, b: 0x12, c: 0x34},
+			expectedMem: map[uint16]uint8{0xfffd: 0x12, 0xfffc: 0x34},
+		},
+		{
+			testName:    "PUSH AF",
+			opcode:      0xf5,
+			before:      regs{sp: 0xfffe, a: 0x12, p: FlagReg{z: 1, c: 1}},
+			after:       regs{pc: 1, sp: 0xfffc, a: 0x12, p: FlagReg{z: 1, c: 1}},
+			expectedMem: map[uint16]uint8{0xfffd: 0x12, 0xfffc: 0x90},
+		},
+		{
+			testName: "POP AF drops the low nibble",
+			opcode:   0xf1,
+			mem:      map[uint16]uint8{0xfffc: 0xff, 0xfffd: 0x12},
+			before:   regs{sp: 0xfffc},
+			after:    regs{pc: 1, sp: 0xfffe, a: 0x12, p: FlagReg{z: 1, n: 1, h: 1, c: 1}},
+		},
+		{
+			testName: "POP DE",
+			opcode:   0xd1,
+			mem:      map[uint16]uint8{0xfffc: 0x34, 0xfffd: 0x12},
+			before:   regs{sp: 0xfffc},
+			after:    regs{pc: 1, sp: 0xfffe, d: 0x12, e: 0x34},
+		},
+		// 8-bit ALU
+		{
+			testName: "ADD A,B",
+			opcode:   0x80,
+			before:   regs{a: 0x3a, b: 0xc6},
+			after:    regs{pc: 1, b: 0xc6, p: FlagReg{z: 1, h: 1, c: 1}},
+		},
+		{
+			testName: "ADD A,d8 half carry",
+			opcode:   0xc6,
+			operands: [2]int{0x01},
+			before:   regs{a: 0x0f},
+			after:    regs{pc: 2, a: 0x10, p: FlagReg{h: 1}},
+		},
+		{
+			testName: "ADD A,(HL)",
+			opcode:   0x86,
+			mem:      map[uint16]uint8{0xc000: 0x12},
+			before:   regs{a: 0x3c, h: 0xc0},
+			after:    regs{pc: 1, a: 0x4e, h: 0xc0},
+		},
+		{
+			testName: "ADC A,C",
+			opcode:   0x89,
+			before:   regs{a: 0xe1, c: 0x0f, p: FlagReg{c: 1}},
+			after:    regs{pc: 1, a: 0xf1, c: 0x0f, p: FlagReg{h: 1}},
+		},
+		{
+			testName: "ADC A,d8",
+			opcode:   0xce,
+			operands: [2]int{0x3b},
+			before:   regs{a: 0xe1, p: FlagReg{c: 1}},
+			after:    regs{pc: 2, a: 0x1d, p: FlagReg{c: 1}},
+		},
+		{
+			testName: "SUB E",
+			opcode:   0x93,
+			before:   regs{a: 0x3e, e: 0x3e},
+			after:    regs{pc: 1, e: 0x3e, p: FlagReg{z: 1, n: 1}},
+		},
+		{
+			testName: "SUB d8 half borrow",
+			opcode:   0xd6,
+			operands: [2]int{0x0f},
+			before:   regs{a: 0x3e},
+			after:    regs{pc: 2, a: 0x2f, p: FlagReg{n: 1, h: 1}},
+		},
+		{
+			testName: "SUB d8 borrow",
+			opcode:   0xd6,
+			operands: [2]int{0x40},
+			before:   regs{a: 0x3e},
+			after:    regs{pc: 2, a: 0xfe, p: FlagReg{n: 1, c: 1}},
+		},
+		{
+			testName: "SBC A,H",
+			opcode:   0x9c,
+			before:   regs{a: 0x3b, h: 0x2a, p: FlagReg{c: 1}},
+			after:    regs{pc: 1, a: 0x10, h: 0x2a, p: FlagReg{n: 1}},
+		},
+		{
+			testName: "SBC A,d8",
+			opcode:   0xde,
+			operands: [2]int{0x3a},
+			before:   regs{a: 0x3b, p: FlagReg{c: 1}},
+			after:    regs{pc: 2, p: FlagReg{z: 1, n: 1}},
+		},
+		{
+			testName: "SBC A,(HL)",
+			opcode:   0x9e,
+			mem:      map[uint16]uint8{0xc000: 0x4f},
+			before:   regs{a: 0x3b, h: 0xc0, p: FlagReg{c: 1}},
+			after:    regs{pc: 1, a: 0xeb, h: 0xc0, p: FlagReg{n: 1, h: 1, c: 1}},
+		},
+		{
+			testName: "AND L",
+			opcode:   0xa5,
+			before:   regs{a: 0x5a, l: 0x3f},
+			after:    regs{pc: 1, a: 0x1a, l: 0x3f, p: FlagReg{h: 1}},
+		},
+		{
+			testName: "AND d8 zero",
+			opcode:   0xe6,
+			operands: [2]int{0x00},
+			before:   regs{a: 0x5a, p: FlagReg{c: 1}},
+			after:    regs{pc: 2, p: FlagReg{z: 1, h: 1}},
+		},
+		{
+			testName: "OR A zero",
+			opcode:   0xb7,
+			after:    regs{pc: 1, p: FlagReg{z: 1}},
+		},
+		{
+			testName: "OR d8",
+			opcode:   0xf6,
+			operands: [2]int{0x03},
+			before:   regs{a: 0x5a},
+			after:    regs{pc: 2, a: 0x5b},
+		},
+		{
+			testName: "XOR A",
+			opcode:   0xaf,
+			before:   regs{a: 0xff, p: FlagReg{n: 1, h: 1, c: 1}},
+			after:    regs{pc: 1, p: FlagReg{z: 1}},
+		},
+		{
+			testName: "XOR (HL)",
+			opcode:   0xae,
+			mem:      map[uint16]uint8{0xc000: 0x8a},
+			before:   regs{a: 0xff, h: 0xc0},
+			after:    regs{pc: 1, a: 0x75, h: 0xc0},
+		},
+		{
+			testName: "CP B half borrow",
+			opcode:   0xb8,
+			before:   regs{a: 0x3c, b: 0x2f},
+			after:    regs{pc: 1, a: 0x3c, b: 0x2f, p: FlagReg{n: 1, h: 1}},
+		},
+		{
+			testName: "CP d8 equal",
+			opcode:   0xfe,
+			operands: [2]int{0x3c},
+			before:   regs{a: 0x3c},
+			after:    regs{pc: 2, a: 0x3c, p: FlagReg{z: 1, n: 1}},
+		},
+		{
+			testName: "CP (HL) borrow",
+			opcode:   0xbe,
+			mem:      map[uint16]uint8{0xc000: 0x40},
+			before:   regs{a: 0x3c, h: 0xc0},
+			after:    regs{pc: 1, a: 0x3c, h: 0xc0, p: FlagReg{n: 1, c: 1}},
+		},
+		{
+			testName: "INC A keeps carry",
+			opcode:   0x3c,
+			before:   regs{a: 0xff, p: FlagReg{n: 1, c: 1}},
+			after:    regs{pc: 1, p: FlagReg{z: 1, h: 1, c: 1}},
+		},
+		{
+			testName:    "INC (HL)",
+			opcode:      0x34,
+			mem:         map[uint16]uint8{0xc000: 0x50},
+			before:      regs{h: 0xc0},
+			after:       regs{pc: 1, h: 0xc0},
+			expectedMem: map[uint16]uint8{0xc000: 0x51},
+		},
+		{
+			testName: "DEC L",
+			opcode:   0x2d,
+			before:   regs{l: 0x01},
+			after:    regs{pc: 1, p: FlagReg{z: 1, n: 1}},
+		},
+		{
+			testName:    "DEC (HL) wraps",
+			opcode:      0x35,
+			before:      regs{h: 0xc0, p: FlagReg{c: 1}},
+			after:       regs{pc: 1, h: 0xc0, p: FlagReg{n: 1, h: 1, c: 1}},
+			expectedMem: map[uint16]uint8{0xc000: 0xff},
+		},
+		// 16-bit arithmetic
+		{
+			testName: "INC BC wraps",
+			opcode:   0x03,
+			before:   regs{b: 0xff, c: 0xff},
+			after:    regs{pc: 1},
+		},
+		{
+			testName: "DEC SP wraps",
+			opcode:   0x3b,
+			after:    regs{pc: 1, sp: 0xffff},
+		},
+		{
+			testName: "ADD HL,BC keeps zero",
+			opcode:   0x09,
+			before:   regs{b: 0x06, c: 0x05, h: 0x8a, l: 0x23, p: FlagReg{z: 1, n: 1}},
+			after:    regs{pc: 1, b: 0x06, c: 0x05, h: 0x90, l: 0x28, p: FlagReg{z: 1, h: 1}},
+		},
+		{
+			testName: "ADD HL,HL",
+			opcode:   0x29,
+			before:   regs{h: 0x8a, l: 0x23},
+			after:    regs{pc: 1, h: 0x14, l: 0x46, p: FlagReg{h: 1, c: 1}},
+		},
+		{
+			testName: "ADD HL,SP",
+			opcode:   0x39,
+			before:   regs{sp: 0x0001, h: 0xff, l: 0xff},
+			after:    regs{pc: 1, sp: 0x0001, p: FlagReg{h: 1, c: 1}},
+		},
+		// Miscellaneous
+		{
+			testName: "DAA after addition",
+			opcode:   0x27,
+			before:   regs{a: 0x7d},
+			after:    regs{pc: 1, a: 0x83},
+		},
+		{
+			testName: "DAA after subtraction",
+			opcode:   0x27,
+			before:   regs{a: 0x4b, p: FlagReg{n: 1, h: 1}},
+			after:    regs{pc: 1, a: 0x45, p: FlagReg{n: 1}},
+		},
+		{
+			testName: "DAA overflow",
+			opcode:   0x27,
+			before:   regs{a: 0xa0},
+			after:    regs{pc: 1, p: FlagReg{z: 1, c: 1}},
+		},
+		{
+			testName: "CPL",
+			opcode:   0x2f,
+			before:   regs{a: 0x35},
+			after:    regs{pc: 1, a: 0xca, p: FlagReg{n: 1, h: 1}},
+		},
+		{
+			testName: "CCF",
+			opcode:   0x3f,
+			before:   regs{p: FlagReg{z: 1, n: 1, h: 1, c: 1}},
+			after:    regs{pc: 1, p: FlagReg{z: 1}},
+		},
+		{
+			testName: "SCF",
+			opcode:   0x37,
+			before:   regs{p: FlagReg{z: 1, n: 1, h: 1}},
+			after:    regs{pc: 1, p: FlagReg{z: 1, c: 1}},
+		},
+		// Rotates
+		{
+			testName: "RLCA",
+			opcode:   0x07,
+			before:   regs{a: 0x85, p: FlagReg{z: 1}},
+			after:    regs{pc: 1, a: 0x0b, p: FlagReg{c: 1}},
+		},
+		{
+			testName: "RLA",
+			opcode:   0x17,
+			before:   regs{a: 0x95, p: FlagReg{c: 1}},
+			after:    regs{pc: 1, a: 0x2b, p: FlagReg{c: 1}},
+		},
+		{
+			testName: "RRCA",
+			opcode:   0x0f,
+			before:   regs{a: 0x3b},
+			after:    regs{pc: 1, a: 0x9d, p: FlagReg{c: 1}},
+		},
+		{
+			testName: "RRA clears zero",
+			opcode:   0x1f,
+			before:   regs{a: 0x01},
+			after:    regs{pc: 1, p: FlagReg{c: 1}},
+		},
+		// Jumps
+		{
+			testName: "JP a16",
+			opcode:   0xc3,
+			operands: [2]int{0x00, 0x80},
+			after:    regs{pc: 0x8000},
+		},
+		{
+			testName: "JP NZ,a16 taken",
+			opcode:   0xc2,
+			operands: [2]int{0x00, 0x80},
+			after:    regs{pc: 0x8000},
+		},
+		{
+			testName: "JP Z,a16 not taken",
+			opcode:   0xca,
+			operands: [2]int{0x00, 0x80},
+			after:    regs{pc: 3},
+		},
+		{
+			testName: "JP C,a16 taken",
+			opcode:   0xda,
+			operands: [2]int{0x00, 0x80},
+			before:   regs{p: FlagReg{c: 1}},
+			after:    regs{pc: 0x8000, p: FlagReg{c: 1}},
+		},
+		{
+			testName: "JP NC,a16 not taken",
+			opcode:   0xd2,
+			operands: [2]int{0x00, 0x80},
+			before:   regs{p: FlagReg{c: 1}},
+			after:    regs{pc: 3, p: FlagReg{c: 1}},
+		},
+		{
+			testName: "JP (HL)",
+			opcode:   0xe9,
+			before:   regs{h: 0x40},
+			after:    regs{pc: 0x4000, h: 0x40},
+		},
+		{
+			testName: "JR r8",
+			opcode:   0x18,
+			operands: [2]int{0x05},
+			after:    regs{pc: 0x07},
+		},
+		{
+			testName: "JR r8 backwards",
+			opcode:   0x18,
+			operands: [2]int{0xfe},
+			before:   regs{pc: 0x100},
+			after:    regs{pc: 0x100},
+		},
+		{
+			testName: "JR NZ,r8 not taken",
+			opcode:   0x20,
+			operands: [2]int{0x10},
+			before:   regs{p: FlagReg{z: 1}},
+			after:    regs{pc: 2, p: FlagReg{z: 1}},
+		},
+		{
+			testName: "JR Z,r8 taken",
+			opcode:   0x28,
+			operands: [2]int{0x10},
+			before:   regs{p: FlagReg{z: 1}},
+			after:    regs{pc: 0x12, p: FlagReg{z: 1}},
+		},
+		{
+			testName: "JR NC,r8 taken",
+			opcode:   0x30,
+			operands: [2]int{0x10},
+			after:    regs{pc: 0x12},
+		},
+		{
+			testName: "JR C,r8 not taken",
+			opcode:   0x38,
+			operands: [2]int{0x10},
+			after:    regs{pc: 2},
+		},
+		// Calls and returns
+		{
+			testName:    "CALL a16",
+			opcode:      0xcd,
+			operands:    [2]int{0x34, 0x12},
+			before:      regs{pc: 0x100, sp: 0xfffe},
+			after:       regs{pc: 0x1234, sp: 0xfffc},
+			expectedMem: map[uint16]uint8{0xfffd: 0x01, 0xfffc: 0x03},
+		},
+		{
+			testName: "CALL NZ,a16 not taken",
+			opcode:   0xc4,
+			operands: [2]int{0x34, 0x12},
+			before:   regs{pc: 0x100, sp: 0xfffe, p: FlagReg{z: 1}},
+			after:    regs{pc: 0x103, sp: 0xfffe, p: FlagReg{z: 1}},
+		},
+		{
+			testName:    "CALL C,a16 taken",
+			opcode:      0xdc,
+			operands:    [2]int{0x34, 0x12},
+			before:      regs{pc: 0x100, sp: 0xfffe, p: FlagReg{c: 1}},
+			after:       regs{pc: 0x1234, sp: 0xfffc, p: FlagReg{c: 1}},
+			expectedMem: map[uint16]uint8{0xfffd: 0x01, 0xfffc: 0x03},
+		},
+		{
+			testName:    "RST 38H",
+			opcode:      0xff,
+			before:      regs{pc: 0x100, sp: 0xfffe},
+			after:       regs{pc: 0x38, sp: 0xfffc},
+			expectedMem: map[uint16]uint8{0xfffd: 0x01, 0xfffc: 0x01},
+		},
+		{
+			testName: "RET",
+			opcode:   0xc9,
+			mem:      map[uint16]uint8{0xfffc: 0x34, 0xfffd: 0x12},
+			before:   regs{sp: 0xfffc},
+			after:    regs{pc: 0x1234, sp: 0xfffe},
+		},
+		{
+			testName: "RET Z not taken",
+			opcode:   0xc8,
+			mem:      map[uint16]uint8{0xfffc: 0x34, 0xfffd: 0x12},
+			before:   regs{sp: 0xfffc},
+			after:    regs{pc: 1, sp: 0xfffc},
+		},
+		{
+			testName: "RET NC taken",
+			opcode:   0xd0,
+			mem:      map[uint16]uint8{0xfffc: 0x34, 0xfffd: 0x12},
+			before:   regs{sp: 0xfffc},
+			after:    regs{pc: 0x1234, sp: 0xfffe},
+		},
+		// CB-prefixed
+		{
+			testName: "RLC B",
+			opcode:   0xcb,
+			operands: [2]int{0x00},
+			before:   regs{b: 0x85},
+			after:    regs{pc: 2, b: 0x0b, p: FlagReg{c: 1}},
+		},
+		{
+			testName:    "RRC (HL)",
+			opcode:      0xcb,
+			operands:    [2]int{0x0e},
+			before:      regs{h: 0xc0},
+			after:       regs{pc: 2, h: 0xc0, p: FlagReg{z: 1}},
+			expectedMem: map[uint16]uint8{0xc000: 0x00},
+		},
+		{
+			testName: "RL C",
+			opcode:   0xcb,
+			operands: [2]int{0x11},
+			before:   regs{c: 0x80},
+			after:    regs{pc: 2, p: FlagReg{z: 1, c: 1}},
+		},
+		{
+			testName: "RR D",
+			opcode:   0xcb,
+			operands: [2]int{0x1a},
+			before:   regs{d: 0x8a, p: FlagReg{c: 1}},
+			after:    regs{pc: 2, d: 0xc5},
+		},
+		{
+			testName: "SLA E",
+			opcode:   0xcb,
+			operands: [2]int{0x23},
+			before:   regs{e: 0xff},
+			after:    regs{pc: 2, e: 0xfe, p: FlagReg{c: 1}},
+		},
+		{
+			testName: "SRA H keeps bit 7",
+			opcode:   0xcb,
+			operands: [2]int{0x2c},
+			before:   regs{h: 0x8a},
+			after:    regs{pc: 2, h: 0xc5},
+		},
+		{
+			testName: "SWAP A",
+			opcode:   0xcb,
+			operands: [2]int{0x37},
+			before:   regs{a: 0xf1, p: FlagReg{c: 1}},
+			after:    regs{pc: 2, a: 0x1f},
+		},
+		{
+			testName: "SRL L",
+			opcode:   0xcb,
+			operands: [2]int{0x3d},
+			before:   regs{l: 0x01},
+			after:    regs{pc: 2, p: FlagReg{z: 1, c: 1}},
+		},
+		{
+			testName: "BIT 7,H",
+			opcode:   0xcb,
+			operands: [2]int{0x7c},
+			before:   regs{h: 0x7f, p: FlagReg{n: 1, c: 1}},
+			after:    regs{pc: 2, h: 0x7f, p: FlagReg{z: 1, h: 1, c: 1}},
+		},
+		{
+			testName: "BIT 0,(HL)",
+			opcode:   0xcb,
+			operands: [2]int{0x46},
+			mem:      map[uint16]uint8{0xc000: 0x01},
+			before:   regs{h: 0xc0},
+			after:    regs{pc: 2, h: 0xc0, p: FlagReg{h: 1}},
+		},
+		{
+			testName: "RES 3,A",
+			opcode:   0xcb,
+			operands: [2]int{0x9f},
+			before:   regs{a: 0xff},
+			after:    regs{pc: 2, a: 0xf7},
+		},
+		{
+			testName:    "SET 7,(HL)",
+			opcode:      0xcb,
+			operands:    [2]int{0xfe},
+			before:      regs{h: 0xc0},
+			after:       regs{pc: 2, h: 0xc0},
+			expectedMem: map[uint16]uint8{0xc000: 0x80},
+		},
+	} {
+		t.Log(tt.testName)
+
+		cpu := Cpu{m: &Memory{}}
+		cpu.setRegs(tt.before)
+
+		for addr, val := range tt.mem {
+			cpu.poke(addr, val)
+		}
+
+		step(&cpu, tt.opcode, tt.operands)
+
+		if cpu.regs() != tt.after {
+			t.Errorf("%s: expected %+v, got %+v\n", tt.testName, tt.after, cpu.regs())
+		}
+
+		for addr, val := range tt.expectedMem {
+			if cpu.peek(addr) != val {
+				t.Errorf("%s: expected %#02x at %#04x, got %#02x\n", tt.testName, val, addr, cpu.peek(addr))
+			}
+		}
+	}
+}
+
+func TestStateInstructions(t *testing.T) {
+	cpu := Cpu{m: &Memory{}}
+
+	step(&cpu, 0xfb, [2]int{})
+	step(&cpu, 0x00, [2]int{})
+	if !cpu.ime {
+		t.Errorf("EI: expected IME to be set")
+	}
+
+	step(&cpu, 0xf3, [2]int{})
+	if cpu.ime {
+		t.Errorf("DI: expected IME to be cleared")
+	}
+
+	cpu.poke(0xfffc, 0x34)
+	cpu.poke(0xfffd, 0x12)
+	cpu.sp = 0xfffc
+	step(&cpu, 0xd9, [2]int{})
+	if !cpu.ime || cpu.pc != 0x1234 {
+		t.Errorf("RETI: expected IME set and PC 0x1234, got %v and %#04x", cpu.ime, cpu.pc)
+	}
+
+	step(&cpu, 0x76, [2]int{})
+	if !cpu.halted {
+		t.Errorf("HALT: expected CPU to be halted")
+	}
+
+	cpu.halted = false
+	step(&cpu, 0x10, [2]int{})
+	if !cpu.stopped {
+		t.Errorf("STOP: expected CPU to be stopped")
+	}
+}
+
+func TestRegisterPairs(t *testing.T) {
+	for _, tt := range []struct {
+		testName string
+		reg      int
+		val      uint16
+		expected uint16
+	}{
+		{testName: "BC", reg: BC, val: 0x1234, expected: 0x1234},
+		{testName: "DE", reg: DE, val: 0xbeef, expected: 0xbeef},
+		{testName: "HL", reg: HL, val: 0x00ff, expected: 0x00ff},
+		{testName: "SP", reg: SP, val: 0xfffe, expected: 0xfffe},
+		{testName: "AF drops the low nibble of F", reg: AF, val: 0x12ff, expected: 0x12f0},
+	} {
+		t.Log(tt.testName)
+
+		cpu := Cpu{}
+		cpu.setReg16(tt.reg, tt.val)
+
+		if cpu.reg16(tt.reg) != tt.expected {
+			t.Errorf("%s: expected %#04x, got %#04x", tt.testName, tt.expected, cpu.reg16(tt.reg))
+		}
+	}
+
+	cpu := Cpu{}
+	cpu.setBC(0x0102)
+	cpu.setDE(0x0304)
+	cpu.setHL(0x0506)
+	cpu.setAF(0x0780)
+
+	if cpu.regs() != (regs{a: 7, b: 1, c: 2, d: 3, e: 4, h: 5, l: 6, p: FlagReg{z: 1}}) {
+		t.Errorf("Expected pairs to map onto their registers, got %+v", cpu.regs())
+	}
+
+	for r, expected := range []uint8{7, 1, 2, 3, 4, 5, 6} {
+		if *cpu.reg8(r) != expected {
+			t.Errorf("Expected register %d to be %d, got %d", r, expected, *cpu.reg8(r))
+		}
+	}
+}
+
+func TestRegistersWrap(t *testing.T) {
+	cpu := Cpu{m: &Memory{}, pc: 0xffff, sp: 0x0001}
+	cpu.setHL(0xffff)
+
+	// INC HL at the last address, PC wraps to 0
+	cpu.poke(0xffff, 0x23)
+	cpu.tick()
+
+	if cpu.pc != 0 || cpu.hl() != 0 {
+		t.Errorf("Expected PC and HL to wrap to 0, got %#04x and %#04x", cpu.pc, cpu.hl())
+	}
+
+	// PUSH BC wraps SP below 0
+	cpu.poke(0x0000, 0xc5)
+	cpu.tick()
+
+	if cpu.sp != 0xffff {
+		t.Errorf("Expected SP to wrap to 0xffff, got %#04x", cpu.sp)
+	}
+}
+
+func TestHalt(t *testing.T) {
+	for _, tt := range []struct {
+		testName   string
+		ime        bool
+		expectedPc uint16
+		expectedIf uint8
+	}{
+		{
+			testName:   "IME set dispatches the interrupt",
+			ime:        true,
+			expectedPc: 0x50,
+		},
+		{
+			testName:   "IME clear resumes after HALT",
+			expectedPc: 0x102,
+			expectedIf: BIT_2,
+		},
+	} {
+		t.Log(tt.testName)
+
+		cpu := Cpu{m: &Memory{}, pc: 0x100, sp: 0xfffe, ime: tt.ime}
+		cpu.poke(0x100, 0x76)
+		cpu.poke(IE_ADDR, BIT_2)
+
+		cpu.tick()
+
+		// Nothing is fetched while no interrupt is pending
+		for i := 0; i < 10; i++ {
+			if cycles := cpu.tick(); cycles != 4 || !cpu.halted || cpu.pc != 0x101 {
+				t.Fatalf("%s: expected CPU to idle, got %d cycles at %#04x", tt.testName, cycles, cpu.pc)
+			}
+		}
+
+		cpu.RequestInterrupt(INT_TIMER)
+		cpu.tick()
+
+		if cpu.halted || cpu.pc != tt.expectedPc {
+			t.Errorf("%s: expected PC %#04x, got %#04x", tt.testName, tt.expectedPc, cpu.pc)
+		}
+
+		if cpu.peek(IF_ADDR) != tt.expectedIf {
+			t.Errorf("%s: expected IF %#02x, got %#02x", tt.testName, tt.expectedIf, cpu.peek(IF_ADDR))
+		}
+	}
+}
+
+func TestHaltBug(t *testing.T) {
+	cpu := Cpu{m: &Memory{}}
+	cpu.poke(IE_ADDR, BIT_0)
+	cpu.poke(IF_ADDR, BIT_0)
+
+	// HALT, INC A, NOP
+	cpu.poke(0x00, 0x76)
+	cpu.poke(0x01, 0x3c)
+
+	cpu.Run(12)
+
+	if cpu.halted || cpu.a != 2 || cpu.pc != 2 {
+		t.Errorf("Expected INC A to run twice, got A %d and PC %d", cpu.a, cpu.pc)
+	}
+}
+
+func TestHaltBugAfterEi(t *testing.T) {
+	cpu := Cpu{m: &Memory{}, pc: 0x100, sp: 0xfffe}
+	cpu.poke(IE_ADDR, BIT_0)
+	cpu.poke(IF_ADDR, BIT_0)
+
+	// EI, HALT
+	cpu.poke(0x100, 0xfb)
+	cpu.poke(0x101, 0x76)
+
+	cpu.tick()
+	cpu.tick()
+	cpu.tick()
+
+	if cpu.pc != 0x40 || cpu.pop() != 0x101 {
+		t.Errorf("Expected the interrupt handler to return to HALT")
+	}
+}
+
+func TestStop(t *testing.T) {
+	cpu := Cpu{m: &Memory{}}
+
+	step(&cpu, 0x10, [2]int{})
+
+	for i := 0; i < 10; i++ {
+		if cycles := cpu.tick(); cycles != 4 || !cpu.stopped || cpu.pc != 2 {
+			t.Fatalf("Expected CPU to stay stopped, got %d cycles at %#04x", cycles, cpu.pc)
+		}
+	}
+
+	// Other interrupts do not wake the CPU up
+	cpu.poke(IE_ADDR, 0x1f)
+	cpu.RequestInterrupt(INT_VBLANK)
+	cpu.tick()
+
+	if !cpu.stopped {
+		t.Errorf("Expected CPU to stay stopped on VBlank")
+	}
+
+	cpu.RequestInterrupt(INT_JOYPAD)
+	cpu.tick()
+
+	if cpu.stopped || cpu.pc != 3 {
+		t.Errorf("Expected joypad input to resume execution, PC is %#04x", cpu.pc)
+	}
+}
+
+func TestSpeedSwitch(t *testing.T) {
+	for _, tt := range []struct {
+		testName            string
+		cgb, doubleSpeed    bool
+		key1                uint8
+		expectedStopped     bool
+		expectedDoubleSpeed bool
+		expectedKey1        uint8
+		expectedCycles      int
+	}{
+		{
+			testName:            "Switch to double speed",
+			cgb:                 true,
+			key1:                BIT_0,
+			expectedDoubleSpeed: true,
+			expectedKey1:        BIT_7,
+			expectedCycles:      4 + SPEED_SWITCH_CYCLES,
+		},
+		{
+			testName:       "Switch to normal speed",
+			cgb:            true,
+			doubleSpeed:    true,
+			key1:           BIT_7 | BIT_0,
+			expectedCycles: 4 + SPEED_SWITCH_CYCLES,
+		},
+		{
+			testName:        "Not prepared",
+			cgb:             true,
+			expectedStopped: true,
+			expectedCycles:  4,
+		},
+		{
+			testName:        "DMG ignores KEY1",
+			key1:            BIT_0,
+			expectedStopped: true,
+			expectedKey1:    BIT_0,
+			expectedCycles:  4,
+		},
+	} {
+		t.Log(tt.testName)
+
+		cpu := Cpu{m: &Memory{}, cgb: tt.cgb, doubleSpeed: tt.doubleSpeed}
+		cpu.poke(KEY1_ADDR, tt.key1)
+
+		cycles := step(&cpu, 0x10, [2]int{})
+
+		if cpu.stopped != tt.expectedStopped || cpu.doubleSpeed != tt.expectedDoubleSpeed {
+			t.Errorf("%s: expected stopped %v and double speed %v, got %v and %v", tt.testName,
+				tt.expectedStopped, tt.expectedDoubleSpeed, cpu.stopped, cpu.doubleSpeed)
+		}
+
+		if cpu.peek(KEY1_ADDR) != tt.expectedKey1 {
+			t.Errorf("%s: expected KEY1 %#02x, got %#02x", tt.testName, tt.expectedKey1, cpu.peek(KEY1_ADDR))
+		}
+
+		if cycles != tt.expectedCycles {
+			t.Errorf("%s: expected %d cycles, got %d", tt.testName, tt.expectedCycles, cycles)
+		}
+	}
+}
+
+func TestIllegalOpcodes(t *testing.T) {
+	for _, opcode := range []int{0xd3, 0xdb, 0xdd, 0xe3, 0xe4, 0xeb, 0xec, 0xed, 0xf4, 0xfc, 0xfd} {
+		cpu := Cpu{m: &Memory{}, pc: 0x150, ime: true}
+		cpu.poke(IE_ADDR, 0x1f)
+
+		step(&cpu, opcode, [2]int{})
+
+		err, ok := cpu.Err().(*IllegalOpcodeError)
+		if !ok || err.Opcode != opcode || err.Addr != 0x150 {
+			t.Errorf("%#02x: expected an illegal opcode error at $0150, got %v", opcode, cpu.Err())
+			continue
+		}
+
+		expectedMsg := fmt.Sprintf("illegal opcode $%02X at $0150", opcode)
+		if err.Error() != expectedMsg {
+			t.Errorf("%#02x: expected message %q, got %q", opcode, expectedMsg, err.Error())
+		}
+
+		// Not even interrupts get the CPU going again
+		cpu.RequestInterrupt(INT_VBLANK)
+		for i := 0; i < 10; i++ {
+			if cycles := cpu.tick(); cycles != 4 || cpu.pc != 0x151 {
+				t.Fatalf("%#02x: expected CPU to hang, got %d cycles at %#04x", opcode, cycles, cpu.pc)
+			}
+		}
+	}
+}
+
+func TestAllOpcodesDefined(t *testing.T) {
+	for opcode := 0; opcode < 0x100; opcode++ {
+		instr, ok := instructionSet[opcode]
+		if !ok || instr.size == 0 {
+			t.Errorf("%#02x is neither defined nor flagged illegal", opcode)
+		}
+	}
+}
+
+func TestTick(t *testing.T) {
+	for _, tt := range []struct {
+		testName       string
+		opcode         int
+		operands       [2]int
+		p              FlagReg
+		expectedCycles int
+	}{
+		{testName: "NOP", opcode: 0x00, expectedCycles: 4},
+		{testName: "LD BC,d16", opcode: 0x01, expectedCycles: 12},
+		{testName: "JR NZ,r8 taken", opcode: 0x20, expectedCycles: 12},
+		{testName: "JR NZ,r8 not taken", opcode: 0x20, p: FlagReg{z: 1}, expectedCycles: 8},
+		{testName: "JP C,a16 taken", opcode: 0xda, p: FlagReg{c: 1}, expectedCycles: 16},
+		{testName: "JP C,a16 not taken", opcode: 0xda, expectedCycles: 12},
+		{testName: "CALL Z,a16 taken", opcode: 0xcc, p: FlagReg{z: 1}, expectedCycles: 24},
+		{testName: "CALL Z,a16 not taken", opcode: 0xcc, expectedCycles: 12},
+		{testName: "RET NC taken", opcode: 0xd0, expectedCycles: 20},
+		{testName: "RET NC not taken", opcode: 0xd0, p: FlagReg{c: 1}, expectedCycles: 8},
+		{testName: "RLC B", opcode: 0xcb, operands: [2]int{0x00}, expectedCycles: 8},
+		{testName: "BIT 0,(HL)", opcode: 0xcb, operands: [2]int{0x46}, expectedCycles: 12},
+		{testName: "SET 0,(HL)", opcode: 0xcb, operands: [2]int{0xc6}, expectedCycles: 16},
+	} {
+		t.Log(tt.testName)
+
+		cpu := Cpu{m: &Memory{}, pc: 0x100, sp: 0xfffe, p: tt.p}
+
+		cycles := step(&cpu, tt.opcode, tt.operands)
+
+		if cycles != tt.expectedCycles {
+			t.Errorf("%s: expected %d cycles, got %d\n", tt.testName, tt.expectedCycles, cycles)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	cpu := Cpu{m: &Memory{}}
+
+	// Memory is filled with NOPs, so the last one overshoots by 2 cycles
+	cycles := cpu.Run(10)
+
+	if cycles != 12 || cpu.pc != 3 {
+		t.Errorf("Expected 12 cycles and PC 3, got %d and %d\n", cycles, cpu.pc)
+	}
+}
+
+func TestStepFrame(t *testing.T) {
+	cpu := Cpu{m: &Memory{}}
+
+	// A 12 cycle instruction starting 4 cycles before the end of the
+	// frame overruns it by 8 cycles
+	last := uint16(CYCLES_PER_FRAME/4 - 1)
+	cpu.poke(last, 0x01)
+
+	cpu.StepFrame()
+
+	if cpu.frameOverrun != 8 || cpu.pc != last+3 {
+		t.Errorf("Expected overrun 8 and PC %d, got %d and %d\n", last+3, cpu.frameOverrun, cpu.pc)
+	}
+
+	// The next frame is shortened by the overrun
+	cpu.StepFrame()
+
+	expectedPc := last + 3 + (CYCLES_PER_FRAME-8)/4
+	if cpu.frameOverrun != 0 || cpu.pc != expectedPc {
+		t.Errorf("Expected overrun 0 and PC %d, got %d and %d\n", expectedPc, cpu.frameOverrun, cpu.pc)
+	}
+}
+
+func TestStepFrameDoubleSpeed(t *testing.T) {
+	cpu := Cpu{m: &Memory{}, doubleSpeed: true}
+
+	cpu.StepFrame()
+
+	// A frame of NOPs runs twice as many instructions in double speed
+	expectedPc := uint16(2 * CYCLES_PER_FRAME / 4)
+	if !cpu.DoubleSpeed() || cpu.frameOverrun != 0 || cpu.pc != expectedPc {
+		t.Errorf("Expected overrun 0 and PC %d, got %d and %d\n", expectedPc, cpu.frameOverrun, cpu.pc)
+	}
+}
+
+func TestClock(t *testing.T) {
+	for _, timing := range []int{TIMING_INSTRUCTION, TIMING_MCYCLE} {
+		for _, prefixed := range []bool{false, true} {
+			set := instructionSet
+			if prefixed {
+				set = cbInstructionSet
+			}
+
+			for opcode, instr := range set {
+				if !prefixed && (opcode == 0xcb || instr.name == "ILLEGAL") {
+					continue
+				}
+
+				cpu := Cpu{m: &Memory{}, pc: 0x100, sp: 0xfffe}
+
+				var steps []int
+				cpu.SetClock(func(cycles int) {
+					steps = append(steps, cycles)
+				}, timing)
+
+				var cycles int
+				if prefixed {
+					cycles = step(&cpu, 0xcb, [2]int{opcode})
+				} else {
+					cycles = step(&cpu, opcode, [2]int{})
+				}
+
+				total := 0
+				for _, s := range steps {
+					total += s
+
+					if timing == TIMING_MCYCLE && s != 4 {
+						t.Errorf("%s: expected M-cycle steps, got %v", instr.name, steps)
+						break
+					}
+				}
+
+				if total != cycles || (timing == TIMING_INSTRUCTION && len(steps) != 1) {
+					t.Errorf("%s: expected %d cycles to be clocked, got %v", instr.name, cycles, steps)
+				}
+			}
+		}
+	}
+}
+
+func TestDotClock(t *testing.T) {
+	for _, tt := range []struct {
+		testName    string
+		timing      int
+		doubleSpeed bool
+		expected    int // Dots per 1000 CPU cycles
+	}{
+		{testName: "Per instruction", timing: TIMING_INSTRUCTION, expected: 1000},
+		{testName: "Per M-cycle", timing: TIMING_MCYCLE, expected: 1000},
+		{testName: "Per instruction in double speed", timing: TIMING_INSTRUCTION, doubleSpeed: true, expected: 500},
+		{testName: "Per M-cycle in double speed", timing: TIMING_MCYCLE, doubleSpeed: true, expected: 500},
+	} {
+		t.Log(tt.testName)
+
+		cpu := Cpu{m: &Memory{}, doubleSpeed: tt.doubleSpeed}
+
+		var cycles, dots int
+		cpu.SetClock(func(c int) { cycles += c }, tt.timing)
+		cpu.SetDotClock(func(d int) { dots += d })
+
+		if spent := cpu.Run(1000); spent != 1000 || cycles != 1000 || dots != tt.expected {
+			t.Errorf("%s: expected 1000 cycles and %d dots, got %d, %d and %d", tt.testName, tt.expected, spent, cycles, dots)
+		}
+	}
+}
+
+// The PPU draws a frame per StepFrame whatever the CPU speed
+func TestDoubleSpeedFrames(t *testing.T) {
+	for _, doubleSpeed := range []bool{false, true} {
+		m := NewMmu(nil)
+		m.Write(LCDC_ADDR, LCDC_ENABLE)
+		m.Write(0xc000, 0x18) // JR -2
+		m.Write(0xc001, 0xfe)
+
+		cpu := NewCpu(m)
+		cpu.pc, cpu.doubleSpeed = 0xc000, doubleSpeed
+
+		p := NewPpu(m)
+		frames := 0
+		p.SetFrameHandler(func(*Frame) {
+			frames++
+		})
+
+		cpu.SetClock(m.Tick, TIMING_MCYCLE)
+		cpu.SetDotClock(p.Tick)
+		for i := 0; i < 10; i++ {
+			cpu.StepFrame()
+		}
+
+		if frames != 10 {
+			t.Errorf("Double speed %v: expected 10 frames, got %d", doubleSpeed, frames)
+		}
+	}
+}
+
+func TestMcycleAccessTiming(t *testing.T) {
+	for _, tt := range []struct {
+		testName string
+		opcode   int
+		operands [2]int
+		before   regs
+		ie, iF   uint8
+		ime      bool
+		watch    uint16
+		// Value at the watched address at the start of each M-cycle,
+		// followed by its final value
+		expected []uint8
+	}{
+		{
+			testName: "PUSH BC writes in M3 and M4",
+			opcode:   0xc5,
+			before:   regs{pc: 0x100, sp: 0xfffe, b: 0x12, c: 0x34},
+			watch:    0xfffc,
+			expected: []uint8{0x00, 0x00, 0x00, 0x00, 0x34},
+		},
+		{
+			testName: "CALL a16 writes in M5 and M6",
+			opcode:   0xcd,
+			operands: [2]int{0x00, 0x20},
+			before:   regs{pc: 0x100, sp: 0xfffe},
+			watch:    0xfffd,
+			expected: []uint8{0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x01},
+		},
+		{
+			testName: "LD (a16),A writes in M4",
+			opcode:   0xea,
+			operands: [2]int{0x00, 0xc0},
+			before:   regs{pc: 0x100, a: 0x55},
+			watch:    0xc000,
+			expected: []uint8{0x00, 0x00, 0x00, 0x00, 0x55},
+		},
+		{
+			testName: "LD (HL),d8 writes in M3",
+			opcode:   0x36,
+			operands: [2]int{0x77},
+			before:   regs{pc: 0x100, h: 0xc0},
+			watch:    0xc000,
+			expected: []uint8{0x00, 0x00, 0x00, 0x77},
+		},
+		{
+			testName: "Interrupt dispatch writes in M3 and M4",
+			before:   regs{pc: 0x100, sp: 0xfffe},
+			ie:       BIT_0,
+			iF:       BIT_0,
+			ime:      true,
+			watch:    0xfffd,
+			expected: []uint8{0x00, 0x00, 0x00, 0x01, 0x01, 0x01},
+		},
+	} {
+		t.Log(tt.testName)
+
+		cpu := Cpu{m: &Memory{}, ime: tt.ime}
+		cpu.setRegs(tt.before)
+		cpu.poke(IE_ADDR, tt.ie)
+		cpu.poke(IF_ADDR, tt.iF)
+
+		var seen []uint8
+		cpu.SetClock(func(cycles int) {
+			seen = append(seen, cpu.peek(tt.watch))
+		}, TIMING_MCYCLE)
+
+		step(&cpu, tt.opcode, tt.operands)
+		seen = append(seen, cpu.peek(tt.watch))
+
+		if fmt.Sprint(seen) != fmt.Sprint(tt.expected) {
+			t.Errorf("%s: expected %v, got %v", tt.testName, tt.expected, seen)
+		}
+	}
+}
+
+// Bus wrapper recording every access made through it
+type busRecorder struct {
+	Mem
+	accesses []string
+}
+
+func (b *busRecorder) Read(addr uint16) uint8 {
+	val := b.Mem.Read(addr)
+	b.accesses = append(b.accesses, fmt.Sprintf("R %04x %02x", addr, val))
+	return val
+}
+
+func (b *busRecorder) Write(addr uint16, val uint8) {
+	b.accesses = append(b.accesses, fmt.Sprintf("W %04x %02x", addr, val))
+	b.Mem.Write(addr, val)
+}
+
+func TestPluggableBus(t *testing.T) {
+	cart := &cartStub{}
+	// LD A,$42; LD ($C000),A
+	copy(cart.memory[0x100:], []uint8{0x3e, 0x42, 0xea, 0x00, 0xc0})
+
+	mmu := NewMmu(cart)
+	bus := &busRecorder{Mem: mmu}
+	cpu := NewCpu(bus)
+	cpu.pc = 0x100
+
+	cpu.tick()
+	cpu.tick()
+
+	if mmu.Read(0xc000) != 0x42 {
+		t.Errorf("Expected the write to reach WRAM, got %#02x", mmu.Read(0xc000))
+	}
+
+	// Checking for interrupts also polls IE and IF through the bus
+	var accesses []string
+	for _, access := range bus.accesses {
+		if access[2:6] != "ffff" && access[2:6] != "ff0f" {
+			accesses = append(accesses, access)
+		}
+	}
+
+	expected := []string{"R 0100 3e", "R 0101 42", "R 0102 ea", "R 0103 00", "R 0104 c0", "W c000 42"}
+	if fmt.Sprint(accesses) != fmt.Sprint(expected) {
+		t.Errorf("Expected accesses %v, got %v", expected, accesses)
+	}
+}
+
+func TestInstructionSetIsImplemented(t *testing.T) {
+	for opcode, instr := range instructionSet {
+		if opcode == 0xcb {
+			continue
+		}
+
+		if instr.operation == nil {
+			t.Errorf("%#02x %s has no operation", opcode, instr.name)
+		}
+	}
+
+	if len(cbInstructionSet) != 256 {
+		t.Errorf("Expected 256 CB instructions, got %d", len(cbInstructionSet))
+	}
+
+	for opcode, instr := range cbInstructionSet {
+		if instr.operation == nil {
+			t.Errorf("CB %#02x %s has no operation", opcode, instr.name)
+		}
+	}
+}
+
 // Compares Instructions skipping the operation field
 func (instr1 *Instruction) Equals(instr2 Instruction) bool {
 	cmpName := instr1.name == instr2.name
 	cmpSize := instr1.size == instr2.size
-	cmpCycles := instr1.cycles == instr2.cycles
+	cmpCycles := instr1.cycles == instr2.cycles && instr1.branchCycles == instr2.branchCycles
 	cmpOperands := instr1.operands == instr2.operands
 
 	return cmpName && cmpCycles && cmpSize && cmpOperands
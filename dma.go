@@ -0,0 +1,104 @@
+package main
+
+const (
+	DMA_ADDR = 0xff46 // OAM DMA source and start
+
+	// M-cycles from the write to DMA_ADDR until the first byte is copied
+	DMA_START_DELAY = 2
+)
+
+// Memory buses the CPU and the DMA unit compete for. The I/O registers,
+// HRAM and IE sit inside the CPU and are always accessible.
+const (
+	BUS_EXTERNAL = iota // Cartridge, WRAM and echo RAM
+	BUS_VRAM
+	BUS_OAM
+	BUS_INTERNAL
+)
+
+// OAM DMA, copying 160 bytes into OAM at one byte per M-cycle
+type dma struct {
+	active bool
+	source uint16
+	index  int   // Next byte to copy
+	last   uint8 // Byte last copied, which conflicting reads see
+
+	// A DMA started while another is running only takes over once its
+	// start delay has elapsed
+	start       int // M-cycles until the next transfer starts
+	startSource uint16
+
+	cycles int // T-cycles not yet making up a full M-cycle
+}
+
+func busOf(addr uint16) int {
+	switch {
+	case addr >= IO_ADDR:
+		return BUS_INTERNAL
+	case addr >= OAM_ADDR:
+		return BUS_OAM
+	case addr >= VRAM_ADDR && addr < EXT_RAM_ADDR:
+		return BUS_VRAM
+	}
+
+	return BUS_EXTERNAL
+}
+
+// Whether a running DMA keeps the CPU from accessing an address. OAM is
+// always blocked, as is the bus the DMA reads from.
+func (m *Mmu) dmaBlocks(addr uint16) bool {
+	if !m.dma.active {
+		return false
+	}
+
+	bus := busOf(addr)
+	return bus == BUS_OAM || bus == busOf(dmaSourceAddr(m.dma.source))
+}
+
+// Sources past WRAM read the echo of WRAM, all the way up to 0xffff
+func dmaSourceAddr(addr uint16) uint16 {
+	if addr >= ECHO_ADDR {
+		addr -= ECHO_ADDR - WRAM_ADDR
+	}
+
+	return addr
+}
+
+func (m *Mmu) startDma(val uint8) {
+	m.dma.start = DMA_START_DELAY
+	m.dma.startSource = uint16(val) << 8
+}
+
+// Runs the DMA unit for the given number of T-cycles. It has to be
+// clocked along with the CPU for transfers to take effect.
+func (m *Mmu) Tick(cycles int) {
+	m.dma.cycles += cycles
+	for ; m.dma.cycles >= 4; m.dma.cycles -= 4 {
+		m.dmaStep()
+	}
+}
+
+// Runs an M-cycle of DMA
+func (m *Mmu) dmaStep() {
+	// OAM is released the M-cycle after the last byte is copied
+	if m.dma.active && m.dma.index == OAM_SIZE {
+		m.dma.active = false
+	}
+
+	if m.dma.start > 0 {
+		m.dma.start--
+		if m.dma.start == 0 {
+			m.dma.active = true
+			m.dma.source = m.dma.startSource
+			m.dma.index = 0
+		}
+	}
+
+	if !m.dma.active || m.dma.index == OAM_SIZE {
+		return
+	}
+
+	m.dma.last = m.read(dmaSourceAddr(m.dma.source + uint16(m.dma.index)))
+	m.oam[m.dma.index] = m.dma.last
+	m.dma.index++
+}
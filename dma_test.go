@@ -0,0 +1,199 @@
+package main
+
+import (
+	"testing"
+)
+
+// Fills every region with a value derived from its address, so that the
+// source of copied bytes can be checked
+func newDmaTestMmu() (*Mmu, *cartStub) {
+	cart := &cartStub{}
+	for addr := range cart.memory {
+		cart.memory[addr] = dmaPattern(uint16(addr))
+	}
+
+	m := NewMmu(cart)
+	for i := range m.vram {
+		m.vram[i] = dmaPattern(VRAM_ADDR + uint16(i))
+	}
+	for i := range m.wram {
+		m.wram[i] = dmaPattern(WRAM_ADDR + uint16(i))
+	}
+
+	return m, cart
+}
+
+func dmaPattern(addr uint16) uint8 {
+	return uint8(addr) ^ uint8(addr>>8) ^ 0x5a
+}
+
+// Runs a number of M-cycles
+func dmaCycles(m *Mmu, count int) {
+	for i := 0; i < count; i++ {
+		m.Tick(4)
+	}
+}
+
+func TestDmaSources(t *testing.T) {
+	for _, tt := range []struct {
+		testName string
+		source   uint8
+		from     uint16 // Where the bytes are expected to come from
+	}{
+		{testName: "ROM", source: 0x00, from: 0x0000},
+		{testName: "Banked ROM", source: 0x7f, from: 0x7f00},
+		{testName: "VRAM", source: 0x80, from: 0x8000},
+		{testName: "Cartridge RAM", source: 0xa0, from: 0xa000},
+		{testName: "WRAM", source: 0xc0, from: 0xc000},
+		{testName: "Echo RAM", source: 0xe0, from: 0xc000},
+		{testName: "OAM reads WRAM", source: 0xfe, from: 0xde00},
+		{testName: "I/O reads WRAM", source: 0xff, from: 0xdf00},
+	} {
+		t.Log(tt.testName)
+
+		m, _ := newDmaTestMmu()
+		m.Write(DMA_ADDR, tt.source)
+		dmaCycles(m, DMA_START_DELAY+OAM_SIZE)
+
+		for i := uint16(0); i < OAM_SIZE; i++ {
+			if val, expected := m.Read(OAM_ADDR+i), dmaPattern(tt.from+i); val != expected {
+				t.Errorf("%s: expected %#02x at OAM byte %d, got %#02x", tt.testName, expected, i, val)
+				break
+			}
+		}
+
+		if val := m.Read(DMA_ADDR); val != tt.source {
+			t.Errorf("%s: expected DMA to read back %#02x, got %#02x", tt.testName, tt.source, val)
+		}
+	}
+}
+
+func TestDmaTiming(t *testing.T) {
+	m, _ := newDmaTestMmu()
+	m.Write(OAM_ADDR, 0x12)
+	m.Write(DMA_ADDR, 0xc0)
+
+	// The M-cycle after the write sets the transfer up
+	dmaCycles(m, 1)
+	if val := m.Read(OAM_ADDR); val != 0x12 {
+		t.Errorf("Expected OAM to be accessible while DMA starts, got %#02x", val)
+	}
+
+	for cycle := 2; cycle < DMA_START_DELAY+OAM_SIZE; cycle++ {
+		dmaCycles(m, 1)
+		if val := m.Read(OAM_ADDR); val != 0xff {
+			t.Fatalf("Expected OAM to be blocked at M-cycle %d, got %#02x", cycle, val)
+		}
+	}
+
+	dmaCycles(m, 1)
+	if val := m.Read(OAM_ADDR); val != dmaPattern(0xc000) {
+		t.Errorf("Expected OAM to be accessible after 160 M-cycles, got %#02x", val)
+	}
+}
+
+func TestDmaRestart(t *testing.T) {
+	m, _ := newDmaTestMmu()
+	m.Write(DMA_ADDR, 0xc0)
+	dmaCycles(m, 50)
+
+	// The running transfer keeps OAM blocked until the new one starts
+	m.Write(DMA_ADDR, 0x80)
+	for cycle := 0; cycle < DMA_START_DELAY+OAM_SIZE-1; cycle++ {
+		dmaCycles(m, 1)
+		if val := m.Read(OAM_ADDR); val != 0xff {
+			t.Fatalf("Expected OAM to be blocked %d M-cycles after the restart, got %#02x", cycle+1, val)
+		}
+	}
+
+	dmaCycles(m, 1)
+	for i := uint16(0); i < OAM_SIZE; i++ {
+		if val, expected := m.Read(OAM_ADDR+i), dmaPattern(0x8000+i); val != expected {
+			t.Fatalf("Expected %#02x from the restarted DMA at OAM byte %d, got %#02x", expected, i, val)
+		}
+	}
+}
+
+func TestDmaBusConflicts(t *testing.T) {
+	for _, tt := range []struct {
+		testName  string
+		source    uint8
+		addr      uint16
+		conflicts bool
+	}{
+		{testName: "WRAM during DMA from WRAM", source: 0xc1, addr: 0xc000, conflicts: true},
+		{testName: "ROM during DMA from WRAM", source: 0xc1, addr: 0x1234, conflicts: true},
+		{testName: "Cartridge RAM during DMA from ROM", source: 0x10, addr: 0xa000, conflicts: true},
+		{testName: "VRAM during DMA from WRAM", source: 0xc1, addr: 0x8000, conflicts: false},
+		{testName: "VRAM during DMA from VRAM", source: 0x81, addr: 0x9000, conflicts: true},
+		{testName: "WRAM during DMA from VRAM", source: 0x81, addr: 0xc000, conflicts: false},
+		{testName: "HRAM", source: 0xc1, addr: 0xff80, conflicts: false},
+		{testName: "HRAM during DMA from 0xff", source: 0xff, addr: 0xff80, conflicts: false},
+		{testName: "WRAM during DMA from 0xfe", source: 0xfe, addr: 0xc000, conflicts: true},
+		{testName: "ROM during DMA from 0xff", source: 0xff, addr: 0x1234, conflicts: true},
+		{testName: "I/O registers", source: 0xc1, addr: 0xff42, conflicts: false},
+		{testName: "IE", source: 0xc1, addr: IE_ADDR, conflicts: false},
+	} {
+		t.Log(tt.testName)
+
+		m, _ := newDmaTestMmu()
+		m.Write(0xff80, 0x33)
+		m.Write(0xff42, 0x44)
+		m.Write(IE_ADDR, 0x15)
+		expected := m.Read(tt.addr)
+
+		m.Write(DMA_ADDR, tt.source)
+		dmaCycles(m, DMA_START_DELAY+10)
+
+		// Reads see the byte DMA last copied, the 11th
+		if tt.conflicts {
+			expected = dmaPattern(dmaSourceAddr(uint16(tt.source)<<8 + 10))
+		}
+
+		if val := m.Read(tt.addr); val != expected {
+			t.Errorf("%s: expected %#02x, got %#02x", tt.testName, expected, val)
+		}
+
+		m.Write(tt.addr, ^expected)
+		dmaCycles(m, OAM_SIZE)
+		written := m.Read(tt.addr) == ^expected
+		if tt.addr >= VRAM_ADDR && written == tt.conflicts {
+			t.Errorf("%s: expected the write to be lost %v", tt.testName, tt.conflicts)
+		}
+	}
+}
+
+func TestDmaFromHram(t *testing.T) {
+	m, _ := newDmaTestMmu()
+	for i, b := range []uint8{
+		0x3e, 0xc1, // LD A,0xc1
+		0xe0, 0x46, // LDH (0x46),A
+		0x3e, 0x28, // LD A,40
+		0x3d,       // DEC A
+		0x20, 0xfd, // JR NZ,-3
+		0x18, 0xfe, // JR -2
+	} {
+		m.hram[i] = b
+	}
+
+	cpu := NewCpu(m)
+	cpu.pc, cpu.sp = HRAM_ADDR, 0xfffe
+	cpu.SetClock(m.Tick, TIMING_MCYCLE)
+
+	// The wait loop ends as the last byte is copied, like the RET of a
+	// typical DMA routine
+	for cpu.pc != HRAM_ADDR+9 {
+		cpu.tick()
+	}
+	if val := m.Read(OAM_ADDR); val != 0xff {
+		t.Errorf("Expected OAM to be blocked until the end of the loop, got %#02x", val)
+	}
+
+	cpu.tick()
+
+	for i := uint16(0); i < OAM_SIZE; i++ {
+		if val, expected := m.Read(OAM_ADDR+i), dmaPattern(0xc100+i); val != expected {
+			t.Fatalf("Expected %#02x at OAM byte %d, got %#02x", expected, i, val)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+// Infrared port of HuC1 and HuC3 cartridges, which the host connects to
+// another emulator or any other light source
+type Infrared interface {
+	// Switches the cartridge's LED on or off
+	SetLed(on bool)
+	// Whether the sensor currently receives light
+	Light() bool
+}
+
+// Mode register value mapping the infrared port in place of RAM
+const HUC_MODE_IR = 0x0e
+
+// Hudson HuC1, an MBC1 variant with an infrared port in place of RAM
+// enable
+type huc1 struct {
+	rom, ram []uint8
+	ir       Infrared // Nil when unconnected
+
+	irMode  bool
+	romBank uint8
+	ramBank uint8
+}
+
+func newHuc1(rom, ram []uint8) *huc1 {
+	return &huc1{rom: rom, ram: ram, romBank: 1}
+}
+
+func (m *huc1) Read(addr uint16) uint8 {
+	switch {
+	case addr < ROM_BANK_SIZE:
+		return romByte(m.rom, 0, addr)
+	case addr < VRAM_ADDR:
+		return romByte(m.rom, int(m.romBank), addr-ROM_BANK_SIZE)
+	case addr >= EXT_RAM_ADDR && addr < WRAM_ADDR:
+		if m.irMode {
+			return 0xc0 | uint8(flag(m.ir != nil && m.ir.Light()))
+		}
+
+		if len(m.ram) == 0 {
+			return 0xff
+		}
+
+		return m.ram[ramOffset(m.ram, int(m.ramBank), addr)]
+	}
+
+	return 0xff
+}
+
+func (m *huc1) Write(addr uint16, val uint8) {
+	switch {
+	case addr < 0x2000:
+		// There is no RAM enable, RAM is accessible in any other mode
+		m.irMode = val&0x0f == HUC_MODE_IR
+	case addr < 0x4000:
+		m.romBank = val & 0x3f
+	case addr < 0x6000:
+		m.ramBank = val & 0x03
+	case addr >= EXT_RAM_ADDR && addr < WRAM_ADDR:
+		if m.irMode {
+			if m.ir != nil {
+				m.ir.SetLed(val&BIT_0 != 0)
+			}
+		} else if len(m.ram) > 0 {
+			m.ram[ramOffset(m.ram, int(m.ramBank), addr)] = val
+		}
+	}
+}
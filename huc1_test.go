@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+)
+
+// An infrared port that records the LED state and lets tests shine light
+type irStub struct {
+	led, light bool
+}
+
+func (ir *irStub) SetLed(on bool) {
+	ir.led = on
+}
+
+func (ir *irStub) Light() bool {
+	return ir.light
+}
+
+func TestHuc1Banking(t *testing.T) {
+	cart := newTestCartridge(t, makeRom(0xff, 0x04, 0x03))
+
+	cart.Write(0x2000, 0x45)
+	if bank := cart.Read(0x7fff); bank != 5 {
+		t.Errorf("Expected ROM bank 5, got %d", bank)
+	}
+
+	// RAM needs no enabling
+	for bank := uint8(0); bank < 4; bank++ {
+		cart.Write(0x4000, bank)
+		cart.Write(0xa000, 0x80+bank)
+	}
+
+	for bank := uint8(0); bank < 4; bank++ {
+		cart.Write(0x4000, bank)
+		if val := cart.Read(0xa000); val != 0x80+bank {
+			t.Errorf("Expected %#02x in RAM bank %d, got %#02x", 0x80+bank, bank, val)
+		}
+	}
+}
+
+func TestHuc1Infrared(t *testing.T) {
+	cart := newTestCartridge(t, makeRom(0xff, 0x04, 0x03))
+	cart.Write(0xa000, 0x12)
+
+	// Unconnected, the sensor sees no light
+	cart.Write(0x0000, HUC_MODE_IR)
+	if val := cart.Read(0xa000); val != 0xc0 {
+		t.Errorf("Expected 0xc0 without an infrared port, got %#02x", val)
+	}
+
+	ir := &irStub{light: true}
+	cart.SetInfrared(ir)
+	if val := cart.Read(0xa000); val != 0xc1 {
+		t.Errorf("Expected 0xc1 with light, got %#02x", val)
+	}
+
+	cart.Write(0xa000, 0x01)
+	if !ir.led {
+		t.Error("Expected the LED to be on")
+	}
+
+	cart.Write(0x0000, 0x00)
+	if val := cart.Read(0xa000); val != 0x12 {
+		t.Errorf("Expected RAM to be unaffected by infrared writes, got %#02x", val)
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// HuC3 mode register values selecting what 0xa000-0xbfff maps
+const (
+	HUC3_MODE_RAM_READ = 0x00
+	HUC3_MODE_RAM      = 0x0a
+	HUC3_MODE_COMMAND  = 0x0b // RTC command write
+	HUC3_MODE_RESPONSE = 0x0c // RTC response read
+	HUC3_MODE_READY    = 0x0d // RTC semaphore
+)
+
+// RTC commands, in the upper nibble of the value written
+const (
+	HUC3_CMD_READ     = 0x1 // Read the nibble at the address and increment it
+	HUC3_CMD_WRITE    = 0x3 // Write the argument at the address and increment it
+	HUC3_CMD_ADDR_LO  = 0x4
+	HUC3_CMD_ADDR_HI  = 0x5
+	HUC3_CMD_EXTENDED = 0x6
+
+	// Extended command arguments
+	HUC3_EXT_LOAD_TIME  = 0x0 // Copy the clock into memory
+	HUC3_EXT_STORE_TIME = 0x1 // Copy memory into the clock
+	HUC3_EXT_STATUS     = 0x2
+)
+
+// Hudson HuC3, with a clock counting minutes and days that is accessed
+// through a nibble-wide memory, and an infrared port
+type huc3 struct {
+	rom, ram []uint8
+	rtc      *huc3Rtc
+	ir       Infrared // Nil when unconnected
+
+	mode    uint8
+	romBank uint8
+	ramBank uint8
+
+	// RTC memory, one nibble per address, and the command state
+	rtcMem   [256]uint8
+	rtcAddr  uint8
+	lastCmd  uint8
+	response uint8
+}
+
+func newHuc3(rom, ram []uint8, rtc *huc3Rtc) *huc3 {
+	return &huc3{rom: rom, ram: ram, rtc: rtc, romBank: 1}
+}
+
+func (m *huc3) Read(addr uint16) uint8 {
+	switch {
+	case addr < ROM_BANK_SIZE:
+		return romByte(m.rom, 0, addr)
+	case addr < VRAM_ADDR:
+		return romByte(m.rom, int(m.romBank), addr-ROM_BANK_SIZE)
+	case addr >= EXT_RAM_ADDR && addr < WRAM_ADDR:
+		switch m.mode {
+		case HUC3_MODE_RAM_READ, HUC3_MODE_RAM:
+			if len(m.ram) > 0 {
+				return m.ram[ramOffset(m.ram, int(m.ramBank), addr)]
+			}
+		case HUC3_MODE_RESPONSE:
+			return m.lastCmd<<4 | m.response
+		case HUC3_MODE_READY:
+			// Commands complete immediately
+			return 0xff
+		case HUC_MODE_IR:
+			return 0xc0 | uint8(flag(m.ir != nil && m.ir.Light()))
+		}
+	}
+
+	return 0xff
+}
+
+func (m *huc3) Write(addr uint16, val uint8) {
+	switch {
+	case addr < 0x2000:
+		m.mode = val & 0x0f
+	case addr < 0x4000:
+		m.romBank = val & 0x7f
+	case addr < 0x6000:
+		m.ramBank = val & 0x03
+	case addr >= EXT_RAM_ADDR && addr < WRAM_ADDR:
+		switch m.mode {
+		case HUC3_MODE_RAM:
+			if len(m.ram) > 0 {
+				m.ram[ramOffset(m.ram, int(m.ramBank), addr)] = val
+			}
+		case HUC3_MODE_COMMAND:
+			m.command(val>>4, val&0x0f)
+		case HUC_MODE_IR:
+			if m.ir != nil {
+				m.ir.SetLed(val&BIT_0 != 0)
+			}
+		}
+	}
+}
+
+func (m *huc3) command(cmd, arg uint8) {
+	m.lastCmd = cmd
+
+	switch cmd {
+	case HUC3_CMD_READ:
+		m.response = m.rtcMem[m.rtcAddr]
+		m.rtcAddr++
+	case HUC3_CMD_WRITE:
+		m.rtcMem[m.rtcAddr] = arg
+		m.rtcAddr++
+	case HUC3_CMD_ADDR_LO:
+		m.rtcAddr = m.rtcAddr&0xf0 | arg
+	case HUC3_CMD_ADDR_HI:
+		m.rtcAddr = m.rtcAddr&0x0f | arg<<4
+	case HUC3_CMD_EXTENDED:
+		switch arg {
+		case HUC3_EXT_LOAD_TIME:
+			putNibbles(m.rtcMem[0:3], m.rtc.minutes)
+			putNibbles(m.rtcMem[3:6], m.rtc.days)
+		case HUC3_EXT_STORE_TIME:
+			m.rtc.minutes = nibbles(m.rtcMem[0:3]) % HUC3_MINUTES_PER_DAY
+			m.rtc.days = nibbles(m.rtcMem[3:6])
+			m.rtc.cycles = 0
+		case HUC3_EXT_STATUS:
+			m.response = 0x1
+		}
+	}
+}
+
+// Stores a value across nibbles, least significant first
+func putNibbles(mem []uint8, val uint16) {
+	for i := range mem {
+		mem[i] = uint8(val>>(i*4)) & 0x0f
+	}
+}
+
+func nibbles(mem []uint8) uint16 {
+	var val uint16
+	for i := range mem {
+		val |= uint16(mem[i]&0x0f) << (i * 4)
+	}
+
+	return val
+}
+
+const (
+	HUC3_MINUTES_PER_DAY = 1440
+
+	// Minutes and days as 16-bit words, followed by a 64-bit Unix
+	// timestamp, all little endian
+	HUC3_TRAILER_SIZE = 12
+)
+
+// Clock of the HuC3, counting minutes within the day and a 12-bit day
+// counter
+type huc3Rtc struct {
+	minutes uint16
+	days    uint16
+	cycles  int // Towards the next minute
+
+	// Source of the wall-clock time saves are stamped with
+	now func() time.Time
+}
+
+func newHuc3Rtc() *huc3Rtc {
+	return &huc3Rtc{now: time.Now}
+}
+
+func (r *huc3Rtc) tick(cycles int) {
+	r.cycles += cycles
+	for r.cycles >= 60*RTC_CYCLES_PER_SECOND {
+		r.cycles -= 60 * RTC_CYCLES_PER_SECOND
+		r.advance(1)
+	}
+}
+
+func (r *huc3Rtc) advance(minutes int64) {
+	total := int64(r.days)*HUC3_MINUTES_PER_DAY + int64(r.minutes) + minutes
+	r.days = uint16(total/HUC3_MINUTES_PER_DAY) & 0x0fff
+	r.minutes = uint16(total % HUC3_MINUTES_PER_DAY)
+}
+
+func (r *huc3Rtc) marshal() []uint8 {
+	data := make([]uint8, HUC3_TRAILER_SIZE)
+	binary.LittleEndian.PutUint16(data[0:], r.minutes)
+	binary.LittleEndian.PutUint16(data[2:], r.days)
+	binary.LittleEndian.PutUint64(data[4:], uint64(r.now().Unix()))
+
+	return data
+}
+
+func (r *huc3Rtc) unmarshal(data []uint8) error {
+	if len(data) != HUC3_TRAILER_SIZE {
+		return fmt.Errorf("RTC data is %d bytes, expected %d", len(data), HUC3_TRAILER_SIZE)
+	}
+
+	r.minutes = binary.LittleEndian.Uint16(data[0:]) % HUC3_MINUTES_PER_DAY
+	r.days = binary.LittleEndian.Uint16(data[2:]) & 0x0fff
+	r.cycles = 0
+
+	saved := int64(binary.LittleEndian.Uint64(data[4:]))
+	if elapsed := r.now().Unix() - saved; elapsed > 0 {
+		r.advance(elapsed / 60)
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+func huc3Command(cart *Cartridge, val uint8) {
+	cart.Write(0x0000, HUC3_MODE_COMMAND)
+	cart.Write(0xa000, val)
+}
+
+func huc3Response(cart *Cartridge) uint8 {
+	cart.Write(0x0000, HUC3_MODE_RESPONSE)
+	return cart.Read(0xa000)
+}
+
+func TestHuc3Ram(t *testing.T) {
+	cart := newTestCartridge(t, makeRom(0xfe, 0x04, 0x03))
+
+	cart.Write(0x0000, HUC3_MODE_RAM)
+	cart.Write(0x4000, 0x02)
+	cart.Write(0xa000, 0x34)
+
+	// Mode 0 maps RAM read-only
+	cart.Write(0x0000, HUC3_MODE_RAM_READ)
+	cart.Write(0xa000, 0x56)
+	if val := cart.Read(0xa000); val != 0x34 {
+		t.Errorf("Expected 0x34 in read-only RAM, got %#02x", val)
+	}
+
+	cart.Write(0x0000, HUC3_MODE_READY)
+	if val := cart.Read(0xa000); val != 0xff {
+		t.Errorf("Expected the clock to be ready, got %#02x", val)
+	}
+
+	cart.Write(0x2000, 0x05)
+	if bank := cart.Read(0x7fff); bank != 5 {
+		t.Errorf("Expected ROM bank 5, got %d", bank)
+	}
+}
+
+func TestHuc3Clock(t *testing.T) {
+	cart := newTestCartridge(t, makeRom(0xfe, 0x04, 0x03))
+
+	// Set the time to day 5, minute 100
+	huc3Command(cart, 0x40)
+	huc3Command(cart, 0x50)
+	for _, nibble := range []uint8{0x4, 0x6, 0x0, 0x5, 0x0, 0x0} {
+		huc3Command(cart, HUC3_CMD_WRITE<<4|nibble)
+	}
+	huc3Command(cart, HUC3_CMD_EXTENDED<<4|HUC3_EXT_STORE_TIME)
+
+	cart.Tick(60 * RTC_CYCLES_PER_SECOND)
+
+	huc3Command(cart, HUC3_CMD_EXTENDED<<4|HUC3_EXT_LOAD_TIME)
+	huc3Command(cart, 0x40)
+
+	var read []uint8
+	for i := 0; i < 6; i++ {
+		huc3Command(cart, HUC3_CMD_READ<<4)
+		read = append(read, huc3Response(cart))
+	}
+
+	for i, expected := range []uint8{0x15, 0x16, 0x10, 0x15, 0x10, 0x10} {
+		if read[i] != expected {
+			t.Errorf("Expected response %#02x for nibble %d, got %#02x", expected, i, read[i])
+		}
+	}
+
+	huc3Command(cart, HUC3_CMD_EXTENDED<<4|HUC3_EXT_STATUS)
+	if val := huc3Response(cart); val != 0x61 {
+		t.Errorf("Expected status 0x61, got %#02x", val)
+	}
+}
+
+func TestHuc3SaveTrailer(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	clock := func() time.Time { return now }
+
+	cart := newTestCartridge(t, makeRom(0xfe, 0x04, 0x03))
+	cart.clock.(*huc3Rtc).now = clock
+	cart.clock.(*huc3Rtc).minutes = 1430
+	cart.clock.(*huc3Rtc).days = 7
+
+	data := cart.ExportSave()
+	if len(data) != 0x8000+HUC3_TRAILER_SIZE {
+		t.Fatalf("Expected %d bytes, got %d", 0x8000+HUC3_TRAILER_SIZE, len(data))
+	}
+
+	if stamp := binary.LittleEndian.Uint64(data[0x8000+4:]); stamp != 1700000000 {
+		t.Errorf("Expected timestamp 1700000000, got %d", stamp)
+	}
+
+	// Load 20 minutes and 59 seconds later, which crosses midnight
+	now = now.Add(20*time.Minute + 59*time.Second)
+	restored := newTestCartridge(t, makeRom(0xfe, 0x04, 0x03))
+	restored.clock.(*huc3Rtc).now = clock
+	if err := restored.ImportSave(data); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if rtc := restored.clock.(*huc3Rtc); rtc.days != 8 || rtc.minutes != 10 {
+		t.Errorf("Expected day 8 minute 10, got day %d minute %d", rtc.days, rtc.minutes)
+	}
+
+	if err := restored.ImportSave(data[:len(data)-1]); err == nil {
+		t.Error("Expected an error for a truncated trailer")
+	}
+}
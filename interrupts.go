@@ -0,0 +1,73 @@
+package main
+
+// Interrupt sources, from highest to lowest priority. Each one is
+// the bit it occupies in IE and IF.
+const (
+	INT_VBLANK = iota
+	INT_STAT
+	INT_TIMER
+	INT_SERIAL
+	INT_JOYPAD
+)
+
+const (
+	IF_ADDR = 0xff0f // Interrupt flag
+	IE_ADDR = 0xffff // Interrupt enable
+
+	INT_VECTOR_BASE = 0x40 // Handler of INT_VBLANK; the rest follow every 8 bytes
+
+	INT_DISPATCH_CYCLES = 20
+)
+
+// Flags an interrupt as requested. It is serviced once it is enabled
+// in IE and IME is set.
+func (cpu *Cpu) RequestInterrupt(interrupt int) {
+	cpu.poke(IF_ADDR, cpu.peek(IF_ADDR)|1<<interrupt)
+}
+
+// Returns the interrupts that are both requested and enabled
+func (cpu *Cpu) pendingInterrupts() uint8 {
+	return cpu.peek(IE_ADDR) & cpu.peek(IF_ADDR) & 0x1f
+}
+
+// Dispatches the highest priority pending interrupt, if IME allows it,
+// returning the cycles spent
+func (cpu *Cpu) handleInterrupts() int {
+	pending := cpu.pendingInterrupts()
+	if !cpu.ime || pending == 0 {
+		return 0
+	}
+
+	interrupt := 0
+	for pending&(1<<interrupt) == 0 {
+		interrupt++
+	}
+
+	// Following the HALT bug, the handler returns to the HALT itself
+	if cpu.haltBug {
+		cpu.haltBug = false
+		cpu.pc--
+	}
+
+	// Two wait states precede pushing PC. The last cycle, which jumps to
+	// the handler, is clocked by tick.
+	cpu.internal()
+	cpu.ime = false
+	cpu.poke(IF_ADDR, cpu.peek(IF_ADDR)&^(1<<interrupt))
+	cpu.push(cpu.pc)
+	cpu.pc = INT_VECTOR_BASE + uint16(interrupt)*8
+
+	return INT_DISPATCH_CYCLES
+}
+
+// Enables IME once the instruction following EI has been executed
+func (cpu *Cpu) updateIme() {
+	if cpu.eiDelay == 0 {
+		return
+	}
+
+	cpu.eiDelay--
+	if cpu.eiDelay == 0 {
+		cpu.ime = true
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestRequestInterrupt(t *testing.T) {
+	cpu := Cpu{m: &Memory{}}
+
+	cpu.RequestInterrupt(INT_TIMER)
+	cpu.RequestInterrupt(INT_JOYPAD)
+
+	if cpu.peek(IF_ADDR) != BIT_2|BIT_4 {
+		t.Errorf("Expected IF %#02x, got %#02x", BIT_2|BIT_4, cpu.peek(IF_ADDR))
+	}
+}
+
+func TestInterruptDispatch(t *testing.T) {
+	for _, tt := range []struct {
+		testName       string
+		ime            bool
+		ie, iF         uint8
+		expectedPc     uint16
+		expectedIf     uint8
+		expectedCycles int
+	}{
+		{
+			testName:       "VBlank",
+			ime:            true,
+			ie:             0x1f,
+			iF:             BIT_0,
+			expectedPc:     0x40,
+			expectedCycles: 20,
+		},
+		{
+			testName:       "Joypad",
+			ime:            true,
+			ie:             0x1f,
+			iF:             BIT_4,
+			expectedPc:     0x60,
+			expectedCycles: 20,
+		},
+		{
+			testName:       "STAT has priority over Timer and Serial",
+			ime:            true,
+			ie:             0x1f,
+			iF:             BIT_1 | BIT_2 | BIT_3,
+			expectedPc:     0x48,
+			expectedIf:     BIT_2 | BIT_3,
+			expectedCycles: 20,
+		},
+		{
+			testName:       "Disabled interrupts are not serviced",
+			ime:            true,
+			ie:             BIT_2,
+			iF:             BIT_0 | BIT_2,
+			expectedPc:     0x50,
+			expectedIf:     BIT_0,
+			expectedCycles: 20,
+		},
+		{
+			testName:       "IME clear",
+			ie:             0x1f,
+			iF:             BIT_0,
+			expectedPc:     0x101,
+			expectedIf:     BIT_0,
+			expectedCycles: 4,
+		},
+		{
+			testName:       "Nothing requested",
+			ime:            true,
+			ie:             0x1f,
+			expectedPc:     0x101,
+			expectedCycles: 4,
+		},
+	} {
+		t.Log(tt.testName)
+
+		cpu := Cpu{m: &Memory{}, pc: 0x100, sp: 0xfffe, ime: tt.ime}
+		cpu.poke(IE_ADDR, tt.ie)
+		cpu.poke(IF_ADDR, tt.iF)
+
+		cycles := cpu.tick()
+
+		if cpu.pc != tt.expectedPc {
+			t.Errorf("%s: expected PC %#04x, got %#04x", tt.testName, tt.expectedPc, cpu.pc)
+		}
+
+		if cpu.peek(IF_ADDR) != tt.expectedIf {
+			t.Errorf("%s: expected IF %#02x, got %#02x", tt.testName, tt.expectedIf, cpu.peek(IF_ADDR))
+		}
+
+		if cycles != tt.expectedCycles {
+			t.Errorf("%s: expected %d cycles, got %d", tt.testName, tt.expectedCycles, cycles)
+		}
+
+		// A dispatch pushes PC and disables further interrupts
+		if cycles == INT_DISPATCH_CYCLES {
+			if cpu.ime || cpu.sp != 0xfffc || cpu.pop() != 0x100 {
+				t.Errorf("%s: expected IME cleared and PC pushed", tt.testName)
+			}
+		}
+	}
+}
+
+func TestEiDelay(t *testing.T) {
+	cpu := Cpu{m: &Memory{}, sp: 0xfffe}
+	cpu.poke(IE_ADDR, BIT_0)
+	cpu.poke(IF_ADDR, BIT_0)
+
+	// EI, NOP, NOP
+	cpu.poke(0x00, 0xfb)
+
+	cpu.tick()
+	if cpu.ime || cpu.pc != 1 {
+		t.Errorf("Expected IME clear right after EI")
+	}
+
+	cpu.tick()
+	if !cpu.ime || cpu.pc != 2 {
+		t.Errorf("Expected the instruction after EI to run before any interrupt")
+	}
+
+	cpu.tick()
+	if cpu.pc != 0x40 {
+		t.Errorf("Expected the interrupt to be dispatched, PC is %#04x", cpu.pc)
+	}
+}
+
+func TestDiCancelsEi(t *testing.T) {
+	cpu := Cpu{m: &Memory{}}
+
+	// EI, DI, NOP
+	cpu.poke(0x00, 0xfb)
+	cpu.poke(0x01, 0xf3)
+
+	cpu.Run(12)
+
+	if cpu.ime {
+		t.Errorf("Expected DI right after EI to keep IME clear")
+	}
+}
+
+func TestRetiEnablesImmediately(t *testing.T) {
+	cpu := Cpu{m: &Memory{}, pc: 0x100, sp: 0xfffc}
+	cpu.poke(0xfffc, 0x00)
+	cpu.poke(0xfffd, 0x02)
+	cpu.poke(0x100, 0xd9)
+	cpu.poke(IE_ADDR, BIT_3)
+	cpu.poke(IF_ADDR, BIT_3)
+
+	cpu.tick()
+	cpu.tick()
+
+	if cpu.pc != 0x58 || cpu.pop() != 0x200 {
+		t.Errorf("Expected the Serial interrupt right after RETI, PC is %#04x", cpu.pc)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+// MBC1, supporting up to 2MB of ROM and 32KB of RAM. Its second bank
+// register either extends the ROM bank number or selects the RAM bank,
+// depending on the banking mode.
+type mbc1 struct {
+	rom, ram []uint8
+
+	ramEnabled bool
+	bank1      uint8 // Low bits of the ROM bank, never 0
+	bank2      uint8 // Upper ROM bank bits, or RAM bank
+	mode       uint8 // In mode 1, bank2 also applies to 0x0000-0x3fff and RAM
+
+	// MBC1M multicarts wire the upper bank bits one bit lower, leaving
+	// 4 bits for bank1
+	multicart bool
+}
+
+func newMbc1(rom, ram []uint8) *mbc1 {
+	return &mbc1{rom: rom, ram: ram, bank1: 1, multicart: isMbc1Multicart(rom)}
+}
+
+// Multicarts are 1MB images holding several games, each with its own
+// header, so the logo shows up again at the start of the second game
+func isMbc1Multicart(rom []uint8) bool {
+	const gameBank = 0x10
+
+	if len(rom) != 64*ROM_BANK_SIZE {
+		return false
+	}
+
+	logo := rom[gameBank*ROM_BANK_SIZE+HEADER_LOGO:]
+	return [48]uint8(logo[:len(nintendoLogo)]) == nintendoLogo
+}
+
+func (m *mbc1) bank2Shift() uint {
+	if m.multicart {
+		return 4
+	}
+
+	return 5
+}
+
+func (m *mbc1) Read(addr uint16) uint8 {
+	switch {
+	case addr < ROM_BANK_SIZE:
+		bank := 0
+		if m.mode == 1 {
+			bank = int(m.bank2) << m.bank2Shift()
+		}
+
+		return romByte(m.rom, bank, addr)
+	case addr < VRAM_ADDR:
+		bank1 := m.bank1
+		if m.multicart {
+			bank1 &= 0x0f
+		}
+
+		bank := int(m.bank2)<<m.bank2Shift() | int(bank1)
+		return romByte(m.rom, bank, addr-ROM_BANK_SIZE)
+	case addr >= EXT_RAM_ADDR && addr < WRAM_ADDR:
+		if !m.ramEnabled || len(m.ram) == 0 {
+			return 0xff
+		}
+
+		return m.ram[ramOffset(m.ram, m.ramBank(), addr)]
+	}
+
+	return 0xff
+}
+
+func (m *mbc1) Write(addr uint16, val uint8) {
+	switch {
+	case addr < 0x2000:
+		m.ramEnabled = val&0x0f == 0x0a
+	case addr < 0x4000:
+		// Bank 0 is translated to 1 before the bank is masked to the ROM
+		// size, so banks 0x20, 0x40 and 0x60 are never reachable here
+		m.bank1 = val & 0x1f
+		if m.bank1 == 0 {
+			m.bank1 = 1
+		}
+	case addr < 0x6000:
+		m.bank2 = val & 0x03
+	case addr < VRAM_ADDR:
+		m.mode = val & 0x01
+	case addr >= EXT_RAM_ADDR && addr < WRAM_ADDR:
+		if m.ramEnabled && len(m.ram) > 0 {
+			m.ram[ramOffset(m.ram, m.ramBank(), addr)] = val
+		}
+	}
+}
+
+func (m *mbc1) ramBank() int {
+	if m.mode == 1 {
+		return int(m.bank2)
+	}
+
+	return 0
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"testing"
+)
+
+// A write to an MBC register
+type mbcWrite struct {
+	addr uint16
+	val  uint8
+}
+
+func newTestCartridge(t *testing.T, rom []uint8) *Cartridge {
+	cart, err := NewCartridge(rom)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	return cart
+}
+
+func TestMbc1RomBanking(t *testing.T) {
+	for _, tt := range []struct {
+		testName      string
+		romCode       uint8
+		writes        []mbcWrite
+		expectedBank0 uint8 // Bank mapped at 0x0000-0x3fff
+		expectedBank1 uint8 // Bank mapped at 0x4000-0x7fff
+	}{
+		{
+			testName:      "Power on",
+			romCode:       0x04,
+			expectedBank0: 0,
+			expectedBank1: 1,
+		},
+		{
+			testName:      "Select bank 5",
+			romCode:       0x04,
+			writes:        []mbcWrite{{0x2000, 0x05}},
+			expectedBank1: 5,
+		},
+		{
+			testName:      "Bank 0 selects bank 1",
+			romCode:       0x04,
+			writes:        []mbcWrite{{0x2000, 0x05}, {0x3fff, 0x00}},
+			expectedBank1: 1,
+		},
+		{
+			testName:      "Only 5 bits are used",
+			romCode:       0x04,
+			writes:        []mbcWrite{{0x2000, 0xe3}},
+			expectedBank1: 3,
+		},
+		{
+			testName:      "Bank wraps to the ROM size",
+			romCode:       0x02,
+			writes:        []mbcWrite{{0x2000, 0x0b}},
+			expectedBank1: 3,
+		},
+		{
+			testName:      "Zero check happens before masking",
+			romCode:       0x02,
+			writes:        []mbcWrite{{0x2000, 0x10}},
+			expectedBank1: 0,
+		},
+		{
+			testName:      "Upper bits on a large ROM",
+			romCode:       0x06,
+			writes:        []mbcWrite{{0x2000, 0x04}, {0x4000, 0x02}},
+			expectedBank1: 0x44,
+		},
+		{
+			testName:      "Bank 0x20 maps 0x21",
+			romCode:       0x06,
+			writes:        []mbcWrite{{0x2000, 0x00}, {0x4000, 0x01}},
+			expectedBank1: 0x21,
+		},
+		{
+			testName:      "Mode 1 maps upper bits at 0x0000",
+			romCode:       0x06,
+			writes:        []mbcWrite{{0x2000, 0x01}, {0x4000, 0x03}, {0x6000, 0x01}},
+			expectedBank0: 0x60,
+			expectedBank1: 0x61,
+		},
+		{
+			testName:      "Mode 1 on a small ROM",
+			romCode:       0x04,
+			writes:        []mbcWrite{{0x4000, 0x01}, {0x6000, 0x01}},
+			expectedBank0: 0,
+			expectedBank1: 1,
+		},
+	} {
+		t.Log(tt.testName)
+
+		cart := newTestCartridge(t, makeRom(0x01, tt.romCode, 0x00))
+		for _, w := range tt.writes {
+			cart.Write(w.addr, w.val)
+		}
+
+		if bank := cart.Read(0x3fff); bank != tt.expectedBank0 {
+			t.Errorf("%s: expected bank %#02x at 0x0000, got %#02x", tt.testName, tt.expectedBank0, bank)
+		}
+
+		if bank := cart.Read(0x7fff); bank != tt.expectedBank1 {
+			t.Errorf("%s: expected bank %#02x at 0x4000, got %#02x", tt.testName, tt.expectedBank1, bank)
+		}
+	}
+}
+
+func TestMbc1Ram(t *testing.T) {
+	cart := newTestCartridge(t, makeRom(0x03, 0x04, 0x03))
+
+	// Disabled at power on
+	cart.Write(0xa000, 0x12)
+	if val := cart.Read(0xa000); val != 0xff {
+		t.Errorf("Expected disabled RAM to read 0xff, got %#02x", val)
+	}
+
+	cart.Write(0x0000, 0x0a)
+	for bank := uint8(0); bank < 4; bank++ {
+		cart.Write(0x4000, bank)
+		cart.Write(0xa000, 0x10+bank)
+	}
+
+	// Mode 0 only ever accesses bank 0
+	if val := cart.Read(0xa000); val != 0x13 {
+		t.Errorf("Expected mode 0 to use RAM bank 0, got %#02x", val)
+	}
+
+	cart.Write(0x6000, 0x01)
+	for bank := uint8(0); bank < 4; bank++ {
+		cart.Write(0x4000, bank)
+		cart.Write(0xa000, 0x20+bank)
+	}
+
+	for bank := uint8(0); bank < 4; bank++ {
+		cart.Write(0x4000, bank)
+		if val := cart.Read(0xa000); val != 0x20+bank {
+			t.Errorf("Expected %#02x in RAM bank %d, got %#02x", 0x20+bank, bank, val)
+		}
+	}
+
+	// Any value without 0xa in the low nibble disables RAM
+	cart.Write(0x1fff, 0x1b)
+	if val := cart.Read(0xa000); val != 0xff {
+		t.Errorf("Expected RAM to be disabled, got %#02x", val)
+	}
+}
+
+func TestMbc1SmallRam(t *testing.T) {
+	cart := newTestCartridge(t, makeRom(0x02, 0x00, 0x01))
+
+	cart.Write(0x0000, 0x0a)
+	cart.Write(0xa001, 0x77)
+
+	// 2KB of RAM is mirrored across the bank
+	if val := cart.Read(0xa801); val != 0x77 {
+		t.Errorf("Expected RAM to be mirrored, got %#02x", val)
+	}
+}
+
+func TestMbc1Multicart(t *testing.T) {
+	rom := makeRom(0x01, 0x05, 0x00)
+	copy(rom[0x10*ROM_BANK_SIZE+HEADER_LOGO:], nintendoLogo[:])
+	fixChecksums(rom)
+
+	cart := newTestCartridge(t, rom)
+	if !cart.mbc.(*mbc1).multicart {
+		t.Fatalf("Expected a multicart to be detected")
+	}
+
+	for _, tt := range []struct {
+		testName      string
+		writes        []mbcWrite
+		expectedBank0 uint8
+		expectedBank1 uint8
+	}{
+		{
+			testName:      "Bank 1 uses 4 bits",
+			writes:        []mbcWrite{{0x2000, 0x13}},
+			expectedBank1: 0x03,
+		},
+		{
+			testName:      "Upper bits start at bit 4",
+			writes:        []mbcWrite{{0x2000, 0x02}, {0x4000, 0x01}},
+			expectedBank1: 0x12,
+		},
+		{
+			testName:      "Mode 1 selects the game at 0x0000",
+			writes:        []mbcWrite{{0x2000, 0x01}, {0x4000, 0x02}, {0x6000, 0x01}},
+			expectedBank0: 0x20,
+			expectedBank1: 0x21,
+		},
+	} {
+		t.Log(tt.testName)
+
+		cart := newTestCartridge(t, rom)
+		for _, w := range tt.writes {
+			cart.Write(w.addr, w.val)
+		}
+
+		if bank := cart.Read(0x3fff); bank != tt.expectedBank0 {
+			t.Errorf("%s: expected bank %#02x at 0x0000, got %#02x", tt.testName, tt.expectedBank0, bank)
+		}
+
+		if bank := cart.Read(0x7fff); bank != tt.expectedBank1 {
+			t.Errorf("%s: expected bank %#02x at 0x4000, got %#02x", tt.testName, tt.expectedBank1, bank)
+		}
+	}
+
+	// A regular 1MB MBC1 image is not a multicart
+	if isMbc1Multicart(makeRom(0x01, 0x05, 0x00)) {
+		t.Errorf("Expected a regular 1MB image not to be a multicart")
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+// Built-in RAM of 512 half-bytes
+const MBC2_RAM_SIZE = 0x200
+
+// MBC2, supporting up to 256KB of ROM, with 512x4 bits of RAM inside
+// the controller. Both registers share 0x0000-0x3fff and are told apart
+// by bit 8 of the address.
+type mbc2 struct {
+	rom, ram []uint8
+
+	ramEnabled bool
+	romBank    uint8
+}
+
+func newMbc2(rom, ram []uint8) *mbc2 {
+	return &mbc2{rom: rom, ram: ram, romBank: 1}
+}
+
+func (m *mbc2) Read(addr uint16) uint8 {
+	switch {
+	case addr < ROM_BANK_SIZE:
+		return romByte(m.rom, 0, addr)
+	case addr < VRAM_ADDR:
+		return romByte(m.rom, int(m.romBank), addr-ROM_BANK_SIZE)
+	case addr >= EXT_RAM_ADDR && addr < WRAM_ADDR:
+		if !m.ramEnabled {
+			return 0xff
+		}
+
+		// Only the low nibble is stored, the upper one reads as 1s. RAM
+		// is mirrored across the whole range.
+		return m.ram[addr&(MBC2_RAM_SIZE-1)] | 0xf0
+	}
+
+	return 0xff
+}
+
+func (m *mbc2) Write(addr uint16, val uint8) {
+	switch {
+	case addr < ROM_BANK_SIZE:
+		if addr&0x100 == 0 {
+			m.ramEnabled = val&0x0f == 0x0a
+			return
+		}
+
+		m.romBank = val & 0x0f
+		if m.romBank == 0 {
+			m.romBank = 1
+		}
+	case addr >= EXT_RAM_ADDR && addr < WRAM_ADDR:
+		if m.ramEnabled {
+			m.ram[addr&(MBC2_RAM_SIZE-1)] = val & 0x0f
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestMbc2RomBanking(t *testing.T) {
+	for _, tt := range []struct {
+		testName     string
+		writes       []mbcWrite
+		expectedBank uint8
+	}{
+		{testName: "Power on", expectedBank: 1},
+		{testName: "Select bank 9", writes: []mbcWrite{{0x2100, 0x09}}, expectedBank: 9},
+		{testName: "Bit 8 clear does not select a bank", writes: []mbcWrite{{0x2000, 0x09}}, expectedBank: 1},
+		{testName: "Any address with bit 8 set", writes: []mbcWrite{{0x0100, 0x05}}, expectedBank: 5},
+		{testName: "Bank 0 selects bank 1", writes: []mbcWrite{{0x2100, 0x09}, {0x3fff, 0x00}}, expectedBank: 1},
+		{testName: "Only 4 bits are used", writes: []mbcWrite{{0x2100, 0xf3}}, expectedBank: 3},
+	} {
+		t.Log(tt.testName)
+
+		cart := newTestCartridge(t, makeRom(0x05, 0x03, 0x00))
+		for _, w := range tt.writes {
+			cart.Write(w.addr, w.val)
+		}
+
+		if bank := cart.Read(0x7fff); bank != tt.expectedBank {
+			t.Errorf("%s: expected bank %#02x at 0x4000, got %#02x", tt.testName, tt.expectedBank, bank)
+		}
+	}
+}
+
+func TestMbc2Ram(t *testing.T) {
+	cart := newTestCartridge(t, makeRom(0x06, 0x03, 0x00))
+	if len(cart.ram) != MBC2_RAM_SIZE {
+		t.Fatalf("Expected %d bytes of RAM, got %d", MBC2_RAM_SIZE, len(cart.ram))
+	}
+
+	cart.Write(0xa000, 0x05)
+	if val := cart.Read(0xa000); val != 0xff {
+		t.Errorf("Expected disabled RAM to read 0xff, got %#02x", val)
+	}
+
+	// Bit 8 set does not enable RAM
+	cart.Write(0x0100, 0x0a)
+	if cart.mbc.(*mbc2).ramEnabled {
+		t.Errorf("Expected RAM to stay disabled")
+	}
+
+	cart.Write(0x0000, 0x0a)
+	cart.Write(0xa000, 0xa5)
+
+	if val := cart.Read(0xa000); val != 0xf5 {
+		t.Errorf("Expected only the low nibble to be stored, got %#02x", val)
+	}
+
+	// 512 bytes mirrored across the whole range
+	for _, addr := range []uint16{0xa200, 0xa400, 0xbe00} {
+		if val := cart.Read(addr); val != 0xf5 {
+			t.Errorf("Expected RAM to be mirrored at %#04x, got %#02x", addr, val)
+		}
+	}
+
+	cart.Write(0xb1ff, 0x0c)
+	if cart.ram[0x1ff] != 0x0c {
+		t.Errorf("Expected a write through a mirror to reach RAM, got %#02x", cart.ram[0x1ff])
+	}
+}
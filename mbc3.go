@@ -0,0 +1,75 @@
+package main
+
+// MBC3, supporting up to 2MB of ROM, 32KB of RAM and, on some
+// cartridges, a real-time clock mapped in place of RAM
+type mbc3 struct {
+	rom, ram []uint8
+	rtc      *rtc // Nil without a timer
+
+	ramEnabled bool // Also enables the RTC registers
+	romBank    uint8
+	ramBank    uint8 // RAM bank 0-3, or an RTC register
+}
+
+func newMbc3(rom, ram []uint8, rtc *rtc) *mbc3 {
+	return &mbc3{rom: rom, ram: ram, rtc: rtc, romBank: 1}
+}
+
+func (m *mbc3) Read(addr uint16) uint8 {
+	switch {
+	case addr < ROM_BANK_SIZE:
+		return romByte(m.rom, 0, addr)
+	case addr < VRAM_ADDR:
+		return romByte(m.rom, int(m.romBank), addr-ROM_BANK_SIZE)
+	case addr >= EXT_RAM_ADDR && addr < WRAM_ADDR:
+		if !m.ramEnabled {
+			return 0xff
+		}
+
+		if m.ramBank >= RTC_S && m.ramBank <= RTC_DH {
+			if m.rtc == nil {
+				return 0xff
+			}
+
+			return m.rtc.read(m.ramBank)
+		}
+
+		if m.ramBank > 0x03 || len(m.ram) == 0 {
+			return 0xff
+		}
+
+		return m.ram[ramOffset(m.ram, int(m.ramBank), addr)]
+	}
+
+	return 0xff
+}
+
+func (m *mbc3) Write(addr uint16, val uint8) {
+	switch {
+	case addr < 0x2000:
+		m.ramEnabled = val&0x0f == 0x0a
+	case addr < 0x4000:
+		m.romBank = val & 0x7f
+		if m.romBank == 0 {
+			m.romBank = 1
+		}
+	case addr < 0x6000:
+		m.ramBank = val & 0x0f
+	case addr < VRAM_ADDR:
+		if m.rtc != nil {
+			m.rtc.writeLatch(val)
+		}
+	case addr >= EXT_RAM_ADDR && addr < WRAM_ADDR:
+		if !m.ramEnabled {
+			return
+		}
+
+		if m.ramBank >= RTC_S && m.ramBank <= RTC_DH {
+			if m.rtc != nil {
+				m.rtc.write(m.ramBank, val)
+			}
+		} else if m.ramBank <= 0x03 && len(m.ram) > 0 {
+			m.ram[ramOffset(m.ram, int(m.ramBank), addr)] = val
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+func TestMbc3RomBanking(t *testing.T) {
+	for _, tt := range []struct {
+		testName     string
+		writes       []mbcWrite
+		expectedBank uint8
+	}{
+		{testName: "Power on", expectedBank: 1},
+		{testName: "Select bank 0x45", writes: []mbcWrite{{0x2000, 0x45}}, expectedBank: 0x45},
+		{testName: "Bank 0 selects bank 1", writes: []mbcWrite{{0x2000, 0x00}}, expectedBank: 1},
+		{testName: "Only 7 bits are used", writes: []mbcWrite{{0x3fff, 0xff}}, expectedBank: 0x7f},
+	} {
+		t.Log(tt.testName)
+
+		cart := newTestCartridge(t, makeRom(0x11, 0x06, 0x00))
+		for _, w := range tt.writes {
+			cart.Write(w.addr, w.val)
+		}
+
+		if bank := cart.Read(0x3fff); bank != 0 {
+			t.Errorf("%s: expected bank 0 at 0x0000, got %#02x", tt.testName, bank)
+		}
+
+		if bank := cart.Read(0x7fff); bank != tt.expectedBank {
+			t.Errorf("%s: expected bank %#02x at 0x4000, got %#02x", tt.testName, tt.expectedBank, bank)
+		}
+	}
+}
+
+func TestMbc3Ram(t *testing.T) {
+	cart := newTestCartridge(t, makeRom(0x13, 0x04, 0x03))
+
+	cart.Write(0x0000, 0x0a)
+	for bank := uint8(0); bank < 4; bank++ {
+		cart.Write(0x4000, bank)
+		cart.Write(0xa000, 0x30+bank)
+	}
+
+	for bank := uint8(0); bank < 4; bank++ {
+		cart.Write(0x4000, bank)
+		if val := cart.Read(0xa000); val != 0x30+bank {
+			t.Errorf("Expected %#02x in RAM bank %d, got %#02x", 0x30+bank, bank, val)
+		}
+	}
+
+	// No RTC on this cartridge
+	cart.Write(0x4000, RTC_S)
+	if val := cart.Read(0xa000); val != 0xff {
+		t.Errorf("Expected RTC registers to read 0xff without a timer, got %#02x", val)
+	}
+
+	cart.Write(0x0000, 0x00)
+	cart.Write(0x4000, 0x00)
+	if val := cart.Read(0xa000); val != 0xff {
+		t.Errorf("Expected disabled RAM to read 0xff, got %#02x", val)
+	}
+}
+
+// Returns a cartridge with an RTC whose wall clock is under the test's
+// control
+func newRtcCartridge(t *testing.T, now *time.Time) *Cartridge {
+	cart := newTestCartridge(t, makeRom(0x10, 0x04, 0x03))
+	cart.clock.(*rtc).now = func() time.Time { return *now }
+	cart.Write(0x0000, 0x0a)
+
+	return cart
+}
+
+func latch(cart *Cartridge) {
+	cart.Write(0x6000, 0x00)
+	cart.Write(0x6000, 0x01)
+}
+
+func readRtc(cart *Cartridge, reg uint8) uint8 {
+	cart.Write(0x4000, reg)
+	return cart.Read(0xa000)
+}
+
+func writeRtc(cart *Cartridge, reg, val uint8) {
+	cart.Write(0x4000, reg)
+	cart.Write(0xa000, val)
+}
+
+func TestRtcLatch(t *testing.T) {
+	now := time.Unix(0, 0)
+	cart := newRtcCartridge(t, &now)
+
+	writeRtc(cart, RTC_S, 10)
+	cart.Tick(RTC_CYCLES_PER_SECOND * 5)
+
+	// Writes show through right away, but counting does not until latched
+	if val := readRtc(cart, RTC_S); val != 10|0xc0 {
+		t.Errorf("Expected seconds 10 before latching, got %d", val&0x3f)
+	}
+
+	latch(cart)
+	if val := readRtc(cart, RTC_S); val != 15|0xc0 {
+		t.Errorf("Expected seconds 15 after latching, got %d", val&0x3f)
+	}
+
+	// Writing 1 again without 0 first does not latch
+	cart.Tick(RTC_CYCLES_PER_SECOND)
+	cart.Write(0x6000, 0x01)
+	if val := readRtc(cart, RTC_S); val != 15|0xc0 {
+		t.Errorf("Expected seconds to stay latched at 15, got %d", val&0x3f)
+	}
+}
+
+func TestRtcCounting(t *testing.T) {
+	for _, tt := range []struct {
+		testName string
+		before   [5]uint8
+		seconds  int
+		expected [5]uint8
+	}{
+		{
+			testName: "Seconds carry into minutes",
+			before:   [5]uint8{59, 0, 0, 0, 0},
+			seconds:  1,
+			expected: [5]uint8{0, 1, 0, 0, 0},
+		},
+		{
+			testName: "Hours carry into days",
+			before:   [5]uint8{59, 59, 23, 0xff, 0},
+			seconds:  1,
+			expected: [5]uint8{0, 0, 0, 0, RTC_DH_DAY_8},
+		},
+		{
+			testName: "Day counter overflow sets carry",
+			before:   [5]uint8{59, 59, 23, 0xff, RTC_DH_DAY_8},
+			seconds:  1,
+			expected: [5]uint8{0, 0, 0, 0, RTC_DH_CARRY},
+		},
+		{
+			testName: "Carry stays set",
+			before:   [5]uint8{0, 0, 0, 0, RTC_DH_CARRY},
+			seconds:  86400,
+			expected: [5]uint8{0, 0, 0, 1, RTC_DH_CARRY},
+		},
+		{
+			testName: "Halted clock does not count",
+			before:   [5]uint8{1, 2, 3, 4, RTC_DH_HALT},
+			seconds:  100,
+			expected: [5]uint8{1, 2, 3, 4, RTC_DH_HALT},
+		},
+		{
+			testName: "Invalid seconds wrap without carry",
+			before:   [5]uint8{63, 0, 0, 0, 0},
+			seconds:  1,
+			expected: [5]uint8{0, 0, 0, 0, 0},
+		},
+		{
+			testName: "Invalid hours count up to 31",
+			before:   [5]uint8{59, 59, 30, 0, 0},
+			seconds:  3602,
+			expected: [5]uint8{1, 0, 0, 0, 0},
+		},
+	} {
+		t.Log(tt.testName)
+
+		// Count both by cycles and by whole seconds
+		for _, bySeconds := range []bool{false, true} {
+			now := time.Unix(0, 0)
+			cart := newRtcCartridge(t, &now)
+
+			for i, val := range tt.before {
+				writeRtc(cart, RTC_S+uint8(i), val)
+			}
+
+			if bySeconds {
+				cart.clock.(*rtc).advance(int64(tt.seconds))
+			} else {
+				cart.Tick(RTC_CYCLES_PER_SECOND * tt.seconds)
+			}
+
+			if regs := cart.clock.(*rtc).registers(); regs != tt.expected {
+				t.Errorf("%s (by seconds %v): expected %v, got %v", tt.testName, bySeconds, tt.expected, regs)
+			}
+		}
+	}
+}
+
+func TestRtcSecondsWriteResetsDivider(t *testing.T) {
+	now := time.Unix(0, 0)
+	cart := newRtcCartridge(t, &now)
+
+	cart.Tick(RTC_CYCLES_PER_SECOND - 4)
+	writeRtc(cart, RTC_S, 0)
+	cart.Tick(4)
+
+	if cart.clock.(*rtc).seconds != 0 {
+		t.Errorf("Expected writing the seconds to restart the second, got %d", cart.clock.(*rtc).seconds)
+	}
+}
+
+func TestRtcSaveTrailer(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	cart := newRtcCartridge(t, &now)
+
+	cart.Write(0x4000, 0x00)
+	cart.Write(0xa000, 0x42)
+
+	for i, val := range []uint8{30, 20, 10, 0x34, RTC_DH_DAY_8} {
+		writeRtc(cart, RTC_S+uint8(i), val)
+	}
+	latch(cart)
+
+	data := cart.ExportSave()
+	if len(data) != 0x8000+RTC_TRAILER_SIZE {
+		t.Fatalf("Expected %d bytes, got %d", 0x8000+RTC_TRAILER_SIZE, len(data))
+	}
+
+	trailer := data[0x8000:]
+	for i, expected := range []uint32{30, 20, 10, 0x34, RTC_DH_DAY_8, 30, 20, 10, 0x34, RTC_DH_DAY_8} {
+		if val := binary.LittleEndian.Uint32(trailer[i*4:]); val != expected {
+			t.Errorf("Expected %d at word %d, got %d", expected, i, val)
+		}
+	}
+
+	if stamp := binary.LittleEndian.Uint64(trailer[40:]); stamp != 1700000000 {
+		t.Errorf("Expected timestamp 1700000000, got %d", stamp)
+	}
+
+	// Load in a later session, 1 day, 1 hour and 5 seconds on
+	later := now.Add(25*time.Hour + 5*time.Second)
+	restored := newRtcCartridge(t, &later)
+	if err := restored.ImportSave(data); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	restored.Write(0x4000, 0x00)
+	if val := restored.Read(0xa000); val != 0x42 {
+		t.Errorf("Expected RAM to be restored, got %#02x", val)
+	}
+
+	expected := [5]uint8{35, 20, 11, 0x35, RTC_DH_DAY_8}
+	if regs := restored.clock.(*rtc).registers(); regs != expected {
+		t.Errorf("Expected the clock to advance to %v, got %v", expected, regs)
+	}
+
+	// Latched values are restored as saved
+	if val := readRtc(restored, RTC_S); val&0x3f != 30 {
+		t.Errorf("Expected latched seconds 30, got %d", val&0x3f)
+	}
+}
+
+func TestRtcSaveTrailer32(t *testing.T) {
+	now := time.Unix(1000, 0)
+	cart := newRtcCartridge(t, &now)
+
+	data := make([]uint8, 0x8000+RTC_TRAILER_SIZE_32)
+	trailer := data[0x8000:]
+	binary.LittleEndian.PutUint32(trailer[0:], 50)
+	binary.LittleEndian.PutUint32(trailer[40:], 990)
+
+	if err := cart.ImportSave(data); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if cart.clock.(*rtc).seconds != 0 || cart.clock.(*rtc).minutes != 1 {
+		t.Errorf("Expected the clock to advance to 1:00, got %d:%02d", cart.clock.(*rtc).minutes, cart.clock.(*rtc).seconds)
+	}
+}
+
+func TestImportSaveSize(t *testing.T) {
+	now := time.Unix(0, 0)
+
+	for _, tt := range []struct {
+		testName string
+		cart     *Cartridge
+		size     int
+		valid    bool
+	}{
+		{testName: "RAM only", cart: newTestCartridge(t, makeRom(0x13, 0x04, 0x03)), size: 0x8000, valid: true},
+		{testName: "Short", cart: newTestCartridge(t, makeRom(0x13, 0x04, 0x03)), size: 0x7fff},
+		{testName: "Trailer without RTC", cart: newTestCartridge(t, makeRom(0x13, 0x04, 0x03)), size: 0x8030},
+		{testName: "RTC without trailer", cart: newRtcCartridge(t, &now), size: 0x8000, valid: true},
+		{testName: "Bad trailer size", cart: newRtcCartridge(t, &now), size: 0x8010},
+	} {
+		t.Log(tt.testName)
+
+		err := tt.cart.ImportSave(make([]uint8, tt.size))
+		if (err == nil) != tt.valid {
+			t.Errorf("%s: expected valid %v, got error %v", tt.testName, tt.valid, err)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+// MBC5, supporting up to 8MB of ROM and 128KB of RAM. Unlike the
+// earlier controllers, ROM bank 0 can be mapped at 0x4000-0x7fff.
+type mbc5 struct {
+	rom, ram []uint8
+
+	ramEnabled bool
+	romBank    uint16 // 9 bits
+	ramBank    uint8
+
+	// Rumble cartridges wire bit 3 of the RAM bank to the motor
+	hasRumble bool
+	motor     bool
+	rumble    func(on bool)
+}
+
+func newMbc5(rom, ram []uint8, hasRumble bool) *mbc5 {
+	return &mbc5{rom: rom, ram: ram, romBank: 1, hasRumble: hasRumble}
+}
+
+func (m *mbc5) Read(addr uint16) uint8 {
+	switch {
+	case addr < ROM_BANK_SIZE:
+		return romByte(m.rom, 0, addr)
+	case addr < VRAM_ADDR:
+		return romByte(m.rom, int(m.romBank), addr-ROM_BANK_SIZE)
+	case addr >= EXT_RAM_ADDR && addr < WRAM_ADDR:
+		if !m.ramEnabled || len(m.ram) == 0 {
+			return 0xff
+		}
+
+		return m.ram[ramOffset(m.ram, int(m.ramBank), addr)]
+	}
+
+	return 0xff
+}
+
+func (m *mbc5) Write(addr uint16, val uint8) {
+	switch {
+	case addr < 0x2000:
+		// All 8 bits are checked, unlike on MBC1 and MBC3
+		m.ramEnabled = val == 0x0a
+	case addr < 0x3000:
+		m.romBank = m.romBank&0x100 | uint16(val)
+	case addr < 0x4000:
+		m.romBank = m.romBank&0xff | uint16(val&0x01)<<8
+	case addr < 0x6000:
+		m.ramBank = val & 0x0f
+		if m.hasRumble {
+			m.ramBank &= 0x07
+			m.setMotor(val&BIT_3 != 0)
+		}
+	case addr >= EXT_RAM_ADDR && addr < WRAM_ADDR:
+		if m.ramEnabled && len(m.ram) > 0 {
+			m.ram[ramOffset(m.ram, int(m.ramBank), addr)] = val
+		}
+	}
+}
+
+// Reports changes of the motor state to the host
+func (m *mbc5) setMotor(on bool) {
+	if on == m.motor {
+		return
+	}
+
+	m.motor = on
+	if m.rumble != nil {
+		m.rumble(on)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestMbc5RomBanking(t *testing.T) {
+	for _, tt := range []struct {
+		testName     string
+		writes       []mbcWrite
+		expectedBank int
+	}{
+		{testName: "Power on", expectedBank: 1},
+		{testName: "Select bank 0xab", writes: []mbcWrite{{0x2000, 0xab}}, expectedBank: 0xab},
+		{testName: "Bank 0 is mapped", writes: []mbcWrite{{0x2000, 0x00}}, expectedBank: 0},
+		{testName: "Bank bit 8", writes: []mbcWrite{{0x2000, 0x12}, {0x3000, 0x01}}, expectedBank: 0x112},
+		{testName: "Only bit 0 of the high register", writes: []mbcWrite{{0x2000, 0x12}, {0x3fff, 0xfe}}, expectedBank: 0x12},
+	} {
+		t.Log(tt.testName)
+
+		rom := makeRom(0x19, 0x08, 0x00)
+		// Banks past 255 cannot be told apart by their tag alone
+		for bank := 0; bank < len(rom)/ROM_BANK_SIZE; bank++ {
+			rom[bank*ROM_BANK_SIZE+ROM_BANK_SIZE-2] = uint8(bank >> 8)
+		}
+		fixChecksums(rom)
+
+		cart := newTestCartridge(t, rom)
+		for _, w := range tt.writes {
+			cart.Write(w.addr, w.val)
+		}
+
+		bank := int(cart.Read(0x7ffe))<<8 | int(cart.Read(0x7fff))
+		if bank != tt.expectedBank {
+			t.Errorf("%s: expected bank %#03x at 0x4000, got %#03x", tt.testName, tt.expectedBank, bank)
+		}
+	}
+}
+
+func TestMbc5Ram(t *testing.T) {
+	cart := newTestCartridge(t, makeRom(0x1b, 0x04, 0x04))
+
+	// Only exactly 0x0a enables RAM
+	cart.Write(0x0000, 0x1a)
+	cart.Write(0xa000, 0x12)
+	if val := cart.Read(0xa000); val != 0xff {
+		t.Errorf("Expected disabled RAM to read 0xff, got %#02x", val)
+	}
+
+	cart.Write(0x0000, 0x0a)
+	for bank := uint8(0); bank < 16; bank++ {
+		cart.Write(0x4000, bank)
+		cart.Write(0xbfff, 0x40+bank)
+	}
+
+	for bank := uint8(0); bank < 16; bank++ {
+		cart.Write(0x4000, bank)
+		if val := cart.Read(0xbfff); val != 0x40+bank {
+			t.Errorf("Expected %#02x in RAM bank %d, got %#02x", 0x40+bank, bank, val)
+		}
+	}
+}
+
+func TestMbc5Rumble(t *testing.T) {
+	cart := newTestCartridge(t, makeRom(0x1e, 0x04, 0x03))
+
+	var states []bool
+	cart.SetRumble(func(on bool) {
+		states = append(states, on)
+	})
+
+	cart.Write(0x0000, 0x0a)
+	cart.Write(0x4000, 0x00)
+	cart.Write(0xa000, 0x11)
+
+	// The motor bit does not select a RAM bank
+	cart.Write(0x4000, BIT_3)
+	cart.Write(0x4000, BIT_3)
+	if val := cart.Read(0xa000); val != 0x11 {
+		t.Errorf("Expected RAM bank 0 with the motor on, got %#02x", val)
+	}
+
+	cart.Write(0x4000, 0x01)
+
+	// Only changes are reported
+	if len(states) != 2 || !states[0] || states[1] {
+		t.Errorf("Expected the motor to turn on then off, got %v", states)
+	}
+}
+
+func TestMbc5WithoutRumble(t *testing.T) {
+	cart := newTestCartridge(t, makeRom(0x1b, 0x04, 0x04))
+
+	called := false
+	cart.SetRumble(func(on bool) {
+		called = true
+	})
+
+	cart.Write(0x0000, 0x0a)
+	cart.Write(0x4000, 0x08)
+	cart.Write(0xa000, 0x99)
+	cart.Write(0x4000, 0x00)
+
+	if called {
+		t.Errorf("Expected no rumble on a cartridge without a motor")
+	}
+
+	// Bit 3 selects a RAM bank instead
+	if val := cart.Read(0xa000); val == 0x99 {
+		t.Errorf("Expected bank 8 to be separate from bank 0")
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+const (
+	MBC6_FLASH_SIZE = 0x100000
+
+	// MBC6 splits both ROM and RAM into two independently banked halves
+	MBC6_ROM_BANK_SIZE   = 0x2000
+	MBC6_RAM_BANK_SIZE   = 0x1000
+	MBC6_FLASH_SECTOR    = 0x20000
+	MBC6_SELECT_FLASH    = 0x08
+	MBC6_FLASH_MAKER_ID  = 0xc2
+	MBC6_FLASH_DEVICE_ID = 0x81
+)
+
+// Flash command sequence states
+const (
+	FLASH_READ = iota
+	FLASH_UNLOCK1
+	FLASH_UNLOCK2
+	FLASH_PROGRAM
+	FLASH_ERASE_UNLOCK0
+	FLASH_ERASE_UNLOCK1
+	FLASH_ERASE_UNLOCK2
+	FLASH_ID
+)
+
+// MBC6, used by Net de Get. ROM and 1MB of flash share two 8KB windows
+// at 0x4000 and 0x6000, each with its own bank and source, and RAM is
+// split into two 4KB windows.
+type mbc6 struct {
+	rom, ram, flash []uint8
+
+	ramEnabled        bool
+	flashEnabled      bool
+	flashWriteEnabled bool
+
+	romBank  [2]uint8
+	useFlash [2]bool
+	ramBank  [2]uint8
+
+	flashState int
+}
+
+func newMbc6(rom, ram, flash []uint8) *mbc6 {
+	for i := range flash {
+		flash[i] = 0xff
+	}
+
+	return &mbc6{rom: rom, ram: ram, flash: flash}
+}
+
+func (m *mbc6) Read(addr uint16) uint8 {
+	switch {
+	case addr < ROM_BANK_SIZE:
+		return m.rom[addr]
+	case addr < VRAM_ADDR:
+		window := (addr - ROM_BANK_SIZE) / MBC6_ROM_BANK_SIZE
+		offset := int(addr % MBC6_ROM_BANK_SIZE)
+
+		if !m.useFlash[window] {
+			bank := int(m.romBank[window]) % (len(m.rom) / MBC6_ROM_BANK_SIZE)
+			return m.rom[bank*MBC6_ROM_BANK_SIZE+offset]
+		}
+
+		if !m.flashEnabled {
+			return 0xff
+		}
+
+		flashAddr := m.flashAddr(window, offset)
+		if m.flashState == FLASH_ID {
+			switch flashAddr & 0xff {
+			case 0:
+				return MBC6_FLASH_MAKER_ID
+			case 1:
+				return MBC6_FLASH_DEVICE_ID
+			}
+			return 0x00
+		}
+
+		return m.flash[flashAddr]
+	case addr >= EXT_RAM_ADDR && addr < WRAM_ADDR:
+		if !m.ramEnabled || len(m.ram) == 0 {
+			return 0xff
+		}
+
+		return m.ram[m.ramAddr(addr)]
+	}
+
+	return 0xff
+}
+
+func (m *mbc6) flashAddr(window uint16, offset int) int {
+	bank := int(m.romBank[window]) % (MBC6_FLASH_SIZE / MBC6_ROM_BANK_SIZE)
+	return bank*MBC6_ROM_BANK_SIZE + offset
+}
+
+func (m *mbc6) ramAddr(addr uint16) int {
+	window := (addr - EXT_RAM_ADDR) / MBC6_RAM_BANK_SIZE
+	offset := int(addr % MBC6_RAM_BANK_SIZE)
+
+	return (int(m.ramBank[window])*MBC6_RAM_BANK_SIZE + offset) % len(m.ram)
+}
+
+func (m *mbc6) Write(addr uint16, val uint8) {
+	switch {
+	case addr < 0x0400:
+		m.ramEnabled = val == 0x0a
+	case addr < 0x0800:
+		m.ramBank[0] = val & 0x07
+	case addr < 0x0c00:
+		m.ramBank[1] = val & 0x07
+	case addr < 0x1000:
+		m.flashEnabled = val&BIT_0 != 0
+	case addr < 0x2000:
+		if addr == 0x1000 {
+			m.flashWriteEnabled = val&BIT_0 != 0
+		}
+	case addr < 0x2800:
+		m.romBank[0] = val
+	case addr < 0x3000:
+		m.useFlash[0] = val == MBC6_SELECT_FLASH
+	case addr < 0x3800:
+		m.romBank[1] = val
+	case addr < 0x4000:
+		m.useFlash[1] = val == MBC6_SELECT_FLASH
+	case addr < VRAM_ADDR:
+		window := (addr - ROM_BANK_SIZE) / MBC6_ROM_BANK_SIZE
+		if m.useFlash[window] && m.flashEnabled {
+			m.writeFlash(m.flashAddr(window, int(addr%MBC6_ROM_BANK_SIZE)), val)
+		}
+	case addr >= EXT_RAM_ADDR && addr < WRAM_ADDR:
+		if m.ramEnabled && len(m.ram) > 0 {
+			m.ram[m.ramAddr(addr)] = val
+		}
+	}
+}
+
+// Steps the flash command state machine. Commands are unlocked by
+// writing 0xaa to 0x5555 then 0x55 to 0x2aaa.
+func (m *mbc6) writeFlash(flashAddr int, val uint8) {
+	cmdAddr := flashAddr & 0x7fff
+
+	if val == 0xf0 && m.flashState != FLASH_PROGRAM {
+		m.flashState = FLASH_READ
+		return
+	}
+
+	switch m.flashState {
+	case FLASH_READ, FLASH_ID:
+		if cmdAddr == 0x5555 && val == 0xaa {
+			m.flashState = FLASH_UNLOCK1
+		}
+	case FLASH_UNLOCK1:
+		m.flashState = FLASH_READ
+		if cmdAddr == 0x2aaa && val == 0x55 {
+			m.flashState = FLASH_UNLOCK2
+		}
+	case FLASH_UNLOCK2:
+		m.flashState = FLASH_READ
+		if cmdAddr != 0x5555 {
+			return
+		}
+
+		switch val {
+		case 0xa0:
+			m.flashState = FLASH_PROGRAM
+		case 0x80:
+			m.flashState = FLASH_ERASE_UNLOCK0
+		case 0x90:
+			m.flashState = FLASH_ID
+		}
+	case FLASH_PROGRAM:
+		// Programming can only clear bits
+		if m.flashWriteEnabled {
+			m.flash[flashAddr] &= val
+		}
+		m.flashState = FLASH_READ
+	case FLASH_ERASE_UNLOCK0:
+		m.flashState = FLASH_READ
+		if cmdAddr == 0x5555 && val == 0xaa {
+			m.flashState = FLASH_ERASE_UNLOCK1
+		}
+	case FLASH_ERASE_UNLOCK1:
+		m.flashState = FLASH_READ
+		if cmdAddr == 0x2aaa && val == 0x55 {
+			m.flashState = FLASH_ERASE_UNLOCK2
+		}
+	case FLASH_ERASE_UNLOCK2:
+		m.flashState = FLASH_READ
+		if !m.flashWriteEnabled {
+			return
+		}
+
+		switch {
+		case val == 0x30:
+			sector := flashAddr / MBC6_FLASH_SECTOR * MBC6_FLASH_SECTOR
+			m.erase(m.flash[sector : sector+MBC6_FLASH_SECTOR])
+		case val == 0x10 && cmdAddr == 0x5555:
+			m.erase(m.flash)
+		}
+	}
+}
+
+func (m *mbc6) erase(flash []uint8) {
+	for i := range flash {
+		flash[i] = 0xff
+	}
+}
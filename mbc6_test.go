@@ -0,0 +1,114 @@
+package main
+
+import (
+	"testing"
+)
+
+// Builds an MBC6 ROM with each 8KB bank tagged with its number
+func makeMbc6Rom() []uint8 {
+	rom := makeRom(0x20, 0x05, 0x03)
+	for bank := 0; bank < len(rom)/MBC6_ROM_BANK_SIZE; bank++ {
+		rom[bank*MBC6_ROM_BANK_SIZE+MBC6_ROM_BANK_SIZE-2] = uint8(bank)
+	}
+
+	fixChecksums(rom)
+	return rom
+}
+
+// Sends a flash command through the unlock sequence, with bank 2 mapped
+// at 0x4000 and bank 1 at 0x6000 so that 0x5555 and 0x2aaa are reachable
+func flashCommand(cart *Cartridge, cmd uint8) {
+	cart.Write(0x5555, 0xaa)
+	cart.Write(0x6aaa, 0x55)
+	cart.Write(0x5555, cmd)
+}
+
+func TestMbc6RomBanking(t *testing.T) {
+	cart := newTestCartridge(t, makeMbc6Rom())
+
+	cart.Write(0x2000, 0x05)
+	cart.Write(0x3000, 0x06)
+
+	if bank := cart.Read(0x5ffe); bank != 5 {
+		t.Errorf("Expected bank 5 at 0x4000, got %d", bank)
+	}
+	if bank := cart.Read(0x7ffe); bank != 6 {
+		t.Errorf("Expected bank 6 at 0x6000, got %d", bank)
+	}
+}
+
+func TestMbc6Ram(t *testing.T) {
+	cart := newTestCartridge(t, makeMbc6Rom())
+
+	cart.Write(0x0000, 0x0a)
+	cart.Write(0x0400, 0x03)
+	cart.Write(0x0800, 0x05)
+	cart.Write(0xa000, 0x33)
+	cart.Write(0xb000, 0x55)
+
+	if val := cart.ram[3*MBC6_RAM_BANK_SIZE]; val != 0x33 {
+		t.Errorf("Expected 0x33 in RAM bank 3, got %#02x", val)
+	}
+	if val := cart.ram[5*MBC6_RAM_BANK_SIZE]; val != 0x55 {
+		t.Errorf("Expected 0x55 in RAM bank 5, got %#02x", val)
+	}
+
+	// Flash is saved along with the RAM
+	if size := len(cart.ExportSave()); size != 0x8000+MBC6_FLASH_SIZE {
+		t.Errorf("Expected %d bytes of save data, got %d", 0x8000+MBC6_FLASH_SIZE, size)
+	}
+}
+
+func TestMbc6Flash(t *testing.T) {
+	cart := newTestCartridge(t, makeMbc6Rom())
+
+	cart.Write(0x2000, 0x02)
+	cart.Write(0x2800, MBC6_SELECT_FLASH)
+	cart.Write(0x3000, 0x01)
+	cart.Write(0x3800, MBC6_SELECT_FLASH)
+
+	if val := cart.Read(0x4010); val != 0xff {
+		t.Errorf("Expected disabled flash to read 0xff, got %#02x", val)
+	}
+
+	cart.Write(0x0c00, 0x01)
+
+	// Programming needs writes enabled
+	flashCommand(cart, 0xa0)
+	cart.Write(0x4010, 0x5a)
+	if val := cart.Read(0x4010); val != 0xff {
+		t.Errorf("Expected write protected flash to be unchanged, got %#02x", val)
+	}
+
+	cart.Write(0x1000, 0x01)
+	flashCommand(cart, 0xa0)
+	cart.Write(0x4010, 0x5a)
+	if val := cart.Read(0x4010); val != 0x5a {
+		t.Errorf("Expected 0x5a to be programmed, got %#02x", val)
+	}
+
+	// Only an erase sets bits back
+	flashCommand(cart, 0xa0)
+	cart.Write(0x4010, 0xf0)
+	if val := cart.Read(0x4010); val != 0x50 {
+		t.Errorf("Expected programming to clear bits only, got %#02x", val)
+	}
+
+	flashCommand(cart, 0x90)
+	if maker, device := cart.Read(0x4000), cart.Read(0x4001); maker != MBC6_FLASH_MAKER_ID || device != MBC6_FLASH_DEVICE_ID {
+		t.Errorf("Expected flash IDs %#02x %#02x, got %#02x %#02x", MBC6_FLASH_MAKER_ID, MBC6_FLASH_DEVICE_ID, maker, device)
+	}
+
+	cart.Write(0x4000, 0xf0)
+	if val := cart.Read(0x4010); val != 0x50 {
+		t.Errorf("Expected reset to leave ID mode, got %#02x", val)
+	}
+
+	flashCommand(cart, 0x80)
+	cart.Write(0x5555, 0xaa)
+	cart.Write(0x6aaa, 0x55)
+	cart.Write(0x4010, 0x30)
+	if val := cart.Read(0x4010); val != 0xff {
+		t.Errorf("Expected the sector to be erased, got %#02x", val)
+	}
+}
@@ -0,0 +1,282 @@
+package main
+
+const (
+	// Accelerometer reading when level, and the change for 1g of tilt
+	MBC7_ACCEL_CENTER = 0x81d0
+	MBC7_ACCEL_1G     = 0x70
+
+	// Bits of the EEPROM register
+	EEPROM_DO  = BIT_0
+	EEPROM_DI  = BIT_1
+	EEPROM_CLK = BIT_6
+	EEPROM_CS  = BIT_7
+)
+
+// MBC7, found in tilt controlled games. Instead of RAM it maps an
+// accelerometer and a serial EEPROM as registers at 0xa000-0xafff.
+type mbc7 struct {
+	rom    []uint8
+	eeprom *eeprom93lc56
+
+	ramEnabled1, ramEnabled2 bool
+	romBank                  uint8
+
+	// Tilt set by the host, in g, and the latched sensor readings
+	tiltX, tiltY     float64
+	accelX, accelY   uint16
+	accelLatchPrimed bool // 0x55 was written, 0xaa latches
+}
+
+func newMbc7(rom, eeprom []uint8) *mbc7 {
+	for i := range eeprom {
+		eeprom[i] = 0xff
+	}
+
+	return &mbc7{
+		rom:     rom,
+		eeprom:  &eeprom93lc56{data: eeprom, do: true},
+		romBank: 1,
+		accelX:  0x8000,
+		accelY:  0x8000,
+	}
+}
+
+func (m *mbc7) Read(addr uint16) uint8 {
+	switch {
+	case addr < ROM_BANK_SIZE:
+		return romByte(m.rom, 0, addr)
+	case addr < VRAM_ADDR:
+		return romByte(m.rom, int(m.romBank), addr-ROM_BANK_SIZE)
+	case addr >= EXT_RAM_ADDR && addr < 0xb000:
+		if !m.ramEnabled1 || !m.ramEnabled2 {
+			return 0xff
+		}
+
+		return m.readRegister(addr >> 4 & 0x0f)
+	}
+
+	return 0xff
+}
+
+func (m *mbc7) readRegister(reg uint16) uint8 {
+	switch reg {
+	case 0x2:
+		return uint8(m.accelX)
+	case 0x3:
+		return uint8(m.accelX >> 8)
+	case 0x4:
+		return uint8(m.accelY)
+	case 0x5:
+		return uint8(m.accelY >> 8)
+	case 0x6:
+		return 0x00
+	case 0x8:
+		return m.eeprom.read()
+	}
+
+	return 0xff
+}
+
+func (m *mbc7) Write(addr uint16, val uint8) {
+	switch {
+	case addr < 0x2000:
+		m.ramEnabled1 = val == 0x0a
+	case addr < 0x4000:
+		m.romBank = val
+	case addr < 0x6000:
+		m.ramEnabled2 = val == 0x40
+	case addr >= EXT_RAM_ADDR && addr < 0xb000:
+		if m.ramEnabled1 && m.ramEnabled2 {
+			m.writeRegister(addr>>4&0x0f, val)
+		}
+	}
+}
+
+func (m *mbc7) writeRegister(reg uint16, val uint8) {
+	switch reg {
+	case 0x0:
+		if val == 0x55 {
+			m.accelX, m.accelY = 0x8000, 0x8000
+			m.accelLatchPrimed = true
+		}
+	case 0x1:
+		if val == 0xaa && m.accelLatchPrimed {
+			m.accelX = uint16(MBC7_ACCEL_CENTER + m.tiltX*MBC7_ACCEL_1G)
+			m.accelY = uint16(MBC7_ACCEL_CENTER + m.tiltY*MBC7_ACCEL_1G)
+			m.accelLatchPrimed = false
+		}
+	case 0x8:
+		m.eeprom.write(val)
+	}
+}
+
+// Size of the 93LC56 EEPROM, 128 words of 16 bits
+const EEPROM_SIZE = 0x100
+
+// EEPROM opcodes, following the start bit. The commands sharing opcode
+// 0 are told apart by the top 2 address bits.
+const (
+	EEPROM_READ  = 0x2
+	EEPROM_WRITE = 0x1
+	EEPROM_ERASE = 0x3
+	EEPROM_MISC  = 0x0 // EWEN, EWDS, ERAL and WRAL, told apart by address
+
+	EEPROM_EWDS = 0x0
+	EEPROM_WRAL = 0x1
+	EEPROM_ERAL = 0x2
+	EEPROM_EWEN = 0x3
+)
+
+// Microwire serial EEPROM. Bits are shifted in on the rising edge of
+// CLK while CS is high: a start bit, a 2-bit opcode, an 8-bit address
+// and, for writes, 16 bits of data.
+type eeprom93lc56 struct {
+	data []uint8 // Little endian words
+
+	cs, clk, di, do bool
+	writeEnabled    bool
+
+	shift   uint32 // Bits received so far, start bit included
+	bits    int
+	command uint32 // Opcode and address, once received
+	reading bool
+	out     uint16 // Word being shifted out
+	outBits int
+}
+
+func (e *eeprom93lc56) read() uint8 {
+	var val uint8
+	if e.do {
+		val |= EEPROM_DO
+	}
+	if e.di {
+		val |= EEPROM_DI
+	}
+	if e.clk {
+		val |= EEPROM_CLK
+	}
+	if e.cs {
+		val |= EEPROM_CS
+	}
+
+	return val
+}
+
+func (e *eeprom93lc56) write(val uint8) {
+	cs := val&EEPROM_CS != 0
+	clk := val&EEPROM_CLK != 0
+	e.di = val&EEPROM_DI != 0
+
+	if !cs {
+		// Deselecting aborts whatever command was in progress
+		e.cs, e.clk = false, clk
+		e.shift, e.bits, e.reading = 0, 0, false
+		e.do = true
+		return
+	}
+
+	rising := clk && !e.clk
+	e.cs, e.clk = true, clk
+	if rising {
+		e.clock()
+	}
+}
+
+// Handles a rising edge of the clock
+func (e *eeprom93lc56) clock() {
+	if e.reading {
+		e.do = e.out&0x8000 != 0
+		e.out <<= 1
+		e.outBits--
+
+		// Reads continue with the next word
+		if e.outBits == 0 {
+			e.command = e.command&^0x7f | (e.command+1)&0x7f
+			e.out, e.outBits = e.word(int(e.command&0x7f)), 16
+		}
+		return
+	}
+
+	// Wait for the start bit
+	if e.bits == 0 && !e.di {
+		return
+	}
+
+	e.shift = e.shift<<1 | uint32(flag(e.di))
+	e.bits++
+
+	switch e.bits {
+	case 11:
+		e.command = e.shift & 0x3ff
+		e.execute()
+	case 27:
+		e.executeWrite(uint16(e.shift))
+	}
+}
+
+// Runs a command once its opcode and address have been received
+func (e *eeprom93lc56) execute() {
+	addr := int(e.command & 0x7f)
+
+	switch e.command >> 8 {
+	case EEPROM_READ:
+		// A dummy 0 precedes the data
+		e.reading = true
+		e.do = false
+		e.out, e.outBits = e.word(addr), 16
+		return
+	case EEPROM_WRITE:
+		// Waits for the data
+		return
+	case EEPROM_ERASE:
+		if e.writeEnabled {
+			e.setWord(addr, 0xffff)
+		}
+	case EEPROM_MISC:
+		switch e.command >> 6 & 0x3 {
+		case EEPROM_EWEN:
+			e.writeEnabled = true
+		case EEPROM_EWDS:
+			e.writeEnabled = false
+		case EEPROM_ERAL:
+			if e.writeEnabled {
+				for i := 0; i < EEPROM_SIZE/2; i++ {
+					e.setWord(i, 0xffff)
+				}
+			}
+		case EEPROM_WRAL:
+			return
+		}
+	}
+
+	e.done()
+}
+
+func (e *eeprom93lc56) executeWrite(data uint16) {
+	if e.writeEnabled {
+		if e.command>>8 == EEPROM_WRITE {
+			e.setWord(int(e.command&0x7f), data)
+		} else {
+			for i := 0; i < EEPROM_SIZE/2; i++ {
+				e.setWord(i, data)
+			}
+		}
+	}
+
+	e.done()
+}
+
+// Signals the command completed and waits for the next one
+func (e *eeprom93lc56) done() {
+	e.shift, e.bits = 0, 0
+	e.do = true
+}
+
+func (e *eeprom93lc56) word(addr int) uint16 {
+	return uint16(e.data[addr*2]) | uint16(e.data[addr*2+1])<<8
+}
+
+func (e *eeprom93lc56) setWord(addr int, val uint16) {
+	e.data[addr*2] = uint8(val)
+	e.data[addr*2+1] = uint8(val >> 8)
+}
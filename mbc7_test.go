@@ -0,0 +1,115 @@
+package main
+
+import (
+	"testing"
+)
+
+func newMbc7Cartridge(t *testing.T) *Cartridge {
+	cart := newTestCartridge(t, makeRom(0x22, 0x04, 0x00))
+	cart.Write(0x0000, 0x0a)
+	cart.Write(0x4000, 0x40)
+
+	return cart
+}
+
+// Shifts bits into the EEPROM, most significant first
+func eepromSend(cart *Cartridge, bits uint32, count int) {
+	for i := count - 1; i >= 0; i-- {
+		di := uint8(0)
+		if bits>>i&1 != 0 {
+			di = EEPROM_DI
+		}
+
+		cart.Write(0xa080, EEPROM_CS|di)
+		cart.Write(0xa080, EEPROM_CS|EEPROM_CLK|di)
+	}
+}
+
+func eepromReceive(cart *Cartridge, count int) uint16 {
+	var val uint16
+	for i := 0; i < count; i++ {
+		cart.Write(0xa080, EEPROM_CS)
+		cart.Write(0xa080, EEPROM_CS|EEPROM_CLK)
+		val = val<<1 | uint16(cart.Read(0xa080)&EEPROM_DO)
+	}
+
+	return val
+}
+
+// Sends a start bit, an opcode and an address, after selecting the chip
+func eepromCommand(cart *Cartridge, opcode, addr uint8) {
+	cart.Write(0xa080, 0x00)
+	eepromSend(cart, 1<<10|uint32(opcode)<<8|uint32(addr), 11)
+}
+
+func TestMbc7Accelerometer(t *testing.T) {
+	cart := newMbc7Cartridge(t)
+	cart.SetAccelerometer(0.5, -1)
+
+	// 0xaa alone does not latch
+	cart.Write(0xa010, 0xaa)
+	if x := uint16(cart.Read(0xa030))<<8 | uint16(cart.Read(0xa020)); x != 0x8000 {
+		t.Errorf("Expected X to be unlatched, got %#04x", x)
+	}
+
+	cart.Write(0xa000, 0x55)
+	cart.Write(0xa010, 0xaa)
+
+	if x := uint16(cart.Read(0xa030))<<8 | uint16(cart.Read(0xa020)); x != 0x8208 {
+		t.Errorf("Expected X 0x8208, got %#04x", x)
+	}
+	if y := uint16(cart.Read(0xa050))<<8 | uint16(cart.Read(0xa040)); y != 0x8160 {
+		t.Errorf("Expected Y 0x8160, got %#04x", y)
+	}
+
+	// Both enables are needed
+	cart.Write(0x4000, 0x00)
+	if val := cart.Read(0xa020); val != 0xff {
+		t.Errorf("Expected disabled registers to read 0xff, got %#02x", val)
+	}
+}
+
+func TestMbc7Eeprom(t *testing.T) {
+	cart := newMbc7Cartridge(t)
+
+	// Writes are ignored until enabled
+	eepromCommand(cart, EEPROM_WRITE, 0x05)
+	eepromSend(cart, 0x1234, 16)
+	if cart.ram[10] != 0xff || cart.ram[11] != 0xff {
+		t.Errorf("Expected a write protected word, got %#02x%02x", cart.ram[11], cart.ram[10])
+	}
+
+	eepromCommand(cart, EEPROM_MISC, EEPROM_EWEN<<6)
+	eepromCommand(cart, EEPROM_WRITE, 0x05)
+	eepromSend(cart, 0x1234, 16)
+	if cart.ram[10] != 0x34 || cart.ram[11] != 0x12 {
+		t.Errorf("Expected 0x1234 stored little endian, got %#02x%02x", cart.ram[11], cart.ram[10])
+	}
+
+	eepromCommand(cart, EEPROM_READ, 0x05)
+	if val := cart.Read(0xa080) & EEPROM_DO; val != 0 {
+		t.Error("Expected a dummy 0 before the data")
+	}
+	if val := eepromReceive(cart, 16); val != 0x1234 {
+		t.Errorf("Expected to read 0x1234, got %#04x", val)
+	}
+	// Reads carry on with the next word
+	if val := eepromReceive(cart, 16); val != 0xffff {
+		t.Errorf("Expected to read 0xffff from the next word, got %#04x", val)
+	}
+
+	eepromCommand(cart, EEPROM_ERASE, 0x05)
+	if cart.ram[10] != 0xff || cart.ram[11] != 0xff {
+		t.Errorf("Expected an erased word, got %#02x%02x", cart.ram[11], cart.ram[10])
+	}
+
+	eepromCommand(cart, EEPROM_MISC, EEPROM_WRAL<<6)
+	eepromSend(cart, 0xbeef, 16)
+	eepromCommand(cart, EEPROM_MISC, EEPROM_EWDS<<6)
+	eepromCommand(cart, EEPROM_MISC, EEPROM_ERAL<<6)
+	for addr := 0; addr < EEPROM_SIZE; addr += 2 {
+		if cart.ram[addr] != 0xef || cart.ram[addr+1] != 0xbe {
+			t.Fatalf("Expected 0xbeef in word %d, got %#02x%02x", addr/2, cart.ram[addr+1], cart.ram[addr])
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+// MMM01, used by multicarts. It starts out mapping the menu in the last
+// 32KB of the ROM, which selects a game by setting the outer bank bits
+// and masks, then locks the mapping. From then on it behaves as an MBC1
+// confined to that game.
+type mmm01 struct {
+	rom, ram []uint8
+
+	locked     bool
+	ramEnabled bool
+
+	romBankLo   uint8 // 5 bits, selected by the game
+	romBankMid  uint8 // 2 bits, fixed once locked
+	romBankHi   uint8 // 2 bits, fixed once locked
+	romBankMask uint8 // Bits of romBankLo fixed once locked
+	ramBankLo   uint8 // 2 bits, selected by the game
+	ramBankHi   uint8 // 2 bits, fixed once locked
+
+	mode          uint8
+	modeWriteLock bool // Keeps the game from changing the mode
+}
+
+func newMmm01(rom, ram []uint8) *mmm01 {
+	return &mmm01{rom: rom, ram: ram}
+}
+
+// Outer ROM bank bits selecting the game
+func (m *mmm01) romBase() int {
+	return int(m.romBankHi)<<7 | int(m.romBankMid)<<5
+}
+
+func (m *mmm01) Read(addr uint16) uint8 {
+	menuBank := len(m.rom)/ROM_BANK_SIZE - 2
+
+	switch {
+	case addr < ROM_BANK_SIZE:
+		if !m.locked {
+			return romByte(m.rom, menuBank, addr)
+		}
+
+		bank := m.romBase() | int(m.romBankLo&m.romBankMask)
+		return romByte(m.rom, bank, addr)
+	case addr < VRAM_ADDR:
+		if !m.locked {
+			return romByte(m.rom, menuBank+1, addr-ROM_BANK_SIZE)
+		}
+
+		return romByte(m.rom, m.romBase()|int(m.romBankLo), addr-ROM_BANK_SIZE)
+	case addr >= EXT_RAM_ADDR && addr < WRAM_ADDR:
+		if !m.ramEnabled || len(m.ram) == 0 {
+			return 0xff
+		}
+
+		return m.ram[ramOffset(m.ram, m.ramBank(), addr)]
+	}
+
+	return 0xff
+}
+
+func (m *mmm01) ramBank() int {
+	bank := int(m.ramBankHi) << 2
+	if m.mode == 1 {
+		bank |= int(m.ramBankLo)
+	}
+
+	return bank
+}
+
+func (m *mmm01) Write(addr uint16, val uint8) {
+	switch {
+	case addr < 0x2000:
+		m.ramEnabled = val&0x0f == 0x0a
+		if !m.locked {
+			m.locked = val&BIT_6 != 0
+		}
+	case addr < 0x4000:
+		// Bank 0 maps bank 1, within the bits the game can select
+		low := val & 0x1f
+		if low&^m.romBankMask == 0 {
+			low |= 1
+		}
+
+		if m.locked {
+			m.romBankLo = m.romBankLo&m.romBankMask | low&^m.romBankMask
+		} else {
+			m.romBankLo = low
+			m.romBankMid = val >> 5 & 0x03
+		}
+	case addr < 0x6000:
+		m.ramBankLo = val & 0x03
+		if !m.locked {
+			m.ramBankHi = val >> 2 & 0x03
+			m.romBankHi = val >> 4 & 0x03
+			m.modeWriteLock = val&BIT_6 != 0
+		}
+	case addr < VRAM_ADDR:
+		if !m.modeWriteLock {
+			m.mode = val & 0x01
+		}
+		if !m.locked {
+			// The mask covers bits 1-4 of the ROM bank
+			m.romBankMask = val & 0x3c >> 1
+		}
+	case addr >= EXT_RAM_ADDR && addr < WRAM_ADDR:
+		if m.ramEnabled && len(m.ram) > 0 {
+			m.ram[ramOffset(m.ram, m.ramBank(), addr)] = val
+		}
+	}
+}
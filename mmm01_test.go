@@ -0,0 +1,112 @@
+package main
+
+import (
+	"testing"
+)
+
+// Builds a 4MB MMM01 multicart, with the menu header in the last 32KB
+// and an MBC1 game header in the first bank
+func makeMmm01Rom() []uint8 {
+	rom := makeRom(0x01, 0x07, 0x00)
+
+	base := len(rom) - 2*ROM_BANK_SIZE
+	copy(rom[base:base+HEADER_END], rom[:HEADER_END])
+	rom[base+HEADER_TYPE] = 0x0d
+	rom[base+HEADER_RAM_SIZE] = 0x03
+
+	fixChecksums(rom)
+	return rom
+}
+
+func TestMmm01Header(t *testing.T) {
+	cart := newTestCartridge(t, makeMmm01Rom())
+
+	if cart.Header.Type != 0x0d || cart.Header.RamSize != 0x8000 {
+		t.Errorf("Expected the menu header, got type %#02x with %d bytes of RAM", cart.Header.Type, cart.Header.RamSize)
+	}
+}
+
+func TestMmm01RomBanking(t *testing.T) {
+	for _, tt := range []struct {
+		testName      string
+		writes        []mbcWrite
+		expectedBank0 uint8
+		expectedBank1 uint8
+	}{
+		{
+			testName:      "Power on maps the menu",
+			expectedBank0: 254,
+			expectedBank1: 255,
+		},
+		{
+			testName:      "Banking is ignored until locked",
+			writes:        []mbcWrite{{0x2000, 0x05}},
+			expectedBank0: 254,
+			expectedBank1: 255,
+		},
+		{
+			testName:      "Locking maps the selected game",
+			writes:        []mbcWrite{{0x6000, 0x30}, {0x2000, 0x20}, {0x0000, 0x40}},
+			expectedBank0: 0x20,
+			expectedBank1: 0x21,
+		},
+		{
+			testName:      "The game selects banks within its 128KB",
+			writes:        []mbcWrite{{0x6000, 0x30}, {0x2000, 0x20}, {0x0000, 0x40}, {0x2000, 0x05}},
+			expectedBank0: 0x20,
+			expectedBank1: 0x25,
+		},
+		{
+			testName:      "Masked bits cannot be changed by the game",
+			writes:        []mbcWrite{{0x6000, 0x30}, {0x2000, 0x20}, {0x0000, 0x40}, {0x2000, 0x7f}},
+			expectedBank0: 0x20,
+			expectedBank1: 0x27,
+		},
+		{
+			testName:      "Bank 0 maps bank 1 of the game",
+			writes:        []mbcWrite{{0x6000, 0x30}, {0x2000, 0x20}, {0x0000, 0x40}, {0x2000, 0x05}, {0x2000, 0x00}},
+			expectedBank0: 0x20,
+			expectedBank1: 0x21,
+		},
+		{
+			testName:      "Upper bits select games past 2MB",
+			writes:        []mbcWrite{{0x4000, 0x10}, {0x2000, 0x42}, {0x0000, 0x40}},
+			expectedBank0: 0xc0,
+			expectedBank1: 0xc2,
+		},
+	} {
+		t.Log(tt.testName)
+
+		cart := newTestCartridge(t, makeMmm01Rom())
+		for _, w := range tt.writes {
+			cart.Write(w.addr, w.val)
+		}
+
+		if bank := cart.Read(0x3fff); bank != tt.expectedBank0 {
+			t.Errorf("%s: expected bank %#02x at 0x0000, got %#02x", tt.testName, tt.expectedBank0, bank)
+		}
+		if bank := cart.Read(0x7fff); bank != tt.expectedBank1 {
+			t.Errorf("%s: expected bank %#02x at 0x4000, got %#02x", tt.testName, tt.expectedBank1, bank)
+		}
+	}
+}
+
+func TestMmm01Ram(t *testing.T) {
+	cart := newTestCartridge(t, makeMmm01Rom())
+
+	// The menu picks the upper RAM bank bits, the game the lower ones
+	cart.Write(0x4000, 0x04)
+	cart.Write(0x0000, 0x4a)
+	cart.Write(0x6000, 0x01)
+	cart.Write(0x4000, 0x02)
+	cart.Write(0xa000, 0x66)
+
+	if val := cart.ram[ramOffset(cart.ram, 0x06, 0xa000)]; val != 0x66 {
+		t.Errorf("Expected the write in RAM bank 6, got %#02x there", val)
+	}
+
+	cart.Write(0x0000, 0x00)
+	if val := cart.Read(0xa000); val != 0xff {
+		t.Errorf("Expected disabled RAM to read 0xff, got %#02x", val)
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+// Memory map boundaries
+const (
+	ROM_ADDR      = 0x0000 // Cartridge ROM
+	VRAM_ADDR     = 0x8000
+	EXT_RAM_ADDR  = 0xa000 // Cartridge RAM
+	WRAM_ADDR     = 0xc000
+	ECHO_ADDR     = 0xe000 // Mirror of 0xc000-0xddff
+	OAM_ADDR      = 0xfe00
+	UNUSABLE_ADDR = 0xfea0
+	IO_ADDR       = 0xff00
+	HRAM_ADDR     = 0xff80
+
+	VRAM_SIZE = 0x2000
+	WRAM_SIZE = 0x2000
+	OAM_SIZE  = 0xa0
+	IO_SIZE   = 0x80
+	HRAM_SIZE = 0x7f
+
+	// Joypad. Bits 4-5 select the button groups read in bits 0-3, where
+	// 0 means pressed.
+	P1_ADDR = 0xff00
+)
+
+// Bits of each I/O register that are not backed by anything and read
+// as 1. Write-only registers and unmapped addresses read as 0xff. With no
+// joypad attached, no button of P1 reads as pressed.
+var ioUnusedBits = [IO_SIZE]uint8{
+	// P1, SB, SC, -, DIV, TIMA, TMA, TAC, -, -, -, -, -, -, -, IF
+	0xcf, 0x00, 0x7e, 0xff, 0x00, 0x00, 0x00, 0xf8, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xe0,
+	// NR10-NR14, -, NR21-NR24, NR30-NR34, -
+	0x80, 0x3f, 0x00, 0xff, 0xbf, 0xff, 0x3f, 0x00, 0xff, 0xbf, 0x7f, 0xff, 0x9f, 0xff, 0xbf, 0xff,
+	// NR41-NR44, NR50-NR52, -
+	0xff, 0x00, 0x00, 0xbf, 0x00, 0x00, 0x70, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+	// Wave RAM
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	// LCDC, STAT, SCY, SCX, LY, LYC, DMA, BGP, OBP0, OBP1, WY, WX, -, -, -, -
+	0x00, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xff, 0xff, 0xff, 0xff,
+	0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+	0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+	0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+}
+
+// The memory map. Cartridge ROM and RAM are delegated to the cartridge;
+// everything else is owned here. In CGB mode VRAM and WRAM are banked
+// and palette RAM is added.
+type Mmu struct {
+	cart    Mem     // Nil when no cartridge is inserted
+	bootRom []uint8 // Nil once unmapped, or when booting without one
+	cgb     bool
+
+	vram [VRAM_BANKS * VRAM_SIZE]uint8
+	wram [WRAM_BANKS * WRAM_BANK_SIZE]uint8
+	oam  [OAM_SIZE]uint8
+	io   [IO_SIZE]uint8
+	hram [HRAM_SIZE]uint8
+	ie   uint8
+
+	bgPalettes, objPalettes paletteRam
+
+	dma dma
+	ppu *Ppu // Restricts access to VRAM and OAM and sees STAT writes, when attached
+}
+
+func NewMmu(cart Mem) *Mmu {
+	return &Mmu{cart: cart}
+}
+
+func (m *Mmu) Read(addr uint16) uint8 {
+	// A running DMA drives the bus, and OAM reads back as 0xff
+	if m.dmaBlocks(addr) {
+		if busOf(addr) == BUS_OAM {
+			return 0xff
+		}
+
+		return m.dma.last
+	}
+
+	// VRAM and OAM read back as 0xff while the PPU uses them
+	if m.ppu != nil && m.ppu.blocks(addr) {
+		return 0xff
+	}
+
+	return m.read(addr)
+}
+
+// Reads memory regardless of DMA
+func (m *Mmu) read(addr uint16) uint8 {
+	switch {
+	case m.bootRomMapped(addr):
+		return m.bootRom[addr]
+	case addr < VRAM_ADDR:
+		return m.readCart(addr)
+	case addr < EXT_RAM_ADDR:
+		return m.vram[m.vramOffset(addr)]
+	case addr < WRAM_ADDR:
+		return m.readCart(addr)
+	case addr < ECHO_ADDR:
+		return m.wram[m.wramOffset(addr)]
+	case addr < OAM_ADDR:
+		return m.wram[m.wramOffset(addr-(ECHO_ADDR-WRAM_ADDR))]
+	case addr < UNUSABLE_ADDR:
+		return m.oam[addr-OAM_ADDR]
+	case addr < IO_ADDR:
+		// Reads 0x00 on DMG while OAM is accessible
+		return 0x00
+	case addr < HRAM_ADDR:
+		if val, ok := m.readCgbIo(addr); ok {
+			return val
+		}
+
+		return m.io[addr-IO_ADDR] | ioUnusedBits[addr-IO_ADDR]
+	case addr < IE_ADDR:
+		return m.hram[addr-HRAM_ADDR]
+	}
+
+	return m.ie
+}
+
+func (m *Mmu) Write(addr uint16, val uint8) {
+	// Writes are lost while DMA drives the bus
+	if m.dmaBlocks(addr) {
+		return
+	}
+
+	// As are writes to VRAM and OAM while the PPU uses them
+	if m.ppu != nil && m.ppu.blocks(addr) {
+		return
+	}
+
+	switch {
+	case addr < VRAM_ADDR:
+		m.writeCart(addr, val)
+	case addr < EXT_RAM_ADDR:
+		m.vram[m.vramOffset(addr)] = val
+	case addr < WRAM_ADDR:
+		m.writeCart(addr, val)
+	case addr < ECHO_ADDR:
+		m.wram[m.wramOffset(addr)] = val
+	case addr < OAM_ADDR:
+		m.wram[m.wramOffset(addr-(ECHO_ADDR-WRAM_ADDR))] = val
+	case addr < UNUSABLE_ADDR:
+		m.oam[addr-OAM_ADDR] = val
+	case addr < IO_ADDR:
+		// Writes to the unusable region are ignored
+	case addr < HRAM_ADDR:
+		if m.writeCgbIo(addr, val) {
+			return
+		}
+
+		switch {
+		case addr == BOOT_ADDR && val&BIT_0 != 0:
+			m.unmapBootRom()
+		case addr == DMA_ADDR:
+			m.startDma(val)
+		case addr == P1_ADDR:
+			// Only the button group selection is writable
+			val &= BIT_5 | BIT_4
+		case addr == LY_ADDR:
+			// Only the PPU sets LY
+			return
+		case addr == STAT_ADDR:
+			// The mode and coincidence bits are read-only
+			val = val&^0x07 | m.io[STAT_ADDR-IO_ADDR]&0x07
+			m.io[addr-IO_ADDR] = val
+			if m.ppu != nil {
+				m.ppu.writeStat()
+			}
+			return
+		}
+		m.io[addr-IO_ADDR] = val
+	case addr < IE_ADDR:
+		m.hram[addr-HRAM_ADDR] = val
+	default:
+		m.ie = val
+	}
+}
+
+// The data bus floats high when no cartridge drives it
+func (m *Mmu) readCart(addr uint16) uint8 {
+	if m.cart == nil {
+		return 0xff
+	}
+
+	return m.cart.Read(addr)
+}
+
+func (m *Mmu) writeCart(addr uint16, val uint8) {
+	if m.cart != nil {
+		m.cart.Write(addr, val)
+	}
+}
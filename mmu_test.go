@@ -0,0 +1,149 @@
+package main
+
+import (
+	"testing"
+)
+
+// Flat memory standing in for a cartridge
+type cartStub struct {
+	memory [1 << 16]uint8
+}
+
+func (c *cartStub) Read(addr uint16) uint8 {
+	return c.memory[addr]
+}
+
+func (c *cartStub) Write(addr uint16, val uint8) {
+	c.memory[addr] = val
+}
+
+func TestMmuRegions(t *testing.T) {
+	for _, tt := range []struct {
+		testName string
+		addr     uint16
+	}{
+		{testName: "ROM bank 0", addr: 0x0000},
+		{testName: "ROM bank 1", addr: 0x7fff},
+		{testName: "VRAM", addr: 0x8000},
+		{testName: "VRAM end", addr: 0x9fff},
+		{testName: "External RAM", addr: 0xa000},
+		{testName: "WRAM", addr: 0xc000},
+		{testName: "WRAM end", addr: 0xdfff},
+		{testName: "OAM", addr: 0xfe00},
+		{testName: "OAM end", addr: 0xfe9f},
+		{testName: "HRAM", addr: 0xff80},
+		{testName: "HRAM end", addr: 0xfffe},
+		{testName: "IE", addr: IE_ADDR},
+	} {
+		t.Log(tt.testName)
+
+		cart := &cartStub{}
+		m := NewMmu(cart)
+
+		m.Write(tt.addr, 0x5a)
+		if val := m.Read(tt.addr); val != 0x5a {
+			t.Errorf("%s: expected %#02x, got %#02x", tt.testName, 0x5a, val)
+		}
+	}
+}
+
+func TestMmuCartridge(t *testing.T) {
+	cart := &cartStub{}
+	m := NewMmu(cart)
+
+	for _, addr := range []uint16{0x0000, 0x4000, 0x7fff, 0xa000, 0xbfff} {
+		m.Write(addr, 0x42)
+		if cart.memory[addr] != 0x42 {
+			t.Errorf("%#04x: expected write to reach the cartridge", addr)
+		}
+	}
+
+	cart.memory[0x0100] = 0x00
+	cart.memory[0xb000] = 0x99
+	if m.Read(0x0100) != 0x00 || m.Read(0xb000) != 0x99 {
+		t.Errorf("Expected reads to come from the cartridge")
+	}
+
+	// Nothing else is forwarded to the cartridge
+	m.Write(0x8000, 0x11)
+	m.Write(0xc000, 0x11)
+	if cart.memory[0x8000] != 0 || cart.memory[0xc000] != 0 {
+		t.Errorf("Expected VRAM and WRAM writes not to reach the cartridge")
+	}
+}
+
+func TestMmuNoCartridge(t *testing.T) {
+	m := NewMmu(nil)
+
+	for _, addr := range []uint16{0x0000, 0x7fff, 0xa000, 0xbfff} {
+		m.Write(addr, 0x00)
+		if val := m.Read(addr); val != 0xff {
+			t.Errorf("%#04x: expected open bus 0xff, got %#02x", addr, val)
+		}
+	}
+}
+
+func TestMmuEcho(t *testing.T) {
+	m := NewMmu(nil)
+
+	m.Write(0xc000, 0x12)
+	if val := m.Read(0xe000); val != 0x12 {
+		t.Errorf("Expected echo RAM to mirror WRAM, got %#02x", val)
+	}
+
+	m.Write(0xfdff, 0x34)
+	if val := m.Read(0xddff); val != 0x34 {
+		t.Errorf("Expected echo RAM writes to reach WRAM, got %#02x", val)
+	}
+}
+
+func TestMmuUnusable(t *testing.T) {
+	m := NewMmu(nil)
+
+	for addr := uint16(UNUSABLE_ADDR); addr < IO_ADDR; addr++ {
+		m.Write(addr, 0xff)
+		if val := m.Read(addr); val != 0x00 {
+			t.Fatalf("%#04x: expected 0x00, got %#02x", addr, val)
+		}
+	}
+
+	// Writes do not spill into OAM or I/O
+	if m.oam[OAM_SIZE-1] != 0 || m.io[0] != 0 {
+		t.Errorf("Expected writes to the unusable region to be dropped")
+	}
+}
+
+func TestMmuIoReadBack(t *testing.T) {
+	for _, tt := range []struct {
+		testName string
+		addr     uint16
+		write    uint8
+		expected uint8
+	}{
+		{testName: "P1", addr: 0xff00, write: 0x00, expected: 0xcf},
+		{testName: "P1 selecting the buttons", addr: 0xff00, write: 0x10, expected: 0xdf},
+		{testName: "P1 selecting the d-pad", addr: 0xff00, write: 0x20, expected: 0xef},
+		{testName: "P1 selecting neither group", addr: 0xff00, write: 0x30, expected: 0xff},
+		{testName: "SC", addr: 0xff02, write: 0x81, expected: 0xff},
+		{testName: "SC clear", addr: 0xff02, write: 0x00, expected: 0x7e},
+		{testName: "TAC", addr: 0xff07, write: 0x05, expected: 0xfd},
+		{testName: "IF", addr: IF_ADDR, write: 0x01, expected: 0xe1},
+		{testName: "NR10", addr: 0xff10, write: 0x00, expected: 0x80},
+		{testName: "NR13 is write-only", addr: 0xff13, write: 0x00, expected: 0xff},
+		{testName: "NR52", addr: 0xff26, write: 0x80, expected: 0xf0},
+		{testName: "Wave RAM", addr: 0xff30, write: 0xa5, expected: 0xa5},
+		{testName: "STAT", addr: 0xff41, write: 0x00, expected: 0x80},
+		{testName: "BGP", addr: 0xff47, write: 0xe4, expected: 0xe4},
+		{testName: "Unmapped", addr: 0xff03, write: 0x00, expected: 0xff},
+		{testName: "Unmapped high", addr: 0xff7f, write: 0x00, expected: 0xff},
+	} {
+		t.Log(tt.testName)
+
+		m := NewMmu(nil)
+		m.Write(tt.addr, tt.write)
+
+		if val := m.Read(tt.addr); val != tt.expected {
+			t.Errorf("%s: expected %#02x, got %#02x", tt.testName, tt.expected, val)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+// Accesses that corrupt OAM when they put an address in 0xfe00-0xfeff
+// on the bus while the PPU scans OAM. The increment/decrement unit puts
+// the address of a 16-bit register out as it changes it, which counts
+// as a write unless the register is read through at the same time.
+const (
+	OAM_BUG_WRITE = iota
+	OAM_BUG_READ
+	OAM_BUG_READ_INC // Read through a register being incremented or decremented
+)
+
+// OAM is accessed as 20 rows of 4 words, the PPU scanning one row per
+// M-cycle of mode 2
+const (
+	OAM_ROW_SIZE = 8
+	OAM_ROWS     = OAM_SIZE / OAM_ROW_SIZE
+)
+
+// Implemented by buses on which the OAM corruption bug can be triggered
+type oamBugBus interface {
+	oamBug(addr uint16, kind int)
+}
+
+// Signals an address put on the bus, for the OAM corruption bug. Only
+// DMG hardware is affected.
+func (cpu *Cpu) oamBug(addr uint16, kind int) {
+	if cpu.cgbMode() || addr < OAM_ADDR || addr >= IO_ADDR {
+		return
+	}
+
+	if bus, ok := cpu.m.(oamBugBus); ok {
+		bus.oamBug(addr, kind)
+	}
+}
+
+func (m *Mmu) oamBug(addr uint16, kind int) {
+	if m.ppu != nil {
+		m.ppu.corruptOam(kind)
+	}
+}
+
+func (p *Ppu) oamWord(row, word int) uint16 {
+	i := row*OAM_ROW_SIZE + word*2
+	return uint16(p.m.oam[i]) | uint16(p.m.oam[i+1])<<8
+}
+
+func (p *Ppu) setOamWord(row, word int, val uint16) {
+	i := row*OAM_ROW_SIZE + word*2
+	p.m.oam[i], p.m.oam[i+1] = uint8(val), uint8(val>>8)
+}
+
+// Copies the words of a row from the given one on over another row
+func (p *Ppu) copyOamRow(dst, src, from int) {
+	copy(p.m.oam[dst*OAM_ROW_SIZE+from*2:(dst+1)*OAM_ROW_SIZE], p.m.oam[src*OAM_ROW_SIZE+from*2:])
+}
+
+// Corrupts the row of OAM being scanned. Its first word is garbled with
+// words of the preceding row, which the rest of it is copied from. The
+// first row is never affected.
+func (p *Ppu) corruptOam(kind int) {
+	if !p.enabled || p.mode != MODE_OAM_SCAN {
+		return
+	}
+
+	row := p.dot / 4
+	if row == 0 || row >= OAM_ROWS {
+		return
+	}
+
+	// The increment or decrement first garbles the preceding row and
+	// spreads it over its neighbours, away from the ends of OAM
+	if kind == OAM_BUG_READ_INC && row >= 4 && row < OAM_ROWS-1 {
+		a, b := p.oamWord(row-2, 0), p.oamWord(row-1, 0)
+		c, d := p.oamWord(row, 0), p.oamWord(row-1, 2)
+		p.setOamWord(row-1, 0, b&(a|c|d)|a&c&d)
+		p.copyOamRow(row-2, row-1, 0)
+		p.copyOamRow(row, row-1, 0)
+	}
+
+	a, b, c := p.oamWord(row, 0), p.oamWord(row-1, 0), p.oamWord(row-1, 2)
+	if kind == OAM_BUG_WRITE {
+		p.setOamWord(row, 0, (a^c)&(b^c)^c)
+	} else {
+		p.setOamWord(row, 0, b|a&c)
+	}
+	p.copyOamRow(row, row-1, 1)
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// OAM filled with a pattern making each corruption distinct
+func fillOamPattern(m *Mmu) {
+	for i := range m.oam {
+		m.oam[i] = uint8(i*29 + 7)
+	}
+}
+
+func TestOamCorruption(t *testing.T) {
+	for _, tt := range []struct {
+		testName string
+		kind     int
+		dots     int
+		lcdOff   bool
+		// Rows expected to change, by index
+		expected map[int][4]uint16
+	}{
+		{
+			testName: "Write",
+			kind:     OAM_BUG_WRITE,
+			dots:     5*4 + 1,
+			expected: map[int][4]uint16{5: {0xac8f, 0xfee1, 0x381b, 0x7255}},
+		},
+		{
+			testName: "Read",
+			kind:     OAM_BUG_READ,
+			dots:     5*4 + 1,
+			expected: map[int][4]uint16{5: {0xecaf, 0xfee1, 0x381b, 0x7255}},
+		},
+		{
+			testName: "Read with increment",
+			kind:     OAM_BUG_READ_INC,
+			dots:     5*4 + 1,
+			expected: map[int][4]uint16{
+				3: {0xccaf, 0xfee1, 0x381b, 0x7255},
+				4: {0xccaf, 0xfee1, 0x381b, 0x7255},
+				5: {0xccaf, 0xfee1, 0x381b, 0x7255},
+			},
+		},
+		{
+			testName: "Read with increment in the first rows",
+			kind:     OAM_BUG_READ_INC,
+			dots:     2*4 + 1,
+			expected: map[int][4]uint16{2: {0x8cef, 0x4629, 0x8063, 0xba9d}},
+		},
+		{testName: "First row", kind: OAM_BUG_WRITE, dots: 1},
+		{testName: "Drawing", kind: OAM_BUG_WRITE, dots: OAM_SCAN_DOTS + 1},
+		{testName: "HBlank", kind: OAM_BUG_WRITE, dots: DOTS_PER_LINE - 1},
+		{testName: "LCD off", kind: OAM_BUG_WRITE, dots: 5*4 + 1, lcdOff: true},
+	} {
+		t.Log(tt.testName)
+
+		m, p := newTestPpu(RENDER_SCANLINE)
+		if tt.lcdOff {
+			m.Write(LCDC_ADDR, 0x00)
+		}
+		fillOamPattern(m)
+		original := m.oam
+		p.Tick(tt.dots)
+
+		p.corruptOam(tt.kind)
+
+		for row := 0; row < OAM_ROWS; row++ {
+			var expected [4]uint16
+			if words, ok := tt.expected[row]; ok {
+				expected = words
+			} else {
+				for word := range expected {
+					i := row*OAM_ROW_SIZE + word*2
+					expected[word] = uint16(original[i]) | uint16(original[i+1])<<8
+				}
+			}
+
+			for word := range expected {
+				if val := p.oamWord(row, word); val != expected[word] {
+					t.Errorf("%s: expected %#04x in word %d of row %d, got %#04x", tt.testName, expected[word], word, row, val)
+				}
+			}
+		}
+	}
+}
+
+// Memory recording the accesses that can trigger the OAM corruption bug
+type oamBugRecorder struct {
+	Memory
+	bugs []string
+}
+
+func (m *oamBugRecorder) oamBug(addr uint16, kind int) {
+	m.bugs = append(m.bugs, fmt.Sprintf("%s %04x", []string{"W", "R", "I"}[kind], addr))
+}
+
+func TestOamBugTriggers(t *testing.T) {
+	for _, tt := range []struct {
+		testName string
+		opcode   int
+		before   regs
+		cgb      bool
+		expected []string
+	}{
+		{testName: "INC HL", opcode: 0x23, before: regs{h: 0xfe, l: 0x10}, expected: []string{"W fe10"}},
+		{testName: "DEC BC", opcode: 0x0b, before: regs{b: 0xfe, c: 0x10}, expected: []string{"W fe10"}},
+		{testName: "INC SP", opcode: 0x33, before: regs{sp: 0xfeff}, expected: []string{"W feff"}},
+		{testName: "INC HL outside OAM", opcode: 0x23, before: regs{h: 0xc0}},
+		{testName: "LD A,(HL)", opcode: 0x7e, before: regs{h: 0xfe, l: 0x10}, expected: []string{"R fe10"}},
+		{testName: "LD (HL),A", opcode: 0x77, before: regs{h: 0xfe, l: 0x10}, expected: []string{"W fe10"}},
+		{testName: "LD A,(HL+)", opcode: 0x2a, before: regs{h: 0xfe, l: 0x10}, expected: []string{"I fe10"}},
+		{testName: "LD A,(HL-)", opcode: 0x3a, before: regs{h: 0xfe, l: 0x10}, expected: []string{"I fe10"}},
+		{testName: "LD (HL+),A", opcode: 0x22, before: regs{h: 0xfe, l: 0x10}, expected: []string{"W fe10"}},
+		{testName: "PUSH BC", opcode: 0xc5, before: regs{sp: 0xfe20}, expected: []string{"W fe20", "W fe1f", "W fe1e"}},
+		{testName: "POP BC", opcode: 0xc1, before: regs{sp: 0xfe20}, expected: []string{"I fe20", "R fe21"}},
+		{testName: "RET", opcode: 0xc9, before: regs{sp: 0xfe20}, expected: []string{"I fe20", "R fe21"}},
+		{testName: "INC HL in CGB mode", opcode: 0x23, before: regs{h: 0xfe, l: 0x10}, cgb: true},
+	} {
+		t.Log(tt.testName)
+
+		m := &oamBugRecorder{}
+		cpu := Cpu{m: m, cgb: tt.cgb}
+		tt.before.pc = 0x100
+		cpu.setRegs(tt.before)
+
+		step(&cpu, tt.opcode, [2]int{})
+
+		if fmt.Sprint(m.bugs) != fmt.Sprint(tt.expected) {
+			t.Errorf("%s: expected %v, got %v", tt.testName, tt.expected, m.bugs)
+		}
+	}
+}
+
+func TestOamBugDuringOamScan(t *testing.T) {
+	for _, tt := range []struct {
+		testName  string
+		dots      int
+		corrupted bool
+	}{
+		{testName: "During OAM scan", dots: 16, corrupted: true},
+		{testName: "During HBlank", dots: DOTS_PER_LINE - 16, corrupted: false},
+	} {
+		t.Log(tt.testName)
+
+		m, p := newTestPpu(RENDER_SCANLINE)
+		fillOamPattern(m)
+		original := m.oam
+
+		// INC HL, with HL in OAM, run from WRAM
+		cpu := NewCpu(m)
+		cpu.SetClock(p.Tick, TIMING_MCYCLE)
+		cpu.pc, cpu.h, cpu.l = WRAM_ADDR, 0xfe, 0x00
+		m.Write(WRAM_ADDR, 0x23)
+
+		p.Tick(tt.dots)
+		cpu.tick()
+
+		if corrupted := m.oam != original; corrupted != tt.corrupted {
+			t.Errorf("%s: expected corruption to be %t", tt.testName, tt.corrupted)
+		}
+	}
+}
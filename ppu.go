@@ -0,0 +1,615 @@
+package main
+
+const (
+	SCREEN_WIDTH  = 160
+	SCREEN_HEIGHT = 144
+
+	DOTS_PER_LINE   = 456
+	LINES_PER_FRAME = 154
+
+	OAM_SCAN_DOTS = 80
+	// Shortest mode 3, which fetches and penalties for scrolling, the
+	// window and sprites make longer
+	DRAW_DOTS = 172
+
+	SPRITES_PER_LINE = 10
+	SPRITE_COUNT     = OAM_SIZE / 4
+)
+
+// LCD registers
+const (
+	LCDC_ADDR = 0xff40
+	STAT_ADDR = 0xff41
+	SCY_ADDR  = 0xff42
+	SCX_ADDR  = 0xff43
+	LY_ADDR   = 0xff44
+	LYC_ADDR  = 0xff45
+	BGP_ADDR  = 0xff47
+	OBP0_ADDR = 0xff48
+	OBP1_ADDR = 0xff49
+	WY_ADDR   = 0xff4a
+	WX_ADDR   = 0xff4b
+)
+
+// LCDC bits
+const (
+	LCDC_BG_ENABLE     = BIT_0 // Also disables the window on DMG, sprite priority on CGB
+	LCDC_OBJ_ENABLE    = BIT_1
+	LCDC_OBJ_SIZE      = BIT_2 // 8x16 sprites
+	LCDC_BG_MAP        = BIT_3 // Tile map at 0x9c00 rather than 0x9800
+	LCDC_TILE_DATA     = BIT_4 // Unsigned tile numbers from 0x8000
+	LCDC_WINDOW_ENABLE = BIT_5
+	LCDC_WINDOW_MAP    = BIT_6
+	LCDC_ENABLE        = BIT_7
+)
+
+// PPU modes, as reported in the low bits of STAT
+const (
+	MODE_HBLANK = iota
+	MODE_VBLANK
+	MODE_OAM_SCAN
+	MODE_DRAWING
+
+	STAT_MODE = BIT_1 | BIT_0
+)
+
+// STAT bits above the mode. Each enabled source holds the STAT interrupt
+// line high while its condition lasts, and the interrupt is requested
+// when the line rises.
+const (
+	STAT_LYC_EQUAL  = BIT_2 // LY matches LYC
+	STAT_HBLANK_INT = BIT_3
+	STAT_VBLANK_INT = BIT_4
+	STAT_OAM_INT    = BIT_5
+	STAT_LYC_INT    = BIT_6
+
+	// Sources a STAT write enables for a cycle on DMG. The OAM scan source
+	// is not among them.
+	STAT_WRITE_BUG_SOURCES = STAT_HBLANK_INT | STAT_VBLANK_INT | STAT_LYC_INT
+)
+
+// Renderers
+const (
+	// Draws whole lines at the start of mode 3, with the registers as
+	// they are then
+	RENDER_SCANLINE = iota
+	// Draws a pixel at a time through the pixel FIFO, so that changes to
+	// registers in the middle of a line show. Only static scenes are
+	// checked to match RENDER_SCANLINE: it is not validated against the
+	// acid2 test ROMs until they are added to testdata.
+	RENDER_FIFO
+)
+
+// Sprite attribute bits
+const (
+	OBJ_CGB_PALETTE = BIT_2 | BIT_1 | BIT_0
+	OBJ_BANK        = BIT_3 // CGB only
+	OBJ_PALETTE     = BIT_4 // DMG only
+	OBJ_FLIP_X      = BIT_5
+	OBJ_FLIP_Y      = BIT_6
+	OBJ_PRIORITY    = BIT_7 // Drawn behind background colors 1-3
+)
+
+// Background map attribute bits, in VRAM bank 1 in CGB mode
+const (
+	BG_PALETTE  = BIT_2 | BIT_1 | BIT_0
+	BG_BANK     = BIT_3
+	BG_FLIP_X   = BIT_5
+	BG_FLIP_Y   = BIT_6
+	BG_PRIORITY = BIT_7 // Drawn in front of sprites for colors 1-3
+)
+
+// A frame of shades, from 0 for white to 3 for black. In CGB mode it
+// holds color numbers within their palettes instead.
+type Frame [SCREEN_HEIGHT][SCREEN_WIDTH]uint8
+
+// A frame of RGB555 colors, red in the low bits. DMG shades are shown
+// as grays.
+type ColorFrame [SCREEN_HEIGHT][SCREEN_WIDTH]uint16
+
+// A background or window pixel
+type bgPixel struct {
+	color uint8
+	attr  uint8 // Map attributes of its tile, in CGB mode
+}
+
+// A sprite pixel waiting to be mixed with the background
+type objPixel struct {
+	color    uint8 // 0 when transparent or empty
+	palette  uint8
+	priority bool
+	index    int // Position of the sprite in OAM
+}
+
+// A sprite selected during OAM scan
+type sprite struct {
+	y, x       int // Screen position of the top left corner
+	tile, attr uint8
+	index      int // Position in OAM, which breaks ties in priority
+}
+
+// Picture processing unit. It reads VRAM, OAM and the LCD registers
+// from the MMU and draws each line in mode 3 with the selected renderer.
+type Ppu struct {
+	m *Mmu
+
+	enabled bool
+	ly      int
+	dot     int // Within the current line
+	mode    int
+	drawEnd int // Dot at which mode 3 ends on the current line
+
+	sprites []sprite // Selected for the current line
+
+	renderer int
+	fifo     pixelFifo
+
+	// The window starts once LY has matched WY in the frame, and has
+	// its own line counter that only advances on lines showing it
+	windowTriggered bool
+	windowLine      int
+
+	statLine bool // STAT interrupt line, high while a source is active
+
+	frame            Frame
+	colorFrame       ColorFrame
+	onFrame          func(frame *Frame)
+	requestInterrupt func(interrupt int)
+}
+
+func NewPpu(m *Mmu) *Ppu {
+	p := &Ppu{m: m, sprites: make([]sprite, 0, SPRITES_PER_LINE)}
+	p.requestInterrupt = func(interrupt int) {
+		p.setReg(IF_ADDR, p.reg(IF_ADDR)|1<<interrupt)
+	}
+	m.ppu = p
+	p.reset()
+
+	return p
+}
+
+// Sets the function called with each completed frame, at the start of
+// VBlank. The frame is reused for the next one once it returns.
+func (p *Ppu) SetFrameHandler(handler func(frame *Frame)) {
+	p.onFrame = handler
+}
+
+// Sets the function raising VBlank and STAT interrupts, normally
+// Cpu.RequestInterrupt. By default they are flagged in IF directly.
+func (p *Ppu) SetInterruptRequest(request func(interrupt int)) {
+	p.requestInterrupt = request
+}
+
+// Selects how lines are drawn, RENDER_SCANLINE or RENDER_FIFO
+func (p *Ppu) SetRenderer(renderer int) {
+	p.renderer = renderer
+}
+
+// Returns the last frame drawn, which is being overwritten outside of
+// VBlank
+func (p *Ppu) Frame() *Frame {
+	return &p.frame
+}
+
+// Returns the colors of the last frame drawn, which are being
+// overwritten outside of VBlank
+func (p *Ppu) ColorFrame() *ColorFrame {
+	return &p.colorFrame
+}
+
+func (p *Ppu) reg(addr uint16) uint8 {
+	return p.m.io[addr-IO_ADDR]
+}
+
+func (p *Ppu) setReg(addr uint16, val uint8) {
+	p.m.io[addr-IO_ADDR] = val
+}
+
+// Restarts from the top of the frame, as when the LCD is switched on
+func (p *Ppu) reset() {
+	p.ly, p.dot = 0, 0
+	p.windowTriggered, p.windowLine = false, 0
+	p.setLy(0)
+	p.setMode(MODE_OAM_SCAN)
+	p.updateStat()
+}
+
+func (p *Ppu) setLy(ly int) {
+	p.ly = ly
+	p.setReg(LY_ADDR, uint8(ly))
+}
+
+func (p *Ppu) setMode(mode int) {
+	p.mode = mode
+	p.setReg(STAT_ADDR, p.reg(STAT_ADDR)&^STAT_MODE|uint8(mode))
+}
+
+// Runs the PPU for the given number of T-cycles
+func (p *Ppu) Tick(cycles int) {
+	if p.reg(LCDC_ADDR)&LCDC_ENABLE == 0 {
+		// LY stays at 0 and STAT reports HBlank while the LCD is off,
+		// with no STAT interrupt sources active
+		if p.enabled {
+			p.enabled = false
+			p.statLine = false
+			p.setLy(0)
+			p.setMode(MODE_HBLANK)
+		}
+		return
+	}
+
+	if !p.enabled {
+		p.enabled = true
+		p.reset()
+	}
+
+	for i := 0; i < cycles; i++ {
+		p.step()
+	}
+}
+
+// Runs a dot
+func (p *Ppu) step() {
+	p.dot++
+
+	switch {
+	case p.ly < SCREEN_HEIGHT && p.dot == OAM_SCAN_DOTS:
+		if p.ly == int(p.reg(WY_ADDR)) {
+			p.windowTriggered = true
+		}
+
+		p.scanOam()
+		p.setMode(MODE_DRAWING)
+		if p.renderer == RENDER_FIFO {
+			p.startFifo()
+		} else {
+			p.drawEnd = OAM_SCAN_DOTS + p.drawDots()
+			p.drawLine()
+		}
+	case p.mode == MODE_DRAWING && p.renderer == RENDER_FIFO:
+		// Mode 3 lasts as long as the FIFO takes to output the line
+		if p.fifoStep() {
+			p.setMode(MODE_HBLANK)
+		}
+	case p.ly < SCREEN_HEIGHT && p.dot == p.drawEnd:
+		p.setMode(MODE_HBLANK)
+	case p.dot == DOTS_PER_LINE:
+		p.dot = 0
+		p.nextLine()
+	}
+
+	p.updateStat()
+}
+
+func (p *Ppu) nextLine() {
+	switch ly := p.ly + 1; {
+	case ly == SCREEN_HEIGHT:
+		p.setLy(ly)
+		p.setMode(MODE_VBLANK)
+		p.requestInterrupt(INT_VBLANK)
+		if p.onFrame != nil {
+			p.onFrame(&p.frame)
+		}
+	case ly == LINES_PER_FRAME:
+		p.windowTriggered, p.windowLine = false, 0
+		p.setLy(0)
+		p.setMode(MODE_OAM_SCAN)
+	case ly < SCREEN_HEIGHT:
+		p.setLy(ly)
+		p.setMode(MODE_OAM_SCAN)
+	default:
+		p.setLy(ly)
+	}
+}
+
+// Whether the PPU keeps the CPU from accessing an address. OAM,
+// including the unusable area after it, is in use during OAM scan and
+// drawing, and VRAM during drawing.
+func (p *Ppu) blocks(addr uint16) bool {
+	if !p.enabled {
+		return false
+	}
+
+	switch {
+	case addr >= OAM_ADDR && addr < IO_ADDR:
+		return p.mode == MODE_OAM_SCAN || p.mode == MODE_DRAWING
+	case addr >= VRAM_ADDR && addr < EXT_RAM_ADDR:
+		return p.mode == MODE_DRAWING
+	}
+
+	return false
+}
+
+// Updates the LY=LYC flag and the STAT interrupt line
+func (p *Ppu) updateStat() {
+	stat := p.reg(STAT_ADDR) &^ STAT_LYC_EQUAL
+	if p.reg(LY_ADDR) == p.reg(LYC_ADDR) {
+		stat |= STAT_LYC_EQUAL
+	}
+	p.setReg(STAT_ADDR, stat)
+
+	p.setStatLine(stat)
+}
+
+// Sets the STAT interrupt line from the sources enabled in stat,
+// requesting the interrupt on a rising edge
+func (p *Ppu) setStatLine(stat uint8) {
+	line := stat&STAT_LYC_INT != 0 && stat&STAT_LYC_EQUAL != 0
+	switch p.mode {
+	case MODE_HBLANK:
+		line = line || stat&STAT_HBLANK_INT != 0
+	case MODE_VBLANK:
+		// The OAM source also fires as VBlank starts
+		line = line || stat&STAT_VBLANK_INT != 0 ||
+			stat&STAT_OAM_INT != 0 && p.ly == SCREEN_HEIGHT && p.dot == 0
+	case MODE_OAM_SCAN:
+		line = line || stat&STAT_OAM_INT != 0
+	}
+
+	if line && !p.statLine {
+		p.requestInterrupt(INT_STAT)
+	}
+	p.statLine = line
+}
+
+// Called after the CPU writes STAT. On DMG the write enables the HBlank,
+// VBlank and LY=LYC sources for a cycle, raising the interrupt if any of
+// their conditions holds.
+func (p *Ppu) writeStat() {
+	if !p.enabled {
+		return
+	}
+
+	if !p.m.cgb {
+		p.setStatLine(p.reg(STAT_ADDR) | STAT_WRITE_BUG_SOURCES)
+	}
+	p.setStatLine(p.reg(STAT_ADDR))
+}
+
+func (p *Ppu) spriteHeight() int {
+	if p.reg(LCDC_ADDR)&LCDC_OBJ_SIZE != 0 {
+		return 16
+	}
+
+	return 8
+}
+
+// Selects the first sprites in OAM that overlap the current line, up to
+// the limit of 10. Sprites are selected even when off screen
+// horizontally or when sprites are disabled.
+func (p *Ppu) scanOam() {
+	p.sprites = p.sprites[:0]
+	height := p.spriteHeight()
+
+	for i := 0; i < SPRITE_COUNT && len(p.sprites) < SPRITES_PER_LINE; i++ {
+		obj := p.m.oam[i*4 : i*4+4]
+		y := int(obj[0]) - 16
+		if p.ly >= y && p.ly < y+height {
+			p.sprites = append(p.sprites, sprite{y: y, x: int(obj[1]) - 8, tile: obj[2], attr: obj[3], index: i})
+		}
+	}
+}
+
+// Whether the window covers part of the current line
+func (p *Ppu) windowVisible() bool {
+	lcdc := p.reg(LCDC_ADDR)
+	return p.windowTriggered && lcdc&LCDC_WINDOW_ENABLE != 0 && (lcdc&LCDC_BG_ENABLE != 0 || p.m.cgb) &&
+		p.reg(WX_ADDR) < SCREEN_WIDTH+7
+}
+
+// Length of mode 3 on the current line. Fine scrolling discards pixels,
+// the window restarts fetching, and each sprite pauses the fetcher for
+// longer the further it is from the next tile boundary.
+func (p *Ppu) drawDots() int {
+	scx := int(p.reg(SCX_ADDR))
+	dots := DRAW_DOTS + scx%8
+
+	if p.windowVisible() {
+		dots += 6
+	}
+
+	if p.reg(LCDC_ADDR)&LCDC_OBJ_ENABLE != 0 {
+		for _, s := range p.sprites {
+			if s.x+8 < SCREEN_WIDTH+8 {
+				dots += 11 - min(5, (s.x+8+scx)%8)
+			}
+		}
+	}
+
+	return dots
+}
+
+// Color number of a pixel in a tile, in one of the VRAM banks
+func (p *Ppu) tilePixel(bank int, addr uint16, row, col int) uint8 {
+	offset := bank*VRAM_SIZE + int(addr-VRAM_ADDR) + row*2
+	lo, hi := p.m.vram[offset], p.m.vram[offset+1]
+	bit := 7 - col
+
+	return (hi>>bit&1)<<1 | lo>>bit&1
+}
+
+// Address of a background or window tile, in either addressing mode
+func (p *Ppu) bgTileAddr(tile uint8) uint16 {
+	if p.reg(LCDC_ADDR)&LCDC_TILE_DATA != 0 {
+		return VRAM_ADDR + uint16(tile)*16
+	}
+
+	return uint16(0x9000 + int(int8(tile))*16)
+}
+
+// Tile number and, in CGB mode, attributes of a tile map entry. The
+// attributes sit in VRAM bank 1 at the same address as the tile number.
+func (p *Ppu) mapEntry(mapAddr uint16, col, row int) (uint8, uint8) {
+	offset := int(mapAddr-VRAM_ADDR) + row*32 + col
+	if !p.m.cgb {
+		return p.m.vram[offset], 0
+	}
+
+	return p.m.vram[offset], p.m.vram[VRAM_SIZE+offset]
+}
+
+// Row of a background tile to fetch, and the VRAM bank it is in
+func bgTileRow(attr uint8, row int) (int, int) {
+	if attr&BG_FLIP_Y != 0 {
+		row = 7 - row
+	}
+
+	return int(attr&BG_BANK) >> 3, row
+}
+
+// Background or window pixel from the tile map at mapAddr
+func (p *Ppu) mapPixel(mapAddr uint16, x, y int) bgPixel {
+	tile, attr := p.mapEntry(mapAddr, x/8, y/8)
+	bank, row := bgTileRow(attr, y%8)
+
+	col := x % 8
+	if attr&BG_FLIP_X != 0 {
+		col = 7 - col
+	}
+
+	return bgPixel{color: p.tilePixel(bank, p.bgTileAddr(tile), row, col), attr: attr}
+}
+
+func shade(palette, color uint8) uint8 {
+	return palette >> (color * 2) & 0x03
+}
+
+// Draws the current line, with the registers as they are at the start
+// of mode 3
+func (p *Ppu) drawLine() {
+	lcdc := p.reg(LCDC_ADDR)
+	scx, scy := int(p.reg(SCX_ADDR)), int(p.reg(SCY_ADDR))
+	wx := int(p.reg(WX_ADDR)) - 7
+
+	bgMap, windowMap := uint16(0x9800), uint16(0x9800)
+	if lcdc&LCDC_BG_MAP != 0 {
+		bgMap = 0x9c00
+	}
+	if lcdc&LCDC_WINDOW_MAP != 0 {
+		windowMap = 0x9c00
+	}
+
+	window := p.windowVisible()
+
+	var objs [SCREEN_WIDTH]objPixel
+	p.lineSprites(&objs)
+
+	for x := 0; x < SCREEN_WIDTH; x++ {
+		var bg bgPixel
+		if window && x >= wx {
+			bg = p.mapPixel(windowMap, x-wx, p.windowLine)
+		} else {
+			bg = p.mapPixel(bgMap, (x+scx)&0xff, (p.ly+scy)&0xff)
+		}
+
+		p.drawPixel(x, bg, objs[x])
+	}
+
+	if window {
+		p.windowLine++
+	}
+}
+
+// Picks the sprite pixel shown at each point of the line. On DMG the
+// sprite with the lowest X wins where sprites overlap, then the one
+// first in OAM, while on CGB only the position in OAM counts.
+// Transparent pixels let lower priority sprites show through.
+func (p *Ppu) lineSprites(objs *[SCREEN_WIDTH]objPixel) {
+	height := p.spriteHeight()
+
+	for x := 0; x < SCREEN_WIDTH; x++ {
+		var winner *sprite
+
+		for i := range p.sprites {
+			s := &p.sprites[i]
+			if x < s.x || x >= s.x+8 {
+				continue
+			}
+			if winner != nil && (p.m.cgb || s.x > winner.x || s.x == winner.x && s.index > winner.index) {
+				continue
+			}
+
+			if color := p.spritePixel(s, x-s.x, height); color != 0 {
+				winner = s
+				objs[x] = p.objPixel(s, color)
+			}
+		}
+	}
+}
+
+func (p *Ppu) spritePixel(s *sprite, col, height int) uint8 {
+	row := p.ly - s.y
+	if s.attr&OBJ_FLIP_Y != 0 {
+		row = height - 1 - row
+	}
+	if s.attr&OBJ_FLIP_X != 0 {
+		col = 7 - col
+	}
+
+	tile := s.tile
+	if height == 16 {
+		tile &^= 1
+	}
+
+	bank := 0
+	if p.m.cgb && s.attr&OBJ_BANK != 0 {
+		bank = 1
+	}
+
+	return p.tilePixel(bank, VRAM_ADDR+uint16(tile)*16, row, col)
+}
+
+// A sprite pixel of the given color, with its palette number from the
+// attributes
+func (p *Ppu) objPixel(s *sprite, color uint8) objPixel {
+	palette := s.attr & OBJ_CGB_PALETTE
+	if !p.m.cgb {
+		palette = (s.attr & OBJ_PALETTE) >> 4
+	}
+
+	return objPixel{color: color, palette: palette, priority: s.attr&OBJ_PRIORITY != 0, index: s.index}
+}
+
+// Grays the DMG shades are shown as in the color frame
+var dmgColors = [4]uint16{0x7fff, 0x56b5, 0x294a, 0x0000}
+
+// Mixes background and sprite pixels into the frame, with the palettes
+// and LCDC as they are at the time. On DMG clearing LCDC_BG_ENABLE
+// blanks the background, while on CGB it puts all sprites in front.
+func (p *Ppu) drawPixel(x int, bg bgPixel, obj objPixel) {
+	lcdc := p.reg(LCDC_ADDR)
+	cgb := p.m.cgb
+	if !cgb && lcdc&LCDC_BG_ENABLE == 0 {
+		bg.color = 0
+	}
+
+	front := obj.color != 0 && lcdc&LCDC_OBJ_ENABLE != 0
+	if front && bg.color != 0 {
+		if cgb {
+			front = lcdc&LCDC_BG_ENABLE == 0 || !obj.priority && bg.attr&BG_PRIORITY == 0
+		} else {
+			front = !obj.priority
+		}
+	}
+
+	switch {
+	case cgb && front:
+		p.frame[p.ly][x] = obj.color
+		p.colorFrame[p.ly][x] = p.m.objPalettes.color(obj.palette, obj.color)
+	case cgb:
+		p.frame[p.ly][x] = bg.color
+		p.colorFrame[p.ly][x] = p.m.bgPalettes.color(bg.attr&BG_PALETTE, bg.color)
+	default:
+		palette := p.reg(BGP_ADDR)
+		color := bg.color
+		if front {
+			palette, color = p.reg(OBP0_ADDR), obj.color
+			if obj.palette != 0 {
+				palette = p.reg(OBP1_ADDR)
+			}
+		}
+
+		p.frame[p.ly][x] = shade(palette, color)
+		p.colorFrame[p.ly][x] = dmgColors[p.frame[p.ly][x]]
+	}
+}
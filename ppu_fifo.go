@@ -0,0 +1,224 @@
+package main
+
+// Steps of the background fetcher, each taking 2 dots but for pushing,
+// which waits for the FIFO to empty
+const (
+	FETCH_TILE = iota
+	FETCH_LOW
+	FETCH_HIGH
+	FETCH_PUSH
+
+	FETCH_STEP_DOTS = 2
+	// The first tile of each line is fetched twice
+	FETCH_START_DELAY = 6
+)
+
+// State of the pixel FIFO renderer over mode 3
+type pixelFifo struct {
+	bg      [8]bgPixel // The next one at 8-bgCount
+	bgCount int
+	obj     [8]objPixel // Sprite pixels lined up with the next 8 pixels
+
+	step, ticks int
+	delay       int
+	fetchX      int // Tile column being fetched, from the left edge or the window
+	tile, attr  uint8
+	lo, hi      uint8
+
+	x       int // Next pixel on the line
+	discard int // Pixels still to drop for fine scrolling or the window
+	window  bool
+	stall   int // Dots the sprite fetch still pauses output for
+	fetched [SPRITES_PER_LINE]bool
+}
+
+// Starts mode 3 with empty FIFOs
+func (p *Ppu) startFifo() {
+	p.fifo = pixelFifo{
+		delay:   FETCH_START_DELAY,
+		discard: int(p.reg(SCX_ADDR) & 0x07),
+	}
+}
+
+// Runs a dot of mode 3, returning whether the line is complete
+func (p *Ppu) fifoStep() bool {
+	f := &p.fifo
+
+	if f.stall > 0 {
+		f.stall--
+		return false
+	}
+
+	if f.discard == 0 && p.fetchSprites() {
+		return false
+	}
+
+	if wx := int(p.reg(WX_ADDR)) - 7; !f.window && f.discard == 0 && p.windowVisible() && f.x >= wx {
+		// The window restarts fetching from its own first tile, with the
+		// pixels left of the screen dropped when WX is below 7
+		f.window = true
+		f.bgCount = 0
+		f.fetchX = 0
+		f.step, f.ticks = FETCH_TILE, 0
+		f.discard = max(0, -wx)
+	}
+
+	if f.delay > 0 {
+		f.delay--
+	} else {
+		p.fetcherStep()
+	}
+
+	if f.bgCount == 0 {
+		return false
+	}
+
+	bg := f.bg[len(f.bg)-f.bgCount]
+	f.bgCount--
+	obj := f.obj[0]
+	copy(f.obj[:], f.obj[1:])
+	f.obj[len(f.obj)-1] = objPixel{}
+
+	if f.discard > 0 {
+		f.discard--
+		return false
+	}
+
+	p.drawPixel(f.x, bg, obj)
+	f.x++
+
+	if f.x < SCREEN_WIDTH {
+		return false
+	}
+
+	if f.window {
+		p.windowLine++
+	}
+	return true
+}
+
+// Fetches the next sprite starting at the current pixel, if any,
+// returning whether one was. Pixel output stalls while it is fetched,
+// for longer the further the background fetcher is from the next tile.
+func (p *Ppu) fetchSprites() bool {
+	f := &p.fifo
+	if p.reg(LCDC_ADDR)&LCDC_OBJ_ENABLE == 0 {
+		return false
+	}
+
+	// Sprites hanging off the left edge all start at pixel 0, and are
+	// fetched from the lowest X, then in OAM order
+	next := -1
+	for i := range p.sprites {
+		s := &p.sprites[i]
+		if f.fetched[i] || max(s.x, 0) != f.x {
+			continue
+		}
+		if next < 0 || s.x < p.sprites[next].x || s.x == p.sprites[next].x && s.index < p.sprites[next].index {
+			next = i
+		}
+	}
+
+	if next < 0 {
+		return false
+	}
+
+	s := &p.sprites[next]
+	f.fetched[next] = true
+	p.mergeSprite(s)
+
+	// This dot is the first of the stall
+	scx := int(p.reg(SCX_ADDR))
+	f.stall = 11 - min(5, (s.x+8+scx)%8) - 1
+	return true
+}
+
+// Adds the pixels of a sprite to the sprite FIFO. Pixels already there
+// come from sprites with priority on DMG, unless transparent, while on
+// CGB they give way to sprites earlier in OAM.
+func (p *Ppu) mergeSprite(s *sprite) {
+	f := &p.fifo
+	height := p.spriteHeight()
+
+	for col := 0; col < 8; col++ {
+		x := s.x + col
+		if x < f.x {
+			continue
+		}
+
+		slot := &f.obj[x-f.x]
+		if slot.color != 0 && !(p.m.cgb && s.index < slot.index) {
+			continue
+		}
+
+		if color := p.spritePixel(s, col, height); color != 0 {
+			*slot = p.objPixel(s, color)
+		}
+	}
+}
+
+// Runs a dot of the background fetcher. SCX and SCY are read as each
+// tile is fetched, so changes to them show from the next tile on.
+func (p *Ppu) fetcherStep() {
+	f := &p.fifo
+
+	if f.step == FETCH_PUSH {
+		if f.bgCount == 0 {
+			for col := 0; col < 8; col++ {
+				bit := 7 - col
+				if f.attr&BG_FLIP_X != 0 {
+					bit = col
+				}
+				f.bg[col] = bgPixel{color: (f.hi>>bit&1)<<1 | f.lo>>bit&1, attr: f.attr}
+			}
+			f.bgCount = 8
+			f.fetchX++
+			f.step, f.ticks = FETCH_TILE, 0
+		}
+		return
+	}
+
+	f.ticks++
+	if f.ticks < FETCH_STEP_DOTS {
+		return
+	}
+	f.ticks = 0
+
+	lcdc := p.reg(LCDC_ADDR)
+	row := p.fetchRow()
+	bank, tileRow := bgTileRow(f.attr, row%8)
+	tileAddr := bank*VRAM_SIZE + int(p.bgTileAddr(f.tile)-VRAM_ADDR) + tileRow*2
+
+	switch f.step {
+	case FETCH_TILE:
+		if f.window {
+			mapAddr := uint16(0x9800)
+			if lcdc&LCDC_WINDOW_MAP != 0 {
+				mapAddr = 0x9c00
+			}
+			f.tile, f.attr = p.mapEntry(mapAddr, f.fetchX&31, row/8)
+		} else {
+			mapAddr := uint16(0x9800)
+			if lcdc&LCDC_BG_MAP != 0 {
+				mapAddr = 0x9c00
+			}
+			col := (int(p.reg(SCX_ADDR))/8 + f.fetchX) & 31
+			f.tile, f.attr = p.mapEntry(mapAddr, col, row/8)
+		}
+	case FETCH_LOW:
+		f.lo = p.m.vram[tileAddr]
+	case FETCH_HIGH:
+		f.hi = p.m.vram[tileAddr+1]
+	}
+
+	f.step++
+}
+
+// Row of the background or window being fetched
+func (p *Ppu) fetchRow() int {
+	if p.fifo.window {
+		return p.windowLine
+	}
+
+	return (p.ly + int(p.reg(SCY_ADDR))) & 0xff
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"errors"
+	"image"
+	"image/png"
+	"io/fs"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Dots into a line at which a pixel of a plain background line is output
+func pixelDot(x int) int {
+	return OAM_SCAN_DOTS + 2*FETCH_START_DELAY + x
+}
+
+func TestFifoMidLinePalette(t *testing.T) {
+	m, p := newTestPpu(RENDER_FIFO)
+	fillTile(m, 0x8010, 1)
+	for i := uint16(0); i < 32; i++ {
+		m.Write(0x9800+i, 1)
+	}
+
+	p.Tick(pixelDot(40))
+	m.Write(BGP_ADDR, 0x1b)
+	p.Tick(DOTS_PER_LINE - pixelDot(40))
+
+	if val := p.Frame()[0][39]; val != 1 {
+		t.Errorf("Expected the old palette before the write, got %d", val)
+	}
+	if val := p.Frame()[0][40]; val != 2 {
+		t.Errorf("Expected the new palette after the write, got %d", val)
+	}
+}
+
+func TestFifoMidLineScroll(t *testing.T) {
+	m, p := newTestPpu(RENDER_FIFO)
+	fillTile(m, 0x8010, 1)
+	fillTile(m, 0x8020, 2)
+	fillTile(m, 0x8030, 3)
+	for i := uint16(0); i < 32; i++ {
+		m.Write(0x9800+i, uint8(i%4))
+	}
+
+	// Tiles already fetched keep the old scroll position: the fetcher is
+	// a tile ahead of the output
+	p.Tick(pixelDot(40))
+	m.Write(SCX_ADDR, 8)
+	p.Tick(DOTS_PER_LINE - pixelDot(40))
+
+	for _, tt := range []struct {
+		testName string
+		x        int
+		expected uint8
+	}{
+		{testName: "Before the write", x: 39, expected: 0},
+		{testName: "Tile fetched before the write", x: 47, expected: 1},
+		{testName: "Tile fetched after the write", x: 48, expected: 3},
+	} {
+		t.Log(tt.testName)
+
+		if val := p.Frame()[0][tt.x]; val != tt.expected {
+			t.Errorf("%s: expected shade %d at %d, got %d", tt.testName, tt.expected, tt.x, val)
+		}
+	}
+}
+
+func TestFifoMidLineSprites(t *testing.T) {
+	m, p := newTestPpu(RENDER_FIFO)
+	fillTile(m, 0x8010, 3)
+	setSprite(m, 0, 0, 0, 1, 0)
+	setSprite(m, 1, 0, 80, 1, 0)
+
+	// Sprites disabled after the first one is drawn
+	p.Tick(pixelDot(40) + 11)
+	m.Write(LCDC_ADDR, m.Read(LCDC_ADDR)&^LCDC_OBJ_ENABLE)
+	p.Tick(DOTS_PER_LINE - pixelDot(40) - 11)
+
+	if val := p.Frame()[0][0]; val != 3 {
+		t.Errorf("Expected the first sprite to be drawn, got %d", val)
+	}
+	if val := p.Frame()[0][80]; val != 0 {
+		t.Errorf("Expected the second sprite to be hidden, got %d", val)
+	}
+
+	// Disabling sprites also shortens mode 3
+	if mode := m.Read(STAT_ADDR) & STAT_MODE; mode != MODE_OAM_SCAN {
+		t.Errorf("Expected the next line to start, got mode %d", mode)
+	}
+}
+
+// Sprites off the left edge are all fetched at pixel 0, where the one
+// with the lowest X still wins on DMG
+func TestFifoSpritesOffLeftEdge(t *testing.T) {
+	for _, renderer := range renderers {
+		m, p := newTestPpu(renderer)
+		fillTile(m, 0x8010, 1)
+		fillTile(m, 0x8020, 3)
+		setSprite(m, 0, 0, -5, 1, 0)
+		setSprite(m, 1, 0, -6, 2, 0)
+
+		p.Tick(DOTS_PER_LINE)
+
+		for _, tt := range []struct {
+			testName string
+			x        int
+			expected uint8
+		}{
+			{testName: "Both sprites", x: 0, expected: 3},
+			{testName: "Both sprites, last pixel of the second", x: 1, expected: 3},
+			{testName: "First sprite only", x: 2, expected: 1},
+		} {
+			t.Log(tt.testName)
+
+			if val := p.Frame()[0][tt.x]; val != tt.expected {
+				t.Errorf("Renderer %d: %s: expected shade %d at %d, got %d", renderer, tt.testName, tt.expected, tt.x, val)
+			}
+		}
+	}
+}
+
+// With WX below 7 the window columns left of the screen are dropped, so
+// that its second tile starts at x WX+1 whatever WX is
+func TestFifoWindowLeftEdge(t *testing.T) {
+	for _, tt := range []struct {
+		testName string
+		wx       uint8
+	}{
+		{testName: "WX 7", wx: 7},
+		{testName: "WX 3", wx: 3},
+		{testName: "WX 0", wx: 0},
+	} {
+		for _, renderer := range renderers {
+			t.Log(tt.testName)
+
+			m, p := newTestPpu(renderer)
+			m.Write(LCDC_ADDR, m.Read(LCDC_ADDR)|LCDC_WINDOW_ENABLE|LCDC_WINDOW_MAP)
+			m.Write(WX_ADDR, tt.wx)
+			fillTile(m, 0x8010, 1)
+			fillTile(m, 0x8020, 2)
+			fillTile(m, 0x8030, 3)
+			for i := uint16(0); i < 3; i++ {
+				m.Write(0x9c00+i, uint8(i+1))
+			}
+
+			p.Tick(DOTS_PER_LINE)
+
+			wx := int(tt.wx)
+			for i, x := range []int{wx, wx + 1, wx + 9} {
+				if val := p.Frame()[0][x]; val != uint8(i+1) {
+					t.Errorf("Renderer %d: %s: expected shade %d at %d, got %d", renderer, tt.testName, i+1, x, val)
+				}
+			}
+		}
+	}
+}
+
+func TestFifoWindowTiming(t *testing.T) {
+	for _, tt := range []struct {
+		testName string
+		wx       uint8
+		wy       uint8
+		drawDots int
+	}{
+		{testName: "Window below the line", wx: 87, wy: 1, drawDots: DRAW_DOTS},
+		{testName: "Window in the middle of the line", wx: 87, drawDots: DRAW_DOTS + 6},
+		{testName: "Window past the right edge", wx: 167, drawDots: DRAW_DOTS},
+	} {
+		t.Log(tt.testName)
+
+		m, p := newTestPpu(RENDER_FIFO)
+		m.Write(LCDC_ADDR, m.Read(LCDC_ADDR)|LCDC_WINDOW_ENABLE)
+		m.Write(WX_ADDR, tt.wx)
+		m.Write(WY_ADDR, tt.wy)
+
+		p.Tick(OAM_SCAN_DOTS + tt.drawDots - 1)
+		if mode := m.Read(STAT_ADDR) & STAT_MODE; mode != MODE_DRAWING {
+			t.Errorf("%s: expected drawing to last %d dots, got mode %d", tt.testName, tt.drawDots, mode)
+		}
+
+		p.Tick(1)
+		if mode := m.Read(STAT_ADDR) & STAT_MODE; mode != MODE_HBLANK {
+			t.Errorf("%s: expected HBlank after %d dots, got mode %d", tt.testName, tt.drawDots, mode)
+		}
+	}
+}
+
+// Fills VRAM, OAM, palettes and the LCD registers at random, keeping the
+// LCD on, so that both renderers can draw the same static scene
+func randomScene(r *rand.Rand, m *Mmu) {
+	r.Read(m.vram[:])
+	r.Read(m.oam[:])
+	r.Read(m.bgPalettes.data[:])
+	r.Read(m.objPalettes.data[:])
+
+	m.Write(LCDC_ADDR, uint8(r.Intn(0x100))|LCDC_ENABLE)
+	for _, addr := range []uint16{SCY_ADDR, SCX_ADDR, BGP_ADDR, OBP0_ADDR, OBP1_ADDR} {
+		m.Write(addr, uint8(r.Intn(0x100)))
+	}
+	m.Write(WY_ADDR, uint8(r.Intn(SCREEN_HEIGHT)))
+	m.Write(WX_ADDR, uint8(r.Intn(SCREEN_WIDTH+7)))
+}
+
+// Without mid-frame changes both renderers have to draw the same
+func TestFifoMatchesScanline(t *testing.T) {
+	for _, cgb := range []bool{false, true} {
+		for seed := int64(0); seed < 150; seed++ {
+			var frames [2]Frame
+			var colors [2]ColorFrame
+			for i, renderer := range renderers {
+				m, p := newTestPpu(renderer)
+				m.cgb = cgb
+				randomScene(rand.New(rand.NewSource(seed)), m)
+
+				frames[i], colors[i] = *drawFrame(t, p), *p.ColorFrame()
+			}
+
+			if frames[0] != frames[1] || colors[0] != colors[1] {
+				t.Errorf("CGB %v: scene %d drawn differently by the renderers", cgb, seed)
+			}
+		}
+	}
+}
+
+// Runs an acid2 test ROM from testdata and returns the frames it
+// draws. The ROMs and their reference images are not distributed with
+// the source, and the test is skipped without them: neither renderer has
+// been checked against them yet. They are dmg-acid2.gb, cgb-acid2.gbc and
+// the matching .png references from the releases of
+// github.com/mattcurrie/dmg-acid2 and github.com/mattcurrie/cgb-acid2.
+func runAcid2(t *testing.T, rom string, model, renderer int) (Frame, ColorFrame) {
+	data, err := os.ReadFile(filepath.Join("testdata", rom))
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Skipf("%s not found in testdata, renderer not validated", rom)
+	} else if err != nil {
+		t.Fatal(err)
+	}
+
+	cart, err := NewCartridge(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewMmu(cart)
+	cpu := NewCpu(m)
+	p := NewPpu(m)
+	p.SetRenderer(renderer)
+	p.SetInterruptRequest(cpu.RequestInterrupt)
+	PostBoot(model, cpu, m)
+	cpu.SetClock(m.Tick, TIMING_MCYCLE)
+	cpu.SetDotClock(func(cycles int) {
+		p.Tick(cycles)
+		cart.Tick(cycles)
+	})
+
+	var frame Frame
+	var colors ColorFrame
+	p.SetFrameHandler(func(f *Frame) {
+		frame, colors = *f, *p.ColorFrame()
+	})
+
+	for i := 0; i < 60; i++ {
+		cpu.StepFrame()
+	}
+
+	return frame, colors
+}
+
+func loadReference(t *testing.T, name string) image.Image {
+	f, err := os.Open(filepath.Join("testdata", name))
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Skipf("%s not found in testdata, renderer not validated", name)
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return img
+}
+
+// Compares with a reference image in which shades go from white to black
+func TestDmgAcid2(t *testing.T) {
+	for _, renderer := range renderers {
+		frame, _ := runAcid2(t, "dmg-acid2.gb", MODEL_DMG, renderer)
+		expected := loadReference(t, "dmg-acid2.png")
+
+		for y := 0; y < SCREEN_HEIGHT; y++ {
+			for x := 0; x < SCREEN_WIDTH; x++ {
+				r, _, _, _ := expected.At(x, y).RGBA()
+				if shade := uint8(3 - r>>8/0x55); frame[y][x] != shade {
+					t.Fatalf("Renderer %d: expected shade %d at %d,%d, got %d", renderer, shade, x, y, frame[y][x])
+				}
+			}
+		}
+	}
+}
+
+// Compares with a reference image in which each 5-bit component c is
+// scaled up to 8 bits as c<<3 | c>>2
+func TestCgbAcid2(t *testing.T) {
+	for _, renderer := range renderers {
+		_, colors := runAcid2(t, "cgb-acid2.gbc", MODEL_CGB, renderer)
+		expected := loadReference(t, "cgb-acid2.png")
+
+		for y := 0; y < SCREEN_HEIGHT; y++ {
+			for x := 0; x < SCREEN_WIDTH; x++ {
+				r, g, b, _ := expected.At(x, y).RGBA()
+				var rgb [3]uint32
+				for i := range rgb {
+					c := uint32(colors[y][x]>>(i*5)) & 0x1f
+					rgb[i] = c<<3 | c>>2
+				}
+
+				if rgb != [3]uint32{r >> 8, g >> 8, b >> 8} {
+					t.Fatalf("Renderer %d: expected RGB %d,%d,%d at %d,%d, got %v", renderer, r>>8, g>>8, b>>8, x, y, rgb)
+				}
+			}
+		}
+	}
+}
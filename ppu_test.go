@@ -0,0 +1,872 @@
+package main
+
+import (
+	"testing"
+)
+
+// Static scenes have to look and time the same with either renderer
+var renderers = []int{RENDER_SCANLINE, RENDER_FIFO}
+
+// Sets up an MMU with the LCD on, unsigned tile numbers and palettes
+// mapping colors to the shade of the same number
+func newTestPpu(renderer int) (*Mmu, *Ppu) {
+	m := NewMmu(nil)
+	m.Write(LCDC_ADDR, LCDC_ENABLE|LCDC_TILE_DATA|LCDC_BG_ENABLE|LCDC_OBJ_ENABLE)
+	m.Write(BGP_ADDR, 0xe4)
+	m.Write(OBP0_ADDR, 0xe4)
+	m.Write(OBP1_ADDR, 0x1b)
+
+	p := NewPpu(m)
+	p.SetRenderer(renderer)
+
+	return m, p
+}
+
+func setTilePixel(m *Mmu, tileAddr uint16, row, col int, color uint8) {
+	addr := tileAddr + uint16(row*2)
+	bit := uint8(0x80) >> col
+
+	m.Write(addr, m.Read(addr)&^bit|bit*(color&1))
+	m.Write(addr+1, m.Read(addr+1)&^bit|bit*(color>>1))
+}
+
+func fillTile(m *Mmu, tileAddr uint16, color uint8) {
+	for row := 0; row < 8; row++ {
+		for col := 0; col < 8; col++ {
+			setTilePixel(m, tileAddr, row, col, color)
+		}
+	}
+}
+
+func setSprite(m *Mmu, index int, y, x int, tile, attr uint8) {
+	addr := OAM_ADDR + uint16(index*4)
+	m.Write(addr, uint8(y+16))
+	m.Write(addr+1, uint8(x+8))
+	m.Write(addr+2, tile)
+	m.Write(addr+3, attr)
+}
+
+// Runs the PPU until a frame is complete and returns it
+func drawFrame(t *testing.T, p *Ppu) *Frame {
+	var frame *Frame
+	p.SetFrameHandler(func(f *Frame) {
+		frame = f
+	})
+
+	for i := 0; i < LINES_PER_FRAME && frame == nil; i++ {
+		p.Tick(DOTS_PER_LINE)
+	}
+
+	if frame == nil {
+		t.Fatal("Expected a frame to be drawn")
+	}
+
+	return frame
+}
+
+func TestPpuModeTiming(t *testing.T) {
+	for _, tt := range []struct {
+		testName  string
+		scx       uint8
+		sprites   []int // Screen X of sprites on line 0
+		drawDots  int
+		lcdcFlags uint8
+	}{
+		{testName: "Plain background", drawDots: DRAW_DOTS},
+		{testName: "Fine scroll", scx: 0x0d, drawDots: DRAW_DOTS + 5},
+		{testName: "Sprite on a tile boundary", sprites: []int{0}, drawDots: DRAW_DOTS + 11},
+		{testName: "Sprite off a tile boundary", sprites: []int{3}, drawDots: DRAW_DOTS + 8},
+		{testName: "Sprite far from the boundary", sprites: []int{6}, drawDots: DRAW_DOTS + 6},
+		{testName: "Off-screen sprite", sprites: []int{SCREEN_WIDTH}, drawDots: DRAW_DOTS},
+		{testName: "Two sprites", sprites: []int{0, 16}, drawDots: DRAW_DOTS + 22},
+	} {
+		t.Log(tt.testName)
+
+		for _, renderer := range renderers {
+			m, p := newTestPpu(renderer)
+			m.Write(SCX_ADDR, tt.scx)
+			for i, x := range tt.sprites {
+				setSprite(m, i, 0, x, 0, 0)
+			}
+
+			p.Tick(OAM_SCAN_DOTS - 1)
+			if mode := m.Read(STAT_ADDR) & STAT_MODE; mode != MODE_OAM_SCAN {
+				t.Errorf("%s (renderer %d): expected OAM scan, got mode %d", tt.testName, renderer, mode)
+			}
+
+			p.Tick(1)
+			if mode := m.Read(STAT_ADDR) & STAT_MODE; mode != MODE_DRAWING {
+				t.Errorf("%s (renderer %d): expected drawing after %d dots, got mode %d", tt.testName, renderer, OAM_SCAN_DOTS, mode)
+			}
+
+			p.Tick(tt.drawDots - 1)
+			if mode := m.Read(STAT_ADDR) & STAT_MODE; mode != MODE_DRAWING {
+				t.Errorf("%s (renderer %d): expected drawing to last %d dots, got mode %d", tt.testName, renderer, tt.drawDots, mode)
+			}
+
+			p.Tick(1)
+			if mode := m.Read(STAT_ADDR) & STAT_MODE; mode != MODE_HBLANK {
+				t.Errorf("%s (renderer %d): expected HBlank after %d dots, got mode %d", tt.testName, renderer, tt.drawDots, mode)
+			}
+
+			p.Tick(DOTS_PER_LINE - OAM_SCAN_DOTS - tt.drawDots)
+			if ly, mode := m.Read(LY_ADDR), m.Read(STAT_ADDR)&STAT_MODE; ly != 1 || mode != MODE_OAM_SCAN {
+				t.Errorf("%s (renderer %d): expected OAM scan on line 1, got mode %d on line %d", tt.testName, renderer, mode, ly)
+			}
+		}
+	}
+}
+
+func TestPpuFrameTiming(t *testing.T) {
+	m, p := newTestPpu(RENDER_SCANLINE)
+
+	frames := 0
+	p.SetFrameHandler(func(*Frame) {
+		frames++
+	})
+
+	p.Tick(SCREEN_HEIGHT*DOTS_PER_LINE - 1)
+	if ly := m.Read(LY_ADDR); ly != SCREEN_HEIGHT-1 || frames != 0 {
+		t.Errorf("Expected line 143 before VBlank, got line %d and %d frames", ly, frames)
+	}
+
+	p.Tick(1)
+	if ly, mode := m.Read(LY_ADDR), m.Read(STAT_ADDR)&STAT_MODE; ly != SCREEN_HEIGHT || mode != MODE_VBLANK {
+		t.Errorf("Expected VBlank on line 144, got mode %d on line %d", mode, ly)
+	}
+	if frames != 1 {
+		t.Errorf("Expected a frame at the start of VBlank, got %d", frames)
+	}
+	if m.Read(IF_ADDR)&(1<<INT_VBLANK) == 0 {
+		t.Error("Expected the VBlank interrupt to be requested")
+	}
+
+	p.Tick((LINES_PER_FRAME-SCREEN_HEIGHT)*DOTS_PER_LINE - 1)
+	if ly := m.Read(LY_ADDR); ly != LINES_PER_FRAME-1 {
+		t.Errorf("Expected line 153 at the end of the frame, got %d", ly)
+	}
+
+	p.Tick(1)
+	if ly, mode := m.Read(LY_ADDR), m.Read(STAT_ADDR)&STAT_MODE; ly != 0 || mode != MODE_OAM_SCAN {
+		t.Errorf("Expected the next frame to start, got mode %d on line %d", mode, ly)
+	}
+}
+
+func TestPpuLcdOff(t *testing.T) {
+	m, p := newTestPpu(RENDER_SCANLINE)
+	p.Tick(10 * DOTS_PER_LINE)
+
+	m.Write(LCDC_ADDR, 0x00)
+	p.Tick(DOTS_PER_LINE)
+	if ly, mode := m.Read(LY_ADDR), m.Read(STAT_ADDR)&STAT_MODE; ly != 0 || mode != MODE_HBLANK {
+		t.Errorf("Expected line 0 in HBlank with the LCD off, got mode %d on line %d", mode, ly)
+	}
+
+	// Writes to LY and the STAT mode are ignored
+	m.Write(LY_ADDR, 0x12)
+	m.Write(STAT_ADDR, 0xff)
+	if ly, stat := m.Read(LY_ADDR), m.Read(STAT_ADDR); ly != 0 || stat != 0xf8 {
+		t.Errorf("Expected LY 0 and STAT 0xf8, got %#02x and %#02x", ly, stat)
+	}
+
+	// Switching on starts from the top of the frame
+	m.Write(LCDC_ADDR, LCDC_ENABLE)
+	p.Tick(DOTS_PER_LINE)
+	if ly := m.Read(LY_ADDR); ly != 1 {
+		t.Errorf("Expected line 1 a line after switching on, got %d", ly)
+	}
+}
+
+func statRequested(m *Mmu) bool {
+	return m.Read(IF_ADDR)&(1<<INT_STAT) != 0
+}
+
+func clearInterrupts(m *Mmu) {
+	m.Write(IF_ADDR, 0x00)
+}
+
+func TestPpuStatInterrupts(t *testing.T) {
+	for _, tt := range []struct {
+		testName string
+		stat     uint8
+		lyc      uint8
+		from     int // Dots run before IF is cleared
+		at       int // Dots from the start of the frame to the request
+	}{
+		{testName: "HBlank", stat: STAT_HBLANK_INT, lyc: 0xff, from: 1, at: OAM_SCAN_DOTS + DRAW_DOTS},
+		{testName: "VBlank", stat: STAT_VBLANK_INT, lyc: 0xff, from: 1, at: SCREEN_HEIGHT * DOTS_PER_LINE},
+		{testName: "OAM scan", stat: STAT_OAM_INT, lyc: 0xff, from: 1, at: DOTS_PER_LINE},
+		{
+			testName: "OAM source as VBlank starts",
+			stat:     STAT_OAM_INT,
+			lyc:      0xff,
+			from:     (SCREEN_HEIGHT-1)*DOTS_PER_LINE + 1,
+			at:       SCREEN_HEIGHT * DOTS_PER_LINE,
+		},
+		{testName: "LY=LYC", stat: STAT_LYC_INT, lyc: 2, from: 1, at: 2 * DOTS_PER_LINE},
+		{testName: "LY=LYC on line 0", stat: STAT_LYC_INT, lyc: 0, from: 1, at: LINES_PER_FRAME * DOTS_PER_LINE},
+	} {
+		t.Log(tt.testName)
+
+		m, p := newTestPpu(RENDER_SCANLINE)
+		m.Write(STAT_ADDR, tt.stat)
+		m.Write(LYC_ADDR, tt.lyc)
+
+		p.Tick(tt.from)
+		clearInterrupts(m)
+
+		p.Tick(tt.at - tt.from - 1)
+		if statRequested(m) {
+			t.Errorf("%s: expected no request before %d dots", tt.testName, tt.at)
+		}
+
+		p.Tick(1)
+		if !statRequested(m) {
+			t.Errorf("%s: expected a request after %d dots", tt.testName, tt.at)
+		}
+	}
+}
+
+func TestPpuLycFlag(t *testing.T) {
+	m, p := newTestPpu(RENDER_SCANLINE)
+	m.Write(LYC_ADDR, 1)
+
+	p.Tick(DOTS_PER_LINE - 1)
+	if m.Read(STAT_ADDR)&STAT_LYC_EQUAL != 0 {
+		t.Error("Expected the LY=LYC flag clear on line 0")
+	}
+
+	p.Tick(1)
+	if m.Read(STAT_ADDR)&STAT_LYC_EQUAL == 0 {
+		t.Error("Expected the LY=LYC flag set on line 1")
+	}
+
+	// Writing LYC compares again on the next dot
+	m.Write(LYC_ADDR, 5)
+	p.Tick(1)
+	if m.Read(STAT_ADDR)&STAT_LYC_EQUAL != 0 {
+		t.Error("Expected the LY=LYC flag clear after changing LYC")
+	}
+}
+
+func TestPpuStatBlocking(t *testing.T) {
+	m, p := newTestPpu(RENDER_SCANLINE)
+	m.Write(STAT_ADDR, STAT_HBLANK_INT|STAT_OAM_INT)
+
+	p.Tick(OAM_SCAN_DOTS + DRAW_DOTS)
+	clearInterrupts(m)
+
+	// The line stays high from HBlank into the next OAM scan
+	p.Tick(DOTS_PER_LINE - DRAW_DOTS)
+	if statRequested(m) {
+		t.Error("Expected OAM scan following HBlank not to request an interrupt")
+	}
+
+	// It drops in mode 3 and rises again in HBlank
+	p.Tick(DRAW_DOTS)
+	if !statRequested(m) {
+		t.Error("Expected HBlank on line 1 to request an interrupt")
+	}
+}
+
+func TestPpuStatWriteBug(t *testing.T) {
+	for _, tt := range []struct {
+		testName  string
+		dots      int
+		lyc       uint8
+		requested bool
+	}{
+		{testName: "During HBlank", dots: OAM_SCAN_DOTS + DRAW_DOTS + 10, lyc: 0xff, requested: true},
+		{testName: "During VBlank", dots: SCREEN_HEIGHT*DOTS_PER_LINE + 10, lyc: 0xff, requested: true},
+		{testName: "During OAM scan", dots: 10, lyc: 0xff, requested: false},
+		{testName: "During drawing", dots: OAM_SCAN_DOTS + 10, lyc: 0xff, requested: false},
+		{testName: "During drawing with LY=LYC", dots: OAM_SCAN_DOTS + 10, lyc: 0, requested: true},
+	} {
+		t.Log(tt.testName)
+
+		m, p := newTestPpu(RENDER_SCANLINE)
+		m.Write(LYC_ADDR, tt.lyc)
+		p.Tick(tt.dots)
+		clearInterrupts(m)
+
+		m.Write(STAT_ADDR, 0x00)
+		if statRequested(m) != tt.requested {
+			t.Errorf("%s: expected the request to be %t", tt.testName, tt.requested)
+		}
+	}
+}
+
+func TestPpuLcdOnLyc(t *testing.T) {
+	m, p := newTestPpu(RENDER_SCANLINE)
+	m.Write(STAT_ADDR, STAT_LYC_INT)
+	p.Tick(10 * DOTS_PER_LINE)
+
+	m.Write(LCDC_ADDR, 0x00)
+	p.Tick(1)
+	clearInterrupts(m)
+
+	// LY restarts at 0, which matches LYC straight away
+	m.Write(LCDC_ADDR, LCDC_ENABLE)
+	p.Tick(1)
+	if !statRequested(m) {
+		t.Error("Expected switching on with LYC 0 to request an interrupt")
+	}
+}
+
+func TestPpuInterruptRequest(t *testing.T) {
+	m, p := newTestPpu(RENDER_SCANLINE)
+
+	var requested []int
+	p.SetInterruptRequest(func(interrupt int) {
+		requested = append(requested, interrupt)
+	})
+	m.Write(STAT_ADDR, STAT_VBLANK_INT)
+	p.Tick(SCREEN_HEIGHT * DOTS_PER_LINE)
+
+	if len(requested) != 2 || requested[0] != INT_VBLANK || requested[1] != INT_STAT {
+		t.Errorf("Expected VBlank and STAT requests, got %v", requested)
+	}
+	if val := m.Read(IF_ADDR) & 0x1f; val != 0 {
+		t.Errorf("Expected IF untouched, got %#02x", val)
+	}
+}
+
+func TestPpuAccessRestrictions(t *testing.T) {
+	for _, tt := range []struct {
+		testName     string
+		dots         int
+		lcdOff       bool
+		vramBlocked  bool
+		oamBlocked   bool
+		unusableRead uint8
+	}{
+		{testName: "OAM scan", dots: 10, oamBlocked: true, unusableRead: 0xff},
+		{testName: "Drawing", dots: OAM_SCAN_DOTS + 10, vramBlocked: true, oamBlocked: true, unusableRead: 0xff},
+		{testName: "HBlank", dots: DOTS_PER_LINE - 10},
+		{testName: "VBlank", dots: SCREEN_HEIGHT*DOTS_PER_LINE + OAM_SCAN_DOTS + 10},
+		{testName: "LCD off", dots: OAM_SCAN_DOTS + 10, lcdOff: true},
+	} {
+		t.Log(tt.testName)
+
+		m, p := newTestPpu(RENDER_SCANLINE)
+		m.Write(VRAM_ADDR, 0x12)
+		m.Write(OAM_ADDR, 0x34)
+		if tt.lcdOff {
+			m.Write(LCDC_ADDR, 0x00)
+		}
+		p.Tick(tt.dots)
+
+		for _, access := range []struct {
+			addr    uint16
+			val     uint8
+			blocked bool
+		}{
+			{addr: VRAM_ADDR, val: 0x12, blocked: tt.vramBlocked},
+			{addr: OAM_ADDR, val: 0x34, blocked: tt.oamBlocked},
+		} {
+			expected := access.val
+			if access.blocked {
+				expected = 0xff
+			}
+			if val := m.Read(access.addr); val != expected {
+				t.Errorf("%s: expected %#02x read from %#04x, got %#02x", tt.testName, expected, access.addr, val)
+			}
+
+			m.Write(access.addr, 0x56)
+			expected = 0x56
+			if access.blocked {
+				expected = access.val
+			}
+			if val := m.read(access.addr); val != expected {
+				t.Errorf("%s: expected %#02x at %#04x after a write, got %#02x", tt.testName, expected, access.addr, val)
+			}
+		}
+
+		if val := m.Read(UNUSABLE_ADDR); val != tt.unusableRead {
+			t.Errorf("%s: expected %#02x read from the unusable area, got %#02x", tt.testName, tt.unusableRead, val)
+		}
+	}
+}
+
+func TestPpuBackground(t *testing.T) {
+	for _, tt := range []struct {
+		testName string
+		lcdc     uint8
+		scx, scy uint8
+		bgp      uint8
+		x, y     int
+		expected uint8
+	}{
+		{testName: "Tile 1 at the top left", x: 0, y: 0, expected: 1},
+		{testName: "Tile 2 next to it", x: 8, y: 0, expected: 2},
+		{testName: "Tile 3 below", x: 0, y: 8, expected: 3},
+		{testName: "Empty tile", x: 16, y: 0, expected: 0},
+		{testName: "Palette", bgp: 0x1b, x: 8, y: 0, expected: 1},
+		{testName: "Horizontal scroll", scx: 4, x: 4, y: 0, expected: 2},
+		{testName: "Vertical scroll", scy: 4, x: 0, y: 4, expected: 3},
+		{testName: "Wrapping scroll", scx: 0xfc, scy: 0xfc, x: 4, y: 4, expected: 1},
+		{testName: "Signed tile numbers", lcdc: LCDC_ENABLE | LCDC_BG_ENABLE, x: 0, y: 0, expected: 3},
+		{testName: "Second tile map", lcdc: LCDC_ENABLE | LCDC_TILE_DATA | LCDC_BG_ENABLE | LCDC_BG_MAP, x: 0, y: 0, expected: 2},
+		{testName: "Background disabled", lcdc: LCDC_ENABLE | LCDC_TILE_DATA, x: 0, y: 0, expected: 0},
+	} {
+		t.Log(tt.testName)
+
+		for _, renderer := range renderers {
+			m, p := newTestPpu(renderer)
+			fillTile(m, 0x8010, 1)
+			fillTile(m, 0x8020, 2)
+			fillTile(m, 0x8030, 3)
+			fillTile(m, 0x9010, 3) // Tile 1 with signed numbers
+			m.Write(0x9800, 1)
+			m.Write(0x9801, 2)
+			m.Write(0x9820, 3)
+			m.Write(0x9c00, 2)
+			m.Write(0x981f, 1) // Wrapped to by scrolling left
+			m.Write(0x9be0, 1) // Wrapped to by scrolling up
+			m.Write(0x9bff, 1)
+
+			if tt.lcdc != 0 {
+				m.Write(LCDC_ADDR, tt.lcdc)
+			}
+			if tt.bgp != 0 {
+				m.Write(BGP_ADDR, tt.bgp)
+			}
+			m.Write(SCX_ADDR, tt.scx)
+			m.Write(SCY_ADDR, tt.scy)
+
+			frame := drawFrame(t, p)
+			if val := frame[tt.y][tt.x]; val != tt.expected {
+				t.Errorf("%s (renderer %d): expected shade %d at %d,%d, got %d", tt.testName, renderer, tt.expected, tt.x, tt.y, val)
+			}
+		}
+	}
+}
+
+func TestPpuWindow(t *testing.T) {
+	for _, renderer := range renderers {
+		m, p := newTestPpu(renderer)
+		fillTile(m, 0x8010, 1)
+		fillTile(m, 0x8020, 2)
+		for i := uint16(0); i < 32*32; i++ {
+			m.Write(0x9800+i, 1)
+			m.Write(0x9c00+i, 2)
+		}
+		// The second row of the window
+		for i := uint16(0); i < 32; i++ {
+			m.Write(0x9c20+i, 0)
+		}
+
+		m.Write(LCDC_ADDR, m.Read(LCDC_ADDR)|LCDC_WINDOW_ENABLE|LCDC_WINDOW_MAP)
+		m.Write(WX_ADDR, 80+7)
+		m.Write(WY_ADDR, 20)
+
+		frame := drawFrame(t, p)
+		for _, tt := range []struct {
+			testName string
+			x, y     int
+			expected uint8
+		}{
+			{testName: "Above the window", x: 100, y: 19, expected: 1},
+			{testName: "Left of the window", x: 79, y: 20, expected: 1},
+			{testName: "Top left of the window", x: 80, y: 20, expected: 2},
+			{testName: "Last line of the first window row", x: 159, y: 27, expected: 2},
+			{testName: "Second window row", x: 80, y: 28, expected: 0},
+		} {
+			t.Log(tt.testName)
+
+			if val := frame[tt.y][tt.x]; val != tt.expected {
+				t.Errorf("%s (renderer %d): expected shade %d at %d,%d, got %d", tt.testName, renderer, tt.expected, tt.x, tt.y, val)
+			}
+		}
+
+		// Hiding the window for some lines delays the rest of it
+		m.Write(WY_ADDR, 0)
+		p.SetFrameHandler(nil)
+		p.Tick((LINES_PER_FRAME - SCREEN_HEIGHT) * DOTS_PER_LINE)
+		for line := 0; line < SCREEN_HEIGHT; line++ {
+			wx := uint8(80 + 7)
+			if line >= 4 && line < 12 {
+				wx = 0xff
+			}
+			m.Write(WX_ADDR, wx)
+			p.Tick(DOTS_PER_LINE)
+		}
+		if val := p.Frame()[12][80]; val != 2 {
+			t.Errorf("Renderer %d: expected line 12 to show the first window row, got %d", renderer, val)
+		}
+		if val := p.Frame()[16][80]; val != 0 {
+			t.Errorf("Renderer %d: expected line 16 to show the second window row, got %d", renderer, val)
+		}
+	}
+}
+
+func TestPpuSprites(t *testing.T) {
+	type obj struct {
+		y, x       int
+		tile, attr uint8
+	}
+
+	for _, tt := range []struct {
+		testName string
+		lcdc     uint8 // LCDC bits to toggle
+		bgColor  uint8
+		objs     []obj
+		x, y     int
+		expected uint8
+	}{
+		{
+			testName: "Sprite over the background",
+			objs:     []obj{{y: 10, x: 10, tile: 1}},
+			x:        10, y: 10,
+			expected: 1,
+		},
+		{
+			testName: "Transparent pixels",
+			bgColor:  2,
+			objs:     []obj{{y: 10, x: 10, tile: 0}},
+			x:        10, y: 10,
+			expected: 2,
+		},
+		{
+			testName: "Second palette",
+			objs:     []obj{{y: 10, x: 10, tile: 1, attr: OBJ_PALETTE}},
+			x:        10, y: 10,
+			expected: 2,
+		},
+		{
+			testName: "Behind background color 0",
+			objs:     []obj{{y: 10, x: 10, tile: 1, attr: OBJ_PRIORITY}},
+			x:        10, y: 10,
+			expected: 1,
+		},
+		{
+			testName: "Behind other background colors",
+			bgColor:  2,
+			objs:     []obj{{y: 10, x: 10, tile: 1, attr: OBJ_PRIORITY}},
+			x:        10, y: 10,
+			expected: 2,
+		},
+		{
+			testName: "Lower X wins",
+			objs:     []obj{{y: 10, x: 12, tile: 1}, {y: 10, x: 10, tile: 3}},
+			x:        12, y: 10,
+			expected: 3,
+		},
+		{
+			testName: "First in OAM wins on the same X",
+			objs:     []obj{{y: 10, x: 10, tile: 1}, {y: 10, x: 10, tile: 3}},
+			x:        10, y: 10,
+			expected: 1,
+		},
+		{
+			testName: "Lower priority shows through transparent pixels",
+			objs:     []obj{{y: 10, x: 10, tile: 0}, {y: 10, x: 10, tile: 3}},
+			x:        10, y: 10,
+			expected: 3,
+		},
+		{
+			testName: "Priority over a sprite behind the background",
+			bgColor:  2,
+			objs:     []obj{{y: 10, x: 10, tile: 1, attr: OBJ_PRIORITY}, {y: 10, x: 10, tile: 3}},
+			x:        10, y: 10,
+			expected: 2,
+		},
+		{
+			testName: "Flipped horizontally",
+			objs:     []obj{{y: 10, x: 10, tile: 4, attr: OBJ_FLIP_X}},
+			x:        17, y: 10,
+			expected: 3,
+		},
+		{
+			testName: "Flipped vertically",
+			objs:     []obj{{y: 10, x: 10, tile: 5, attr: OBJ_FLIP_Y}},
+			x:        10, y: 17,
+			expected: 3,
+		},
+		{
+			testName: "Top half of a tall sprite ignores bit 0",
+			lcdc:     LCDC_OBJ_SIZE,
+			objs:     []obj{{y: 10, x: 10, tile: 3}},
+			x:        10, y: 10,
+			expected: 2,
+		},
+		{
+			testName: "Bottom half of a tall sprite",
+			lcdc:     LCDC_OBJ_SIZE,
+			objs:     []obj{{y: 10, x: 10, tile: 3}},
+			x:        10, y: 18,
+			expected: 3,
+		},
+		{
+			testName: "Sprites disabled",
+			lcdc:     LCDC_OBJ_ENABLE,
+			objs:     []obj{{y: 10, x: 10, tile: 1}},
+			x:        10, y: 10,
+			expected: 0,
+		},
+	} {
+		t.Log(tt.testName)
+
+		for _, renderer := range renderers {
+			m, p := newTestPpu(renderer)
+			fillTile(m, 0x8010, 1)
+			fillTile(m, 0x8020, 2)
+			fillTile(m, 0x8030, 3)
+			setTilePixel(m, 0x8040, 0, 0, 3)
+			setTilePixel(m, 0x8050, 0, 0, 3)
+			fillTile(m, 0x8060, 2)
+			fillTile(m, 0x8070, tt.bgColor)
+
+			for i := uint16(0); i < 32*32; i++ {
+				m.Write(0x9800+i, 7)
+			}
+			for i, o := range tt.objs {
+				setSprite(m, i, o.y, o.x, o.tile, o.attr)
+			}
+
+			if tt.lcdc != 0 {
+				m.Write(LCDC_ADDR, m.Read(LCDC_ADDR)^tt.lcdc)
+			}
+
+			frame := drawFrame(t, p)
+			if val := frame[tt.y][tt.x]; val != tt.expected {
+				t.Errorf("%s (renderer %d): expected shade %d at %d,%d, got %d", tt.testName, renderer, tt.expected, tt.x, tt.y, val)
+			}
+		}
+	}
+}
+
+func TestPpuSpriteLimit(t *testing.T) {
+	for _, renderer := range renderers {
+		m, p := newTestPpu(renderer)
+		fillTile(m, 0x8010, 3)
+
+		// Sprites off screen or on other lines do not count, hidden ones do
+		setSprite(m, 0, 50, 0, 1, 0)
+		setSprite(m, 1, 10, -8, 1, 0)
+		for i := 2; i < 12; i++ {
+			setSprite(m, i, 10, (i-2)*10, 1, 0)
+		}
+
+		frame := drawFrame(t, p)
+		for i := 0; i < 9; i++ {
+			if val := frame[10][i*10]; val != 3 {
+				t.Errorf("Renderer %d: expected sprite %d to be drawn, got %d", renderer, i+2, val)
+			}
+		}
+
+		if val := frame[10][90]; val != 0 {
+			t.Errorf("Renderer %d: expected the 11th sprite on the line to be dropped, got %d", renderer, val)
+		}
+	}
+}
+
+// Distinct colors for every palette entry, sprites having bit 0 set
+func cgbColor(palette, color uint8, obj bool) uint16 {
+	val := uint16(palette)<<10 | uint16(color)<<5
+	if obj {
+		val |= 1
+	}
+
+	return val
+}
+
+func newCgbTestPpu(renderer int) (*Mmu, *Ppu) {
+	m, p := newTestPpu(renderer)
+	m.cgb = true
+
+	for palette := uint8(0); palette < 8; palette++ {
+		for color := uint8(0); color < 4; color++ {
+			i := palette*8 + color*2
+			bg, obj := cgbColor(palette, color, false), cgbColor(palette, color, true)
+			m.bgPalettes.data[i], m.bgPalettes.data[i+1] = uint8(bg), uint8(bg>>8)
+			m.objPalettes.data[i], m.objPalettes.data[i+1] = uint8(obj), uint8(obj>>8)
+		}
+	}
+
+	return m, p
+}
+
+// Sets the attributes of the first entry of the background map
+func setMapAttr(m *Mmu, attr uint8) {
+	m.vram[VRAM_SIZE+0x1800] = attr
+}
+
+func TestPpuCgbBackground(t *testing.T) {
+	for _, tt := range []struct {
+		testName string
+		setup    func(m *Mmu)
+		x        int
+		color    uint8
+		palette  uint8
+	}{
+		{
+			testName: "Palette",
+			setup: func(m *Mmu) {
+				fillTile(m, 0x8010, 1)
+				setMapAttr(m, 2)
+			},
+			color:   1,
+			palette: 2,
+		},
+		{
+			testName: "Tile in bank 1",
+			setup: func(m *Mmu) {
+				fillTile(m, 0x8010, 1)
+				m.Write(VBK_ADDR, 1)
+				fillTile(m, 0x8010, 2)
+				m.Write(VBK_ADDR, 0)
+				setMapAttr(m, BG_BANK|3)
+			},
+			color:   2,
+			palette: 3,
+		},
+		{
+			testName: "Horizontal flip",
+			setup: func(m *Mmu) {
+				setTilePixel(m, 0x8010, 0, 0, 3)
+				setMapAttr(m, BG_FLIP_X)
+			},
+			x:     7,
+			color: 3,
+		},
+		{
+			testName: "Vertical flip",
+			setup: func(m *Mmu) {
+				setTilePixel(m, 0x8010, 7, 0, 3)
+				setMapAttr(m, BG_FLIP_Y)
+			},
+			color: 3,
+		},
+		{
+			testName: "LCDC bit 0 does not blank the background",
+			setup: func(m *Mmu) {
+				fillTile(m, 0x8010, 1)
+				m.Write(LCDC_ADDR, m.Read(LCDC_ADDR)&^LCDC_BG_ENABLE)
+			},
+			color: 1,
+		},
+	} {
+		t.Log(tt.testName)
+
+		for _, renderer := range renderers {
+			m, p := newCgbTestPpu(renderer)
+			m.Write(0x9800, 1)
+			tt.setup(m)
+
+			frame := drawFrame(t, p)
+			if val := frame[0][tt.x]; val != tt.color {
+				t.Errorf("%s (renderer %d): expected color %d, got %d", tt.testName, renderer, tt.color, val)
+			}
+			if expected, val := cgbColor(tt.palette, tt.color, false), p.ColorFrame()[0][tt.x]; val != expected {
+				t.Errorf("%s (renderer %d): expected RGB %#04x, got %#04x", tt.testName, renderer, expected, val)
+			}
+		}
+	}
+}
+
+func TestPpuCgbSprites(t *testing.T) {
+	for _, tt := range []struct {
+		testName string
+		setup    func(m *Mmu)
+		color    uint8
+		palette  uint8
+		obj      bool
+	}{
+		{
+			testName: "Palette and bank",
+			setup: func(m *Mmu) {
+				m.Write(VBK_ADDR, 1)
+				fillTile(m, 0x8020, 3)
+				m.Write(VBK_ADDR, 0)
+				setSprite(m, 0, 0, 0, 2, OBJ_BANK|5)
+			},
+			color:   3,
+			palette: 5,
+			obj:     true,
+		},
+		{
+			testName: "OAM index wins over X",
+			setup: func(m *Mmu) {
+				fillTile(m, 0x8020, 2)
+				fillTile(m, 0x8030, 3)
+				setSprite(m, 0, 0, 4, 2, 1)
+				setSprite(m, 1, 0, 0, 3, 2)
+			},
+			color:   2,
+			palette: 1,
+			obj:     true,
+		},
+		{
+			testName: "Map priority",
+			setup: func(m *Mmu) {
+				fillTile(m, 0x8010, 1)
+				setMapAttr(m, BG_PRIORITY)
+				fillTile(m, 0x8020, 3)
+				setSprite(m, 0, 0, 0, 2, 0)
+			},
+			color: 1,
+		},
+		{
+			testName: "Map priority over color 0",
+			setup: func(m *Mmu) {
+				setMapAttr(m, BG_PRIORITY)
+				fillTile(m, 0x8020, 3)
+				setSprite(m, 0, 0, 0, 2, 0)
+			},
+			color: 3,
+			obj:   true,
+		},
+		{
+			testName: "Sprite priority",
+			setup: func(m *Mmu) {
+				fillTile(m, 0x8010, 1)
+				fillTile(m, 0x8020, 3)
+				setSprite(m, 0, 0, 0, 2, OBJ_PRIORITY)
+			},
+			color: 1,
+		},
+		{
+			testName: "LCDC bit 0 puts sprites in front",
+			setup: func(m *Mmu) {
+				fillTile(m, 0x8010, 1)
+				setMapAttr(m, BG_PRIORITY)
+				fillTile(m, 0x8020, 3)
+				setSprite(m, 0, 0, 0, 2, OBJ_PRIORITY)
+				m.Write(LCDC_ADDR, m.Read(LCDC_ADDR)&^LCDC_BG_ENABLE)
+			},
+			color: 3,
+			obj:   true,
+		},
+	} {
+		t.Log(tt.testName)
+
+		for _, renderer := range renderers {
+			m, p := newCgbTestPpu(renderer)
+			m.Write(0x9800, 1)
+			tt.setup(m)
+
+			frame := drawFrame(t, p)
+			if val := frame[0][4]; val != tt.color {
+				t.Errorf("%s (renderer %d): expected color %d, got %d", tt.testName, renderer, tt.color, val)
+			}
+			if expected, val := cgbColor(tt.palette, tt.color, tt.obj), p.ColorFrame()[0][4]; val != expected {
+				t.Errorf("%s (renderer %d): expected RGB %#04x, got %#04x", tt.testName, renderer, expected, val)
+			}
+		}
+	}
+}
+
+func TestPpuDmgColorFrame(t *testing.T) {
+	m, p := newTestPpu(RENDER_SCANLINE)
+	for x := 0; x < 4; x++ {
+		setTilePixel(m, 0x8000, 0, x, uint8(x))
+	}
+
+	drawFrame(t, p)
+	for x, expected := range []uint16{0x7fff, 0x56b5, 0x294a, 0x0000} {
+		if val := p.ColorFrame()[0][x]; val != expected {
+			t.Errorf("Expected gray %#04x for shade %d, got %#04x", expected, x, val)
+		}
+	}
+}
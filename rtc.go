@@ -0,0 +1,237 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// RTC register selectors, written to the RAM bank register
+const (
+	RTC_S  = 0x08 // Seconds
+	RTC_M  = 0x09 // Minutes
+	RTC_H  = 0x0a // Hours
+	RTC_DL = 0x0b // Low 8 bits of the day counter
+	RTC_DH = 0x0c // Day counter bit 8, halt and day carry
+)
+
+const (
+	// The RTC counts seconds off its own 32768Hz crystal, which we
+	// derive from the CPU clock instead
+	RTC_CYCLES_PER_SECOND = 4194304
+
+	RTC_DH_DAY_8 = BIT_0
+	RTC_DH_HALT  = BIT_6
+	RTC_DH_CARRY = BIT_7
+
+	// Size of the RTC state stored after the save RAM, in the layout
+	// shared by VBA-M, BGB, mGBA and SameBoy
+	RTC_TRAILER_SIZE = 48
+	// Older variant with a 32-bit timestamp
+	RTC_TRAILER_SIZE_32 = 44
+)
+
+// MBC3 real-time clock
+type rtc struct {
+	seconds, minutes, hours uint8
+	days                    uint16 // 9 bits
+	halted                  bool
+	carry                   bool // Day counter overflowed
+	cycles                  int  // Towards the next second
+
+	// Copy of the registers taken on latching, which is what reads see
+	latched [5]uint8
+	// Last value written to the latch register; writing 0 then 1 latches
+	latchWrite uint8
+
+	// Source of the wall-clock time saves are stamped with
+	now func() time.Time
+}
+
+func newRtc() *rtc {
+	return &rtc{now: time.Now, latchWrite: 0xff}
+}
+
+// Advances the clock by the given number of CPU cycles
+func (r *rtc) tick(cycles int) {
+	if r.halted {
+		return
+	}
+
+	r.cycles += cycles
+	for r.cycles >= RTC_CYCLES_PER_SECOND {
+		r.cycles -= RTC_CYCLES_PER_SECOND
+		r.advanceSecond()
+	}
+}
+
+// Counters only roll over when they hit their exact limit, so values
+// written out of range count up to the register width and wrap to 0
+// without carrying
+func (r *rtc) advanceSecond() {
+	r.seconds = (r.seconds + 1) & 0x3f
+	if r.seconds != 60 {
+		return
+	}
+
+	r.seconds = 0
+	r.minutes = (r.minutes + 1) & 0x3f
+	if r.minutes != 60 {
+		return
+	}
+
+	r.minutes = 0
+	r.hours = (r.hours + 1) & 0x1f
+	if r.hours != 24 {
+		return
+	}
+
+	r.hours = 0
+	r.days++
+	if r.days == 512 {
+		r.days = 0
+		r.carry = true
+	}
+}
+
+// Advances the clock by whole seconds, such as the time elapsed while
+// the emulator was not running
+func (r *rtc) advance(seconds int64) {
+	if r.halted {
+		return
+	}
+
+	// Step out of any invalid values one second at a time, as they do
+	// not carry like valid ones
+	for seconds > 0 && (r.seconds >= 60 || r.minutes >= 60 || r.hours >= 24) {
+		r.advanceSecond()
+		seconds--
+	}
+	if seconds <= 0 {
+		return
+	}
+
+	total := int64(r.days)*86400 + int64(r.hours)*3600 + int64(r.minutes)*60 + int64(r.seconds) + seconds
+	days := total / 86400
+	if days >= 512 {
+		r.carry = true
+	}
+
+	r.days = uint16(days % 512)
+	r.hours = uint8(total % 86400 / 3600)
+	r.minutes = uint8(total % 3600 / 60)
+	r.seconds = uint8(total % 60)
+}
+
+func (r *rtc) dh() uint8 {
+	dh := uint8(r.days>>8) & RTC_DH_DAY_8
+	if r.halted {
+		dh |= RTC_DH_HALT
+	}
+	if r.carry {
+		dh |= RTC_DH_CARRY
+	}
+
+	return dh
+}
+
+func (r *rtc) registers() [5]uint8 {
+	return [5]uint8{r.seconds, r.minutes, r.hours, uint8(r.days), r.dh()}
+}
+
+func (r *rtc) writeLatch(val uint8) {
+	if r.latchWrite == 0x00 && val == 0x01 {
+		r.latched = r.registers()
+	}
+
+	r.latchWrite = val
+}
+
+// Reads a latched register. Unused bits read as 1.
+func (r *rtc) read(reg uint8) uint8 {
+	switch reg {
+	case RTC_S, RTC_M:
+		return r.latched[reg-RTC_S] | 0xc0
+	case RTC_H:
+		return r.latched[reg-RTC_S] | 0xe0
+	case RTC_DH:
+		return r.latched[reg-RTC_S] | 0x3e
+	}
+
+	return r.latched[reg-RTC_S]
+}
+
+// Writes go to the counters themselves, and show through the latched
+// copy right away
+func (r *rtc) write(reg, val uint8) {
+	switch reg {
+	case RTC_S:
+		r.seconds = val & 0x3f
+		// Writing the seconds resets the sub-second divider
+		r.cycles = 0
+	case RTC_M:
+		r.minutes = val & 0x3f
+	case RTC_H:
+		r.hours = val & 0x1f
+	case RTC_DL:
+		r.days = r.days&0x100 | uint16(val)
+	case RTC_DH:
+		r.days = r.days&0xff | uint16(val&RTC_DH_DAY_8)<<8
+		r.halted = val&RTC_DH_HALT != 0
+		r.carry = val&RTC_DH_CARRY != 0
+	}
+
+	r.latched[reg-RTC_S] = r.registers()[reg-RTC_S]
+}
+
+// Serializes the clock as stored after the save RAM: the live and the
+// latched registers as 32-bit words, followed by a 64-bit Unix
+// timestamp, all little endian
+func (r *rtc) marshal() []uint8 {
+	data := make([]uint8, RTC_TRAILER_SIZE)
+
+	for i, reg := range r.registers() {
+		binary.LittleEndian.PutUint32(data[i*4:], uint32(reg))
+	}
+	for i, reg := range r.latched {
+		binary.LittleEndian.PutUint32(data[20+i*4:], uint32(reg))
+	}
+	binary.LittleEndian.PutUint64(data[40:], uint64(r.now().Unix()))
+
+	return data
+}
+
+// Restores the clock from a save trailer, advancing it by the time
+// elapsed since the save was written
+func (r *rtc) unmarshal(data []uint8) error {
+	if len(data) != RTC_TRAILER_SIZE && len(data) != RTC_TRAILER_SIZE_32 {
+		return fmt.Errorf("RTC data is %d bytes, expected %d", len(data), RTC_TRAILER_SIZE)
+	}
+
+	var regs [5]uint8
+	for i := range regs {
+		regs[i] = uint8(binary.LittleEndian.Uint32(data[i*4:]))
+		r.latched[i] = uint8(binary.LittleEndian.Uint32(data[20+i*4:]))
+	}
+
+	// Restored through the register writes so out of range bits are
+	// masked, without disturbing the restored latched copy
+	latched := r.latched
+	for i, val := range regs {
+		r.write(RTC_S+uint8(i), val)
+	}
+	r.latched = latched
+
+	var saved int64
+	if len(data) == RTC_TRAILER_SIZE {
+		saved = int64(binary.LittleEndian.Uint64(data[40:]))
+	} else {
+		saved = int64(binary.LittleEndian.Uint32(data[40:]))
+	}
+
+	if elapsed := r.now().Unix() - saved; elapsed > 0 {
+		r.advance(elapsed)
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Emulated time between flushes of dirty save RAM, about 1 second
+const SAVE_FLUSH_CYCLES = RTC_CYCLES_PER_SECOND
+
+// Save file next to a ROM, named after it with a .sav extension
+func savePath(romPath string) string {
+	return strings.TrimSuffix(romPath, filepath.Ext(romPath)) + ".sav"
+}
+
+// Loads the save file if there is one, and saves to it from then on
+func (c *Cartridge) openSave(path string) error {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		if err := c.ImportSave(data); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	c.savePath = path
+	c.dirty = false
+	return nil
+}
+
+// Returns the battery-backed state: the RAM, followed by the clock
+// state when the cartridge has one
+func (c *Cartridge) ExportSave() []uint8 {
+	data := append([]uint8{}, c.ram...)
+	if c.clock != nil {
+		data = append(data, c.clock.marshal()...)
+	}
+
+	return data
+}
+
+// Restores state produced by ExportSave. A missing clock trailer leaves
+// the clock as it is.
+func (c *Cartridge) ImportSave(data []uint8) error {
+	if len(data) < len(c.ram) {
+		return fmt.Errorf("save is %d bytes, expected at least %d", len(data), len(c.ram))
+	}
+
+	if trailer := data[len(c.ram):]; len(trailer) > 0 {
+		if c.clock == nil {
+			return fmt.Errorf("save is %d bytes, expected %d", len(data), len(c.ram))
+		}
+
+		if err := c.clock.unmarshal(trailer); err != nil {
+			return err
+		}
+	}
+
+	copy(c.ram, data)
+	c.dirty = true
+	return nil
+}
+
+// Writes the save file, if the cartridge has one
+func (c *Cartridge) Flush() error {
+	if c.savePath == "" {
+		return nil
+	}
+
+	if err := writeFileAtomic(c.savePath, c.ExportSave()); err != nil {
+		return err
+	}
+
+	c.dirty = false
+	return nil
+}
+
+// Flushes the save file for the last time, to be called on exit
+func (c *Cartridge) Close() error {
+	err := c.Flush()
+	c.savePath = ""
+	return err
+}
+
+// Replaces a file so that it holds either the old or the new data, even
+// when interrupted, by writing a temporary file and renaming it over
+func writeFileAtomic(path string, data []uint8) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+
+	return err
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeRom(t *testing.T, dir string, rom []uint8) string {
+	path := filepath.Join(dir, "game.gb")
+	if err := os.WriteFile(path, rom, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestSavePath(t *testing.T) {
+	for _, tt := range []struct {
+		testName string
+		rom      string
+		expected string
+	}{
+		{testName: "GB ROM", rom: "roms/game.gb", expected: "roms/game.sav"},
+		{testName: "GBC ROM", rom: "game.gbc", expected: "game.sav"},
+		{testName: "No extension", rom: "game", expected: "game.sav"},
+	} {
+		t.Log(tt.testName)
+
+		if path := savePath(tt.rom); path != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.testName, tt.expected, path)
+		}
+	}
+}
+
+func TestBatterySave(t *testing.T) {
+	dir := t.TempDir()
+	path := writeRom(t, dir, makeRom(0x03, 0x04, 0x03))
+
+	cart, err := LoadCartridge(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	cart.Write(0x0000, 0x0a)
+	cart.Write(0xa123, 0x45)
+	if err := cart.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "game.sav"))
+	if err != nil {
+		t.Fatalf("Expected a save file: %v", err)
+	}
+	if len(data) != 0x8000 || data[0x123] != 0x45 {
+		t.Errorf("Expected the RAM to be saved")
+	}
+
+	reloaded, err := LoadCartridge(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	reloaded.Write(0x0000, 0x0a)
+	if val := reloaded.Read(0xa123); val != 0x45 {
+		t.Errorf("Expected the save to be loaded, got %#02x", val)
+	}
+
+	// Only the save file is left behind
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("Expected the ROM and save only, got %d files", len(entries))
+	}
+}
+
+func TestNoBattery(t *testing.T) {
+	dir := t.TempDir()
+	cart, err := LoadCartridge(writeRom(t, dir, makeRom(0x02, 0x04, 0x03)))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	cart.Write(0x0000, 0x0a)
+	cart.Write(0xa000, 0x45)
+	if err := cart.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "game.sav")); err == nil {
+		t.Error("Expected no save file without a battery")
+	}
+}
+
+func TestPeriodicFlush(t *testing.T) {
+	dir := t.TempDir()
+	cart, err := LoadCartridge(writeRom(t, dir, makeRom(0x03, 0x04, 0x03)))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	save := filepath.Join(dir, "game.sav")
+
+	// Nothing is written while the RAM is clean
+	cart.Tick(SAVE_FLUSH_CYCLES)
+	if _, err := os.Stat(save); err == nil {
+		t.Fatal("Expected no save file before RAM is written")
+	}
+
+	cart.Write(0x0000, 0x0a)
+	cart.Write(0xa000, 0x45)
+	cart.Tick(SAVE_FLUSH_CYCLES - 1)
+	if _, err := os.Stat(save); err == nil {
+		t.Fatal("Expected no save file before the flush period")
+	}
+
+	cart.Tick(1)
+	if data, err := os.ReadFile(save); err != nil || data[0] != 0x45 {
+		t.Errorf("Expected the RAM to be flushed, got %v", err)
+	}
+}
+
+func TestBadSaveFile(t *testing.T) {
+	dir := t.TempDir()
+	path := writeRom(t, dir, makeRom(0x03, 0x04, 0x03))
+	if err := os.WriteFile(filepath.Join(dir, "game.sav"), make([]uint8, 0x100), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadCartridge(path); err == nil {
+		t.Error("Expected an error for a truncated save")
+	}
+}
+
+func TestExportImportSave(t *testing.T) {
+	cart := newTestCartridge(t, makeRom(0x1b, 0x04, 0x02))
+	cart.Write(0x0000, 0x0a)
+	cart.Write(0xa010, 0x99)
+
+	data := cart.ExportSave()
+	if len(data) != 0x2000 || data[0x10] != 0x99 {
+		t.Fatalf("Expected the exported RAM")
+	}
+
+	// Exports are copies
+	data[0x10] = 0x11
+	if val := cart.Read(0xa010); val != 0x99 {
+		t.Errorf("Expected RAM to be unaffected by changes to the export, got %#02x", val)
+	}
+
+	other := newTestCartridge(t, makeRom(0x1b, 0x04, 0x02))
+	if err := other.ImportSave(data); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	other.Write(0x0000, 0x0a)
+	if val := other.Read(0xa010); val != 0x11 {
+		t.Errorf("Expected the imported RAM, got %#02x", val)
+	}
+
+	if err := other.ImportSave(append(data, 0x00)); err == nil {
+		t.Error("Expected an error for trailing data without a clock")
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "game.sav")
+	if err := os.WriteFile(path, []uint8{1, 2, 3}, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeFileAtomic(path, []uint8{4, 5}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if data, _ := os.ReadFile(path); !bytes.Equal(data, []uint8{4, 5}) {
+		t.Errorf("Expected the file to be replaced, got %v", data)
+	}
+
+	if err := writeFileAtomic(filepath.Join(path, "missing", "game.sav"), nil); err == nil {
+		t.Error("Expected an error writing into a missing directory")
+	}
+}